@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add workspace properties",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var addMountCmd = &cobra.Command{
+	Use:   "mount HOST:CONTAINER[:ro]",
+	Short: "Bind-mount a host directory into the workspace container",
+	Long: `
+Bind-mount the absolute host path to the absolute path in the workspace container.
+Add the ro option to mount the directory read-only. The mount is applied to an
+existing container without rebuilding it and takes effect when the container is
+started the next time.`,
+	Args: cobra.ExactArgs(1),
+	RunE: addMountRunE,
+}
+
+var addMountWorkspace string
+
+// parseMount parses the mount argument in the format HOST:CONTAINER[:ro|rw].
+func parseMount(arg string) (project.Mount, error) {
+
+	parts := strings.Split(arg, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return project.Mount{}, errdefs.InvalidArgument("invalid mount: '%s'", arg)
+	}
+
+	mount := project.Mount{Source: parts[0], Destination: parts[1]}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			mount.ReadOnly = true
+		case "rw":
+		default:
+			return project.Mount{},
+				errdefs.InvalidArgument("invalid mount option '%s', must be 'ro' or 'rw'",
+					parts[2])
+		}
+	}
+
+	if !filepath.IsAbs(mount.Source) || !filepath.IsAbs(mount.Destination) {
+		return project.Mount{}, errdefs.InvalidArgument("mount paths must be absolute: '%s'", arg)
+	}
+	if _, err := os.Stat(mount.Source); err != nil {
+		return project.Mount{}, errdefs.NotFound("host path", mount.Source)
+	}
+
+	return mount, nil
+}
+
+// updateSpec applies the mounts, environment variables, and resource limits of the workspace
+// to the existing workspace container.
+func updateSpec(ws *project.Workspace) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return ctr.UpdateSpec(conf, ws, user)
+}
+
+func addMountRunE(cmd *cobra.Command, args []string) error {
+
+	mount, err := parseMount(args[0])
+	if err != nil {
+		return err
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, addMountWorkspace)
+	if err != nil {
+		return err
+	}
+
+	err = ws.AddMount(mount)
+	if err != nil {
+		return err
+	}
+
+	err = prj.Write()
+	if err != nil {
+		return err
+	}
+
+	return updateSpec(ws)
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+	addCmd.AddCommand(addMountCmd)
+	addMountCmd.Flags().StringVarP(
+		&addMountWorkspace, "workspace", "w", "", "Name of the workspace")
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCliParseMount(t *testing.T) {
+
+	dir := os.TempDir()
+
+	mount, err := parseMount(dir + ":/work")
+	if err != nil || mount.Source != dir || mount.Destination != "/work" || mount.ReadOnly {
+		t.Errorf("Unexpected mount: %v %v", mount, err)
+	}
+	mount, err = parseMount(dir + ":/work:ro")
+	if err != nil || !mount.ReadOnly {
+		t.Errorf("Expected read-only mount: %v %v", mount, err)
+	}
+
+	for _, arg := range []string{
+		dir,
+		dir + ":/work:xx",
+		dir + ":work",
+		"src:/work",
+		"/nonexistent/cne/path:/work",
+		dir + ":/work:ro:rw",
+	} {
+		if _, err := parseMount(arg); err == nil {
+			t.Errorf("Mount '%s' should fail", arg)
+		}
+	}
+}
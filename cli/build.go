@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -29,25 +31,26 @@ func createContainer(run runtime.Runtime, ws *project.Workspace) (*container.Con
 	// check and pull the image, if required, for building the container
 	img, err := run.GetImage(ws.Environment.Origin)
 	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
-		img, err = pullImage(run, ws.Environment.Origin)
+		img, err = pullImage(run, ws.Environment.Origin, "")
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	ctr, err := container.NewContainer(run, &user, ws, img)
+	ctr, err := container.NewContainer(run, conf, &user, ws, img)
 	if err != nil {
 		return nil, err
 	}
 
-	err = ctr.Create()
+	warnings, err := ctr.Create()
 	if err != nil && errors.Is(err, errdefs.ErrAlreadyExists) {
 		run.DeleteContainer(ctr.Domain, ctr.ID, ctr.Generation)
-		err = ctr.Create()
+		warnings, err = ctr.Create()
 	}
 	if err != nil {
 		return nil, err
 	}
+	printWarnings(warnings)
 
 	return ctr, err
 }
@@ -61,8 +64,10 @@ func createContainer(run runtime.Runtime, ws *project.Workspace) (*container.Con
 func buildLayers(run runtime.Runtime, ctr *container.Container,
 	ws *project.Workspace, layerCount int) error {
 
-	con := console.Current()
-	defer con.Reset()
+	// restore the terminal after the build, which isn't required if the output is redirected
+	if con, err := console.ConsoleFromFile(os.Stdout); err == nil {
+		defer con.Reset()
+	}
 
 	// build the container and provide progress output
 	var wg sync.WaitGroup
@@ -99,19 +104,51 @@ func buildLayers(run runtime.Runtime, ctr *container.Container,
 
 // commitContainer commits the container
 func commitContainer(ctr *container.Container, ws *project.Workspace) error {
-	return ctr.Commit(ws, user, ws.Path)
+	return ctr.Commit(conf, ws, user, ws.Path)
+}
+
+// postBuildContainer runs the post-build command of the workspace, if defined, and streams
+// the output to the provided stream.
+func postBuildContainer(ctr *container.Container, ws *project.Workspace,
+	stream runtime.Stream) error {
+
+	if len(ws.PostBuild.Args) == 0 {
+		return nil
+	}
+
+	return ctr.PostBuild(ws, &user, stream)
 }
 
 // buildContainer builds the container for the provided workspace and outputs progress status.
 // Note that in an error case, it will keep any residual container and snapshots.
 func buildContainer(run runtime.Runtime, ws *project.Workspace) (*container.Container, error) {
+	return buildContainerOutput(run, ws, true)
+}
+
+// buildContainerOutput builds the container for the provided workspace. The build outputs the
+// progress and command output only if showProgress is set, which allows for building multiple
+// containers in parallel.
+func buildContainerOutput(run runtime.Runtime, ws *project.Workspace,
+	showProgress bool) (*container.Container, error) {
 
 	ctr, err := createContainer(run, ws)
 	if err != nil {
 		return nil, err
 	}
 
-	err = buildLayers(run, ctr, ws, -1)
+	stream := runtime.Stream{
+		Stdin:    nil,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Terminal: false,
+	}
+
+	if showProgress {
+		err = buildLayers(run, ctr, ws, -1)
+	} else {
+		stream = NewRingBuffer(outputLineCount, outputLineLength).StreamWriter()
+		err = ctr.Build(ws, -1, &user, &params, nil, stream)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -121,9 +158,184 @@ func buildContainer(run runtime.Runtime, ws *project.Workspace) (*container.Cont
 		return nil, err
 	}
 
+	err = postBuildContainer(ctr, ws, stream)
+	if err != nil {
+		return nil, err
+	}
+
 	return ctr, nil
 }
 
+// buildOptions are the options for rebuilding an existing container.
+type buildOptions struct {
+	force   bool
+	noCache bool
+	upgrade string
+}
+
+// rebuild returns true if an existing container should be rebuilt.
+func (opts buildOptions) rebuild() bool {
+	return opts.force || opts.noCache || opts.upgrade != ""
+}
+
+// removeContainer deletes an existing container of the workspace for rebuilding it. The force
+// and upgrade options also delete the snapshots of the container.
+func (opts buildOptions) removeContainer(run runtime.Runtime, ws *project.Workspace) error {
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+	if err == nil {
+		if opts.force || opts.upgrade != "" {
+			err = ctr.Purge()
+		} else {
+			err = ctr.Delete()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// clear the cache after deleting the container, which is found by the layer snapshots
+	if opts.noCache {
+		ws.ClearLayerCache()
+	}
+	return nil
+}
+
+// buildOrder returns the index of the workspace that must be built before each workspace or
+// -1 if the build can start right away. Workspaces with the same origin are built in the
+// provided order, so the image is pulled only once.
+func buildOrder(workspaces []*project.Workspace) []int {
+
+	after := make([]int, len(workspaces))
+	last := map[string]int{}
+	for i, ws := range workspaces {
+		after[i] = -1
+		if ws.Environment.Origin == "" {
+			continue
+		}
+		if j, ok := last[ws.Environment.Origin]; ok {
+			after[i] = j
+		}
+		last[ws.Environment.Origin] = i
+	}
+	return after
+}
+
+// buildWorkspaces builds the containers for the provided workspaces with up to 'parallel'
+// builds at a time and shows the progress for each workspace. Images are pulled before
+// starting the builds, and builds of workspaces with the same origin wait for the previous
+// build. Existing containers are skipped unless the options request a rebuild. If failFast
+// is set, no further builds are started after a build failed. It returns the build error for
+// each workspace.
+func buildWorkspaces(run runtime.Runtime, workspaces []*project.Workspace,
+	opts buildOptions, parallel int, failFast bool) []error {
+
+	errs := make([]error, len(workspaces))
+	after := buildOrder(workspaces)
+
+	for i, ws := range workspaces {
+		if ws.Environment.Origin == "" || after[i] != -1 {
+			continue
+		}
+		_, err := run.GetImage(ws.Environment.Origin)
+		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+			pullImage(run, ws.Environment.Origin, "") // build reports errors
+		}
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var wg sync.WaitGroup
+	var progWg sync.WaitGroup
+
+	progress := make(chan []runtime.ProgressStatus)
+	progWg.Add(1)
+	go func() {
+		defer progWg.Done()
+		showBuildProgress(progress)
+	}()
+
+	status := func(ws *project.Workspace, stat, details string) {
+		progress <- []runtime.ProgressStatus{{
+			Reference: ws.Name,
+			Status:    stat,
+			Details:   details,
+			UpdatedAt: time.Now(),
+		}}
+	}
+
+	for _, ws := range workspaces {
+		status(ws, runtime.StatusPending, "")
+	}
+
+	var failed int32
+	var mutex sync.Mutex
+
+	done := make([]chan struct{}, len(workspaces))
+	for i := range workspaces {
+		done[i] = make(chan struct{})
+	}
+
+	sem := make(chan struct{}, parallel)
+	for i, ws := range workspaces {
+		wg.Add(1)
+		go func(i int, ws *project.Workspace) {
+			defer wg.Done()
+			defer close(done[i])
+
+			if after[i] != -1 {
+				<-done[after[i]]
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mutex.Lock()
+			abort := failFast && failed != 0
+			mutex.Unlock()
+			if abort {
+				errs[i] = errdefs.Canceled("build", ws.Name)
+				status(ws, runtime.StatusAborted, "")
+				return
+			}
+
+			status(ws, runtime.StatusRunning, "Building")
+
+			var err error
+			if opts.rebuild() {
+				err = opts.removeContainer(run, ws)
+			} else if _, err = container.Get(run, ws); err == nil {
+				status(ws, runtime.StatusExists, "")
+				return
+			} else if errors.Is(err, errdefs.ErrNotFound) {
+				err = nil
+			}
+			if err == nil {
+				_, err = buildContainerOutput(run, ws, false)
+			}
+			errs[i] = err
+			if err != nil {
+				mutex.Lock()
+				failed++
+				mutex.Unlock()
+				status(ws, runtime.StatusError, "")
+			} else {
+				status(ws, runtime.StatusComplete, "")
+			}
+		}(i, ws)
+	}
+
+	wg.Wait()
+	close(progress)
+	progWg.Wait()
+
+	return errs
+}
+
 var buildCmd = &cobra.Command{
 	Use:     "build",
 	Short:   "Build or rebuild an object",
@@ -132,53 +344,78 @@ var buildCmd = &cobra.Command{
 }
 
 var buildWorkspaceCmd = &cobra.Command{
-	Use:     "workspace [NAME]",
-	Short:   "Manually build or rebuild the current or specified workspace",
+	Use:   "workspace [NAME]",
+	Short: "Manually build or rebuild the current or specified workspace",
+	Long: `
+Build the container of the current or specified workspace. Layers are built on
+the cached snapshots of previous builds. The no-cache option rebuilds all layers
+from the base image and replaces an existing container without deleting the
+cached snapshots. The force option deletes an existing container including its
+snapshots before rebuilding it, and can be combined with no-cache to also
+ignore the snapshots of other containers.`,
 	Aliases: []string{"ws"},
 	Args:    cobra.MaximumNArgs(1),
 	RunE:    buildWorkspaceRunE,
 }
 
 var buildWorkspaceForce bool
+var buildWorkspaceNoCache bool
+var buildWorkspaceAll bool
 var buildWorkspaceUpgrade string
 
+// buildWorkspaceOptions returns the build options of the command line flags.
+func buildWorkspaceOptions() buildOptions {
+	return buildOptions{
+		force:   buildWorkspaceForce,
+		noCache: buildWorkspaceNoCache,
+		upgrade: buildWorkspaceUpgrade,
+	}
+}
+
 func buildWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
+	if buildWorkspaceAll {
+		if len(args) != 0 {
+			return errdefs.InvalidArgument("workspace name and all option are exclusive")
+		}
+		return buildAllRunE(cmd, args)
+	}
+
 	prj, err := loadProject()
 	if err != nil {
 		return err
 	}
 
-	ws, err := prj.CurrentWorkspace()
+	name := ""
 	if len(args) != 0 {
-		ws, err = prj.Workspace(args[0])
+		name = args[0]
 	}
+	ws, err := getWorkspace(prj, name)
 	if err != nil {
 		return err
 	}
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
 	defer run.Close()
 
 	// only allow a single build container at a time
-	ctr, err := container.Get(run, ws)
-	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
-		return err
-	}
-	if err != nil {
-		if !buildWorkspaceForce && buildWorkspaceUpgrade == "" {
+	opts := buildWorkspaceOptions()
+	if !opts.rebuild() {
+		ctr, err := container.Get(run, ws)
+		if err == nil {
 			return errdefs.AlreadyExists("container", ctr.Name)
 		}
-		err = ctr.Purge()
-		if err != nil {
+		if !errors.Is(err, errdefs.ErrNotFound) {
 			return err
 		}
+	} else if err = opts.removeContainer(run, ws); err != nil {
+		return err
 	}
 
-	params.Upgrade = buildWorkspaceUpgrade
+	params.Upgrade = opts.upgrade
 	_, err = buildContainer(run, ws)
 	if err != nil {
 		return err
@@ -188,12 +425,99 @@ func buildWorkspaceRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var buildAllCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Build the containers of all workspaces",
+	Long: `
+Build the containers of all workspaces in the order of the project. Workspaces
+with an existing container are skipped unless the force, no-cache, or upgrade
+option is set, which rebuild the containers like for a single workspace. The
+parallel option defines the maximum number of containers that are built at the
+same time. Workspaces with the same origin are built one after the other, so
+the image is pulled only once. A failed build doesn't stop the builds of the
+other workspaces unless the fail-fast option is set.`,
+	Args: cobra.NoArgs,
+	RunE: buildAllRunE,
+}
+
+var buildAllParallel int
+var buildAllFailFast bool
+
+func buildAllRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	workspaces := make([]*project.Workspace, len(prj.Workspaces))
+	for i := range prj.Workspaces {
+		workspaces[i] = &prj.Workspaces[i]
+	}
+
+	opts := buildWorkspaceOptions()
+	params.Upgrade = opts.upgrade
+	errs := buildWorkspaces(run, workspaces, opts, buildAllParallel, buildAllFailFast)
+
+	err = prj.Write()
+	if err != nil {
+		return err
+	}
+
+	summary := make([]struct {
+		Workspace string
+		Result    string
+	}, len(workspaces))
+	failed := 0
+	for i, ws := range workspaces {
+		summary[i].Workspace = ws.Name
+		summary[i].Result = "ok"
+		if errs[i] != nil {
+			summary[i].Result = errs[i].Error()
+			failed++
+		}
+	}
+	fmt.Println()
+	printList(summary, false)
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d workspaces failed to build", failed, len(workspaces))
+	}
+	return nil
+}
+
 func init() {
 
 	rootCmd.AddCommand(buildCmd)
+	buildCmd.AddCommand(buildAllCmd)
+	buildAllCmd.Flags().IntVar(
+		&buildAllParallel, "parallel", 1, "Maximum number of parallel builds")
+	buildAllCmd.Flags().BoolVar(
+		&buildAllFailFast, "fail-fast", false, "Don't start further builds after a build failed")
+	buildAllCmd.Flags().BoolVar(
+		&buildWorkspaceForce, "force", false, "Force a rebuild of the containers")
+	buildAllCmd.Flags().BoolVar(
+		&buildWorkspaceNoCache, "no-cache", false, "Rebuild all layers without cached snapshots")
+	buildAllCmd.Flags().StringVar(
+		&buildWorkspaceUpgrade, "upgrade", "", "Upgrade image, apt, all")
 	buildCmd.AddCommand(buildWorkspaceCmd)
 	buildWorkspaceCmd.Flags().BoolVar(
 		&buildWorkspaceForce, "force", false, "Force a rebuild of the container")
+	buildWorkspaceCmd.Flags().BoolVar(
+		&buildWorkspaceNoCache, "no-cache", false, "Rebuild all layers without cached snapshots")
+	buildWorkspaceCmd.Flags().BoolVar(
+		&buildWorkspaceAll, "all", false, "Build the containers of all workspaces")
+	buildWorkspaceCmd.Flags().IntVar(
+		&buildAllParallel, "parallel", 1, "Maximum number of parallel builds with --all")
+	buildWorkspaceCmd.Flags().BoolVar(
+		&buildAllFailFast, "fail-fast", false,
+		"Don't start further builds after a build failed with --all")
 	buildWorkspaceCmd.Flags().StringVar(
 		&buildWorkspaceUpgrade, "upgrade", "", "Upgrade image, apt, all")
 }
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+// setupBuildProject creates a project with a workspace that has a cached layer snapshot and
+// an existing container in the mock runtime. It returns the project and the mock runtime.
+func setupBuildProject(t *testing.T, dir string) (*project.Project, *mock.Runtime) {
+
+	prj, err := project.Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	ws, err := prj.CreateWorkspace("main", "docker.io/library/busybox:latest", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	layer, err := ws.CreateLayer(false, "tools", -1)
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Digest = "snapshot-0"
+	prj.CurrentWorkspaceName = ws.Name
+	if err = prj.Write(); err != nil {
+		t.Fatalf("Failed to write project: %v", err)
+	}
+
+	run := mock.Namespace("test")
+	img := run.AddImage(ws.Environment.Origin, 1000)
+	dom, _ := uuid.Parse(ws.ProjectUUID)
+	runCtr, err := run.NewContainer(dom, ws.ID(), ws.ConfigHash(), 0, "test", img, nil)
+	if err == nil {
+		_, err = runCtr.Create()
+	}
+	if err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	return prj, run
+}
+
+func TestBuildWorkspaceForceNoCache(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	prj, run := setupBuildProject(t, dir)
+	ws := &prj.Workspaces[0]
+	dom, _ := uuid.Parse(ws.ProjectUUID)
+	gen := ws.ConfigHash()
+
+	defer func() { buildWorkspaceForce, buildWorkspaceNoCache = false, false }()
+	buildWorkspaceForce, buildWorkspaceNoCache = true, true
+
+	// the container with the cached layers must be purged before rebuilding it
+	purgeErr := errors.New("purge failed")
+	run.Fail("Purge", purgeErr)
+	if err := buildWorkspaceRunE(buildWorkspaceCmd, []string{}); err != purgeErr {
+		t.Fatalf("Container with the cached layers not purged: %v", err)
+	}
+	run.Fail("Purge", nil)
+
+	if err := buildWorkspaceRunE(buildWorkspaceCmd, []string{}); err != nil {
+		t.Fatalf("Failed to build workspace: %v", err)
+	}
+
+	if _, err := run.GetContainer(dom, ws.ID(), gen); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Container with the cached layers not deleted: %v", err)
+	}
+}
+
+func TestBuildAllOptions(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	_, run := setupBuildProject(t, dir)
+
+	// fail deleting the existing container to detect a rebuild
+	failErr := errors.New("failed")
+	run.Fail("Delete", failErr)
+	run.Fail("Purge", failErr)
+	defer func() {
+		buildWorkspaceForce, buildWorkspaceNoCache, buildWorkspaceUpgrade = false, false, ""
+	}()
+	tests := []struct {
+		force   bool
+		noCache bool
+		upgrade string
+		fail    bool
+	}{
+		{false, false, "", false},
+		{true, false, "", true},
+		{false, true, "", true},
+		{false, false, "apt", true},
+	}
+	for _, tc := range tests {
+		buildWorkspaceForce, buildWorkspaceNoCache, buildWorkspaceUpgrade =
+			tc.force, tc.noCache, tc.upgrade
+		err := buildAllRunE(buildAllCmd, []string{})
+		if tc.fail && err == nil {
+			t.Errorf("Force %t, no-cache %t, upgrade '%s': container not rebuilt",
+				tc.force, tc.noCache, tc.upgrade)
+		} else if !tc.fail && err != nil {
+			t.Errorf("Existing container should be skipped: %v", err)
+		}
+	}
+}
+
+func TestBuildOrder(t *testing.T) {
+
+	tests := []struct {
+		origins []string
+		after   []int
+	}{
+		{[]string{}, []int{}},
+		{[]string{"a", "b", "c"}, []int{-1, -1, -1}},
+		{[]string{"a", "b", "a", "a"}, []int{-1, -1, 0, 2}},
+		{[]string{"", "", "b"}, []int{-1, -1, -1}},
+	}
+	for _, tc := range tests {
+		workspaces := make([]*project.Workspace, len(tc.origins))
+		for i, origin := range tc.origins {
+			workspaces[i] = &project.Workspace{Environment: project.Environment{Origin: origin}}
+		}
+		after := buildOrder(workspaces)
+		if len(after) != len(tc.after) {
+			t.Fatalf("Origins %v: expected %v, got %v", tc.origins, tc.after, after)
+		}
+		for i := range after {
+			if after[i] != tc.after[i] {
+				t.Errorf("Origins %v: expected %v, got %v", tc.origins, tc.after, after)
+				break
+			}
+		}
+	}
+}
+
+func TestBuildWorkspacesOrigin(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	prj, err := project.Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	for _, w := range []struct{ name, origin string }{
+		{"first", "docker.io/library/busybox:latest"},
+		{"other", "docker.io/library/alpine:latest"},
+		{"second", "docker.io/library/busybox:latest"},
+	} {
+		ws, err := prj.CreateWorkspace(w.name, w.origin, "")
+		if err != nil {
+			t.Fatalf("Failed to create workspace: %v", err)
+		}
+		layer, err := ws.CreateLayer(false, "tools", -1)
+		if err != nil {
+			t.Fatalf("Failed to create layer: %v", err)
+		}
+		layer.Commands = []project.Command{{Args: []string{"build-" + w.name}}}
+	}
+
+	// the first build is the slowest, so the second build would finish first without waiting
+	var mutex sync.Mutex
+	var builds []string
+	run := mock.Namespace("test")
+	run.SetExecFunc(func(stream runtime.Stream, args []string) uint32 {
+		cmd := strings.Join(args, " ")
+		if strings.Contains(cmd, "build-first") {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mutex.Lock()
+		builds = append(builds, cmd)
+		mutex.Unlock()
+		return 0
+	})
+
+	workspaces := make([]*project.Workspace, len(prj.Workspaces))
+	for i := range prj.Workspaces {
+		workspaces[i] = &prj.Workspaces[i]
+	}
+	for i, err := range buildWorkspaces(run, workspaces, buildOptions{}, 3, false) {
+		if err != nil {
+			t.Fatalf("Failed to build workspace %s: %v", workspaces[i].Name, err)
+		}
+	}
+
+	first, second := -1, -1
+	for i, cmd := range builds {
+		if strings.Contains(cmd, "build-first") {
+			first = i
+		} else if strings.Contains(cmd, "build-second") {
+			second = i
+		}
+	}
+	if first == -1 || second == -1 || first > second {
+		t.Errorf("Workspace with the same origin built before the first workspace: %v", builds)
+	}
+}
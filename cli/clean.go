@@ -25,7 +25,7 @@ func cleanProjectRunE(cmd *cobra.Command, args []string) error {
 
 	var prj *project.Project
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -37,10 +37,11 @@ func cleanProjectRunE(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	}
-	ctrs, err := container.Containers(run, prj, &user)
+	ctrs, warnings, err := container.Containers(run, prj, &user)
 	if err != nil {
 		return err
 	}
+	printWarnings(warnings)
 	for _, c := range ctrs {
 		c.Purge() // Ignore errors
 	}
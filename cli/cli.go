@@ -2,6 +2,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/errdefs"
 	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
 )
 
 var conf *config.Config
@@ -21,6 +23,11 @@ var basenamee string
 var rootCneVersion bool
 
 var projectPath string
+var configFile string
+var outputName string
+var colorMode string
+var runtimeProfile string
+var runtimeNamespace string
 
 // helper function to load the project
 func loadProject() (*project.Project, error) {
@@ -38,6 +45,14 @@ func loadProject() (*project.Project, error) {
 	return project.Load(projectPath)
 }
 
+// overrideRuntimeConfig applies the runtime options of the command line to the runtime
+// configuration that is used for opening the runtime.
+func overrideRuntimeConfig(confRun *config.Runtime) {
+	if runtimeNamespace != "" {
+		confRun.Namespace = runtimeNamespace
+	}
+}
+
 var rootCmd = &cobra.Command{
 	SilenceErrors: true,
 	SilenceUsage:  true,
@@ -48,16 +63,40 @@ virtual environment based on containers to provide a reliable and
 reproducible environment for development and other use cases, such as
 machine learning or analytics.
 `,
-	Run: rootRun,
+	Run:               rootRun,
+	PersistentPreRunE: rootPersistentPreRunE,
+}
+
+// skipValidation is the annotation for commands that run with an invalid configuration, for
+// example, to correct the configuration.
+const skipValidation = "cne.skipValidation"
+
+// rootPersistentPreRunE validates the configuration before a command is run.
+func rootPersistentPreRunE(cmd *cobra.Command, args []string) error {
+	if !cmd.HasParent() || cmd.Name() == "help" {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[skipValidation]; ok {
+			return nil
+		}
+	}
+	return conf.Validate(runtime.Runtimes())
 }
 
 var rootVersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Display the version",
-	Args:  cobra.NoArgs,
-	Run:   rootVersionRun,
+	Long: `
+Display the version of cne and, with the --runtime option, the
+runtime and the version of its daemon.`,
+	Args:        cobra.NoArgs,
+	RunE:        rootVersionRunE,
+	Annotations: map[string]string{skipValidation: ""},
 }
 
+var rootVersionRuntime bool
+
 func rootRun(cmd *cobra.Command, args []string) {
 	if rootCneVersion {
 		rootVersionRun(cmd, args)
@@ -74,13 +113,59 @@ func rootVersionRun(cmd *cobra.Command, args []string) {
 	os.Exit(0)
 }
 
+// versionInfo describes the version of cne and the runtime.
+type versionInfo struct {
+	Version string
+	Runtime runtime.VersionInfo
+}
+
+// showVersion prints the version of cne and the runtime. If the daemon of the runtime cannot be
+// reached, it prints the known runtime information and returns the error.
+func showVersion(run runtime.Runtime) error {
+
+	runInfo, err := run.Version()
+	printValue("Field", "Value", "", &versionInfo{
+		Version: config.CneVersion,
+		Runtime: runInfo,
+	})
+	return err
+}
+
+func rootVersionRunE(cmd *cobra.Command, args []string) error {
+
+	if !rootVersionRuntime {
+		rootVersionRun(cmd, args)
+		return nil
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	return showVersion(run)
+}
+
 func init() {
 	rootCmd.Use = filepath.Base(os.Args[0])
 	rootCmd.Flags().BoolVar(
 		&rootCneVersion, "version", false, "Get version information")
 	rootCmd.PersistentFlags().StringVarP(
 		&projectPath, "project", "P", "", "Projet path")
+	rootCmd.PersistentFlags().StringVar(
+		&configFile, "config", "", "Configuration file that replaces the user configuration")
+	rootCmd.PersistentFlags().StringVarP(
+		&outputName, "output", "o", "text", "Output format (text, json, yaml)")
+	rootCmd.PersistentFlags().StringVar(
+		&runtimeProfile, "runtime-profile", "", "Name of the runtime profile")
+	rootCmd.PersistentFlags().StringVar(
+		&runtimeNamespace, "namespace", "", "Override the namespace of the runtime")
+	rootCmd.PersistentFlags().StringVar(
+		&colorMode, "color", "auto", "Colorize the output (auto, always, never)")
 	rootCmd.AddCommand(rootVersionCmd)
+	rootVersionCmd.Flags().BoolVar(
+		&rootVersionRuntime, "runtime", false, "Include the version of the runtime")
 	cobra.OnInitialize(initConfig)
 }
 
@@ -90,22 +175,69 @@ func Execute() error {
 
 	err := rootCmd.Execute()
 	if err != nil && errdefs.IsCneError(err) {
+		hint := errorHint(err)
 		err = fmt.Errorf("%s: %v", basenamee, err)
+		if hint != "" {
+			err = fmt.Errorf("%v\n%s", err, hint)
+		}
 	}
 	return err
 }
 
+// errorHint returns guidance for resolving errors for accessing the runtime socket.
+func errorHint(err error) string {
+
+	if errdefs.Resource(err) != runtime.SocketResource {
+		return ""
+	}
+
+	switch {
+	case errors.Is(err, errdefs.ErrNotFound):
+		return "Is containerd running? Check the socket in the runtime configuration."
+	case errors.Is(err, errdefs.ErrPermissionDenied):
+		return "Try with sudo or add yourself to the group that owns the socket."
+	case errors.Is(err, errdefs.ErrUnavailable):
+		return "Is containerd running and does its version support the client?"
+	}
+	return ""
+}
+
 func initConfig() {
 
 	var err error
 	basenamee = filepath.Base(os.Args[0])
 
+	err = setOutputFormat(outputName)
+	if err != nil {
+		fmt.Printf("%s: %v\n", basenamee, err)
+		os.Exit(1)
+	}
+
+	err = setColorMode(colorMode)
+	if err != nil {
+		fmt.Printf("%s: %v\n", basenamee, err)
+		os.Exit(1)
+	}
+
+	err = config.SetUserConfigFile(configFile)
+	if err != nil {
+		fmt.Printf("%s: %v\n", basenamee, err)
+		os.Exit(1)
+	}
+
 	conf, err = config.Load()
 	if err != nil {
 		fmt.Printf("%s: %v\n", basenamee, err)
 		os.Exit(1)
 	}
 
+	err = conf.SelectRuntimeProfile(runtimeProfile)
+	if err != nil {
+		fmt.Printf("%s: %v\n", basenamee, err)
+		os.Exit(1)
+	}
+	overrideRuntimeConfig(&conf.Runtime)
+
 	user, err = conf.User()
 	if err != nil {
 		fmt.Printf("%s: %v\n", basenamee, err)
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+// testRuntime provides the runtime functions used by the cli package.
+type testRuntime struct {
+	runtime.Runtime
+	imgs    []runtime.Image
+	snaps   []runtime.Snapshot
+	ctrIDs  []string
+	deleted []string
+}
+
+func (run *testRuntime) Images() ([]runtime.Image, error) {
+	return run.imgs, nil
+}
+
+func (run *testRuntime) GetImage(name string) (runtime.Image, error) {
+	for _, img := range run.imgs {
+		if img.Name() == name {
+			return img, nil
+		}
+	}
+	return nil, errdefs.NotFound("image", name)
+}
+
+func (run *testRuntime) DeleteImage(name string) error {
+	run.deleted = append(run.deleted, name)
+	return nil
+}
+
+func (run *testRuntime) Snapshots() ([]runtime.Snapshot, error) {
+	return run.snaps, nil
+}
+
+func (run *testRuntime) ContainerIDs() ([]string, error) {
+	return run.ctrIDs, nil
+}
+
+func (run *testRuntime) DeleteSnapshot(name string) error {
+	run.deleted = append(run.deleted, name)
+	return nil
+}
+
+type testImage struct {
+	runtime.Image
+	name      string
+	createdAt time.Time
+	labels    map[string]string
+	rootfs    []digest.Digest
+	digest    digest.Digest
+	size      int64
+}
+
+func (img *testImage) Name() string {
+	return img.name
+}
+
+func (img *testImage) Digest() digest.Digest {
+	return img.digest
+}
+
+func (img *testImage) Size() int64 {
+	return img.size
+}
+
+func (img *testImage) CreatedAt() time.Time {
+	return img.createdAt
+}
+
+func (img *testImage) Labels() map[string]string {
+	return img.labels
+}
+
+func (img *testImage) RootFS() ([]digest.Digest, error) {
+	return img.rootfs, nil
+}
+
+func (img *testImage) Tag(name string) (runtime.Image, error) {
+	return &testImage{name: name, createdAt: img.createdAt, rootfs: img.rootfs}, nil
+}
+
+type testSnapshot struct {
+	runtime.Snapshot
+	name       string
+	parent     string
+	kind       string
+	createdAt  time.Time
+	lastUsedAt time.Time
+}
+
+func (snap *testSnapshot) Name() string {
+	return snap.name
+}
+
+func (snap *testSnapshot) Parent() string {
+	return snap.parent
+}
+
+func (snap *testSnapshot) Kind() string {
+	return snap.kind
+}
+
+func (snap *testSnapshot) CreatedAt() time.Time {
+	return snap.createdAt
+}
+
+func (snap *testSnapshot) LastUsedAt() time.Time {
+	if snap.lastUsedAt.IsZero() {
+		return snap.createdAt
+	}
+	return snap.lastUsedAt
+}
+
+func (snap *testSnapshot) Labels() map[string]string {
+	return nil
+}
+
+// setupCliTest creates a temporary project directory and selects the mock runtime for the
+// commands. The directory is also used as the runtime directory for the log buffers. The
+// returned function restores the configuration, resets the mock runtime, and removes the
+// directory.
+func setupCliTest(t *testing.T) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "cnetest")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+
+	savedConf, savedPath := conf, projectPath
+	conf = &config.Config{Runtime: config.Runtime{Name: "mock", Namespace: "test"}}
+	projectPath = dir
+
+	savedRuntimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+
+	return dir, func() {
+		conf, projectPath = savedConf, savedPath
+		os.Setenv("XDG_RUNTIME_DIR", savedRuntimeDir)
+		mock.Reset()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCliNamespaceOverride(t *testing.T) {
+
+	defer func() { runtimeNamespace = "" }()
+
+	confRun := config.Runtime{Namespace: config.DefaultExecRuntimeNamespace}
+	overrideRuntimeConfig(&confRun)
+	if confRun.Namespace != config.DefaultExecRuntimeNamespace {
+		t.Errorf("Namespace changed without override: '%s'", confRun.Namespace)
+	}
+
+	runtimeNamespace = "other"
+	overrideRuntimeConfig(&confRun)
+	if confRun.Namespace != "other" {
+		t.Errorf("Namespace not overridden: '%s'", confRun.Namespace)
+	}
+}
+
+func TestCliErrorHint(t *testing.T) {
+
+	socket := "/run/containerd/containerd.sock"
+	if hint := errorHint(errdefs.NotFound(runtime.SocketResource, socket)); hint == "" {
+		t.Errorf("Expected a hint for a missing socket")
+	}
+	if hint := errorHint(errdefs.PermissionDenied(runtime.SocketResource, socket)); hint == "" {
+		t.Errorf("Expected a hint for missing permissions")
+	}
+	if hint := errorHint(errdefs.NotFound("image", "busybox")); hint != "" {
+		t.Errorf("Unexpected hint for a missing image: %s", hint)
+	}
+}
+
+func TestCliShowVersion(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+
+	const expected = "FIELD            VALUE\n" +
+		"Version          \n" +
+		"Runtime/Name     mock\n" +
+		"Runtime/Socket   \n" +
+		"Runtime/Version  1.0.0\n" +
+		"Runtime/Revision \n"
+
+	var err error
+	errPos, out := compareFuncOutput(func() { err = showVersion(run) }, expected)
+	if err != nil {
+		t.Errorf("Failed to get version: %v", err)
+	}
+	if errPos != -1 {
+		t.Errorf("Unexpected output at %d:\n%s", errPos, out)
+	}
+
+	run.Fail("Version", errdefs.Unavailable(runtime.SocketResource, "sock", errors.New("down")))
+	errPos, out = compareFuncOutput(func() { err = showVersion(run) }, "")
+	if !errors.Is(err, errdefs.ErrUnavailable) {
+		t.Errorf("Expected unavailable error, got %v", err)
+	}
+	if !strings.Contains(out, "Runtime/Name     mock") {
+		t.Errorf("Expected the runtime name for an unavailable daemon, got:\n%s", out)
+	}
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone a resource",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var cloneWorkspaceCmd = &cobra.Command{
+	Use:     "workspace SRC [DST]",
+	Aliases: []string{"ws"},
+	Short:   "Clone a workspace",
+	Long: `
+Clone a workspace with its environment, layers, and mounts into a new workspace.
+If DST is omitted, the name of the source workspace with a '-clone' suffix is
+used. The cloned workspace has its own container, which has to be built with
+'cne build'.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: cloneWorkspaceRunE,
+}
+
+func cloneWorkspaceRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	dst := ""
+	if len(args) > 1 {
+		dst = args[1]
+	}
+
+	_, err = prj.CloneWorkspace(args[0], dst)
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+	cloneCmd.AddCommand(cloneWorkspaceCmd)
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit [MESSAGE]",
+	Short: "Commit the current state of the workspace container",
+	Long: `
+Commit the current filesystem of the workspace container as a snapshot and record
+the commit with the message in the workspace. The container must exist.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: commitRunE,
+}
+
+var commitWorkspace string
+
+func commitRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, commitWorkspace)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.NotFound("container", ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = ctr.Checkpoint(ws, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+	commitCmd.Flags().StringVarP(
+		&commitWorkspace, "workspace", "w", "", "Name of the workspace")
+}
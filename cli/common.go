@@ -2,6 +2,9 @@ package cli
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -12,54 +15,120 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+
+	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/errdefs"
 	"github.com/czankel/cne/project"
 	"github.com/czankel/cne/runtime"
 )
 
-// scanLine splits up commands separated by a ',' into multiple command lines
+// scanLine splits up commands separated by a ',' into multiple command lines.
+// Commas inside single or double quotes and commas escaped with a '\' don't separate commands.
+// The quotes are preserved while the '\' of an escaped comma outside quotes is removed.
 func scanLine(line string) []project.Command {
 
-	line = strings.TrimSpace(line)
-	if len(line) == 0 {
-		return []project.Command{}
+	var commands []project.Command
+	var cmd strings.Builder
+
+	addCommand := func() {
+		if c := strings.TrimSpace(cmd.String()); c != "" {
+			commands = append(commands, project.Command{"", []string{}, []string{c}})
+		}
+		cmd.Reset()
 	}
 
-	var commands []project.Command
-	for {
-		pos := strings.IndexAny(line, ",")
-		if pos != -1 {
-			if pos > 0 {
-				commands = append(commands, project.Command{
-					"",
-					[]string{},
-					[]string{strings.TrimSpace(line[:pos])},
-				})
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			if r != ',' || quote != 0 {
+				cmd.WriteRune('\\')
 			}
-			line = strings.TrimSpace(line[pos+1:])
-		} else {
-			commands = append(commands,
-				project.Command{"", []string{}, []string{line}})
-			break
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			continue
+		case r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ',':
+			addCommand()
+			continue
 		}
+		cmd.WriteRune(r)
 	}
+	if escaped {
+		cmd.WriteRune('\\')
+	}
+	addCommand()
 
+	if commands == nil {
+		return []project.Command{}
+	}
 	return commands
 }
 
+// heredocDelimiter returns the delimiter if the line starts a heredoc block ('<<EOF'). The
+// delimiter can be quoted ('<<"EOF"' or "<<'EOF'").
+func heredocDelimiter(line string) (string, bool) {
+
+	if !strings.HasPrefix(line, "<<") {
+		return "", false
+	}
+
+	delim := strings.TrimSpace(line[2:])
+	if l := len(delim); l > 1 && (delim[0] == '"' || delim[0] == '\'') && delim[l-1] == delim[0] {
+		delim = delim[1 : l-1]
+	}
+	if delim == "" || strings.ContainsAny(delim, " \t\"'") {
+		return "", false
+	}
+	return delim, true
+}
+
 // readCommands reads commands from the io.Reader into a slice of strings
+// All lines between a '<<EOF' line and the closing 'EOF' line are combined into a single
+// command that is executed in a shell. The lines are passed unmodified to the shell.
 func readCommands(reader io.Reader) ([]project.Command, error) {
 
 	var commands []project.Command
+	var heredoc []string
+	delim := ""
+
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
+		if delim != "" {
+			if strings.TrimSpace(scanner.Text()) != delim {
+				heredoc = append(heredoc, scanner.Text())
+				continue
+			}
+			commands = append(commands, project.Command{
+				Envs: []string{},
+				Args: []string{"/bin/sh", "-c", strings.Join(heredoc, "\n")},
+			})
+			delim = ""
+			heredoc = nil
+			continue
+		}
+
 		line := strings.TrimSpace(scanner.Text())
+		if d, ok := heredocDelimiter(line); ok {
+			delim = d
+			continue
+		}
 		commands = append(commands,
-			project.Command{"", []string{}, []string{line}})
+			project.Command{Envs: []string{}, Args: []string{line}})
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, errdefs.InvalidArgument("unable to read line: %v", err)
 	}
+	if delim != "" {
+		return nil, errdefs.InvalidArgument("missing heredoc delimiter '%s'", delim)
+	}
 	return commands, nil
 }
 
@@ -83,6 +152,65 @@ func sizeToSIString(sz int64) string {
 	return fmt.Sprintf("%.1f%cB", float64(sz)/float64(div), "kMGTPE"[exp])
 }
 
+// siStringToSize converts a SI size string, such as '512M' or '2.5GB', to an integer value.
+// It is the inverse of sizeToSIString and uses the 10^3x exponent for the units.
+func siStringToSize(str string) (int64, error) {
+
+	num := strings.TrimSuffix(strings.TrimSpace(str), "B")
+	mult := int64(1)
+	if l := len(num); l > 0 {
+		if exp := strings.IndexByte("kMGTPE", num[l-1]); exp != -1 {
+			num = num[:l-1]
+			for ; exp >= 0; exp-- {
+				mult *= 1000
+			}
+		} else if num[l-1] == 'K' {
+			num = num[:l-1]
+			mult = 1000
+		}
+	}
+
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil || val < 0 {
+		return 0, errdefs.InvalidArgument("invalid size: '%s'", str)
+	}
+	return int64(val * float64(mult)), nil
+}
+
+// shortIDLength returns the configured length for displaying IDs and digests.
+func shortIDLength() int {
+	if conf != nil && conf.ShortIDLength > 0 {
+		return conf.ShortIDLength
+	}
+	return config.DefaultShortIDLength
+}
+
+// shortID returns the ID or digest truncated to n characters, or to the configured length
+// if n is 0. IDs shorter than the length are returned unmodified.
+func shortID(s string, n int) string {
+	if n <= 0 {
+		n = shortIDLength()
+	}
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// parseGeneration parses the hex encoded generation of a container.
+func parseGeneration(str string) ([16]byte, error) {
+
+	var gen [16]byte
+
+	b, err := hex.DecodeString(str)
+	if err != nil || len(b) != len(gen) {
+		return gen, errdefs.InvalidArgument("invalid generation: '%s'", str)
+	}
+	copy(gen[:], b)
+
+	return gen, nil
+}
+
 // timeToAgoString converts the timespan from the provided time to the current time to a string
 // in the formwat "T {year|month|hour}[s] ago". Future dates will return 'future'
 func timeToAgoString(t time.Time) string {
@@ -125,6 +253,29 @@ func timeToAgoString(t time.Time) string {
 	return "seconds ago"
 }
 
+// agoStringToTime converts a duration relative to the current time, such as "720h" or "30d",
+// or a RFC3339 timestamp to the time.
+func agoStringToTime(str string) (time.Time, error) {
+
+	t, err := time.Parse(time.RFC3339, str)
+	if err == nil {
+		return t, nil
+	}
+
+	var dur time.Duration
+	if strings.HasSuffix(str, "d") {
+		var days float64
+		days, err = strconv.ParseFloat(str[:len(str)-1], 64)
+		dur = time.Duration(days * float64(24*time.Hour))
+	} else {
+		dur, err = time.ParseDuration(str)
+	}
+	if err != nil || dur < 0 {
+		return time.Time{}, errdefs.InvalidArgument("invalid time or duration: '%s'", str)
+	}
+	return time.Now().Add(-dur), nil
+}
+
 // printValueElem prints the provided value as two columns for name and value content.
 // Struct  Each element is printed as a single row with the provided prefix for the name field
 //         For nested structures, the field names of each substructure are concatenated by '.'
@@ -182,28 +333,392 @@ func printValueElem(w *tabwriter.Writer, prefix string, elem reflect.Value, flat
 			}
 		}
 	} else if kind == reflect.Ptr {
+		if elem.IsNil() {
+			return
+		}
 		printValueElem(w, prefix, elem.Elem(), false)
 	} else if elem.CanInterface() {
 		fmt.Fprintf(w, "%s\t%v\n", prefix, elem.Interface())
 	}
 }
 
+// outputFormat defines the format used by printValue and printList.
+type outputFormat int
+
+const (
+	outputText outputFormat = iota
+	outputJSON
+	outputYAML
+)
+
+var outputFormats = map[string]outputFormat{
+	"text": outputText,
+	"json": outputJSON,
+	"yaml": outputYAML,
+}
+
+// output is the output format selected with the --output option.
+var output = outputText
+
+// setOutputFormat selects the output format by name.
+func setOutputFormat(name string) error {
+	format, ok := outputFormats[name]
+	if !ok {
+		return errdefs.InvalidArgument("invalid output format '%s'", name)
+	}
+	output = format
+	return nil
+}
+
+// ANSI color codes used for the text output.
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// colorEnabled is set if the text output should be colorized.
+var colorEnabled = false
+
+// setColorMode enables or disables colorized output for the mode selected with the
+// --color option: 'always', 'never', or 'auto', which enables colors only if stdout is
+// a terminal and the NO_COLOR environment variable isn't set.
+func setColorMode(mode string) error {
+	switch mode {
+	case "always":
+		colorEnabled = true
+	case "never":
+		colorEnabled = false
+	case "auto":
+		_, noColor := os.LookupEnv("NO_COLOR")
+		colorEnabled = !noColor && term.IsTerminal(int(os.Stdout.Fd()))
+	default:
+		return errdefs.InvalidArgument("invalid color mode '%s'", mode)
+	}
+	return nil
+}
+
+// colorize returns the text with the provided color if colors are enabled.
+func colorize(color, text string) string {
+	if !colorEnabled || color == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// statusColor returns the color for a progress status.
+func statusColor(status string) string {
+	switch status {
+	case runtime.StatusComplete, runtime.StatusExists:
+		return colorGreen
+	case runtime.StatusRunning, runtime.StatusPending, runtime.StatusRetrying:
+		return colorYellow
+	case runtime.StatusError, runtime.StatusAborted:
+		return colorRed
+	}
+	return ""
+}
+
+// headerWriter colorizes the first line written to the underlying writer. It is used
+// as the output of a tabwriter, so the color codes don't affect the column widths.
+type headerWriter struct {
+	w    io.Writer
+	done bool
+}
+
+func newHeaderWriter(w io.Writer) io.Writer {
+	if !colorEnabled {
+		return w
+	}
+	return &headerWriter{w: w}
+}
+
+// Write implements the io.Writer interface.
+func (hw *headerWriter) Write(p []byte) (int, error) {
+
+	if hw.done {
+		return hw.w.Write(p)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(colorBold)
+	if i := bytes.IndexByte(p, '\n'); i >= 0 {
+		buf.Write(p[:i])
+		buf.WriteString(colorReset)
+		buf.Write(p[i:])
+		hw.done = true
+	} else {
+		buf.Write(p)
+		buf.WriteString(colorReset)
+	}
+	if _, err := hw.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// outputField is a named value of an outputObject
+type outputField struct {
+	Name  string
+	Value interface{}
+}
+
+// outputObject is an object that preserves the order of its fields when encoded.
+type outputObject []outputField
+
+// MarshalJSON encodes the object as a JSON object in the order of its fields.
+func (obj outputObject) MarshalJSON() ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	buf.WriteString("{")
+	for i, f := range obj {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML encodes the object as a YAML mapping in the order of its fields.
+func (obj outputObject) MarshalYAML() (interface{}, error) {
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range obj {
+		var value yaml.Node
+		err := value.Encode(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: f.Name}, &value)
+	}
+
+	return node, nil
+}
+
+// outputData converts the provided value to data for encoding it in a machine readable format.
+// It follows the same rules as printValueElem, omitting fields with the output:"-" tag and
+// formatting time the same way as the text output.
+func outputData(elem reflect.Value) interface{} {
+
+	switch elem.Kind() {
+	case reflect.Invalid:
+		return nil
+
+	case reflect.Ptr, reflect.Interface:
+		if elem.IsNil() {
+			return nil
+		}
+		return outputData(elem.Elem())
+
+	case reflect.Struct:
+		elemType := elem.Type()
+		if elemType == reflect.TypeOf(time.Time{}) {
+			return elem.Interface().(time.Time).Format(time.RFC3339Nano)
+		}
+
+		obj := outputObject{}
+		for i := 0; i < elem.NumField(); i++ {
+			elemField := elem.Field(i)
+			if !elemField.CanInterface() || elemType.Field(i).Tag.Get("output") == "-" {
+				continue
+			}
+			obj = append(obj, outputField{elemType.Field(i).Name, outputData(elemField)})
+		}
+		return obj
+
+	case reflect.Map:
+		m := elem.MapKeys()
+		keys := make([]string, len(m))
+		for i := 0; i < len(m); i++ {
+			keys[i] = m[i].String()
+		}
+		sort.Strings(keys)
+		obj := outputObject{}
+		for _, k := range keys {
+			obj = append(obj, outputField{k, outputData(elem.MapIndex(reflect.ValueOf(k)))})
+		}
+		return obj
+
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, elem.Len())
+		for i := 0; i < elem.Len(); i++ {
+			list[i] = outputData(elem.Index(i))
+		}
+		return list
+	}
+
+	if !elem.CanInterface() {
+		return nil
+	}
+	return elem.Interface()
+}
+
+// printData prints the provided data in the selected machine readable format.
+func printData(data interface{}) {
+
+	var out []byte
+	var err error
+
+	switch output {
+	case outputJSON:
+		out, err = json.MarshalIndent(data, "", "  ")
+		out = append(out, '\n')
+	case outputYAML:
+		out, err = yaml.Marshal(data)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode output: %v", err))
+	}
+	fmt.Print(string(out))
+}
+
+// getWorkspace is a helper function returning the specified or, if wsName is empty, the
+// current workspace. If the current workspace isn't set and stdin is a terminal, it lets the
+// user pick one of the workspaces of the project.
+func getWorkspace(prj *project.Project, wsName string) (*project.Workspace, error) {
+
+	if wsName != "" {
+		return prj.Workspace(wsName)
+	}
+
+	ws, err := prj.CurrentWorkspace()
+	if (prj.CurrentWorkspaceName != "" && err == nil) || len(prj.Workspaces) < 2 ||
+		!term.IsTerminal(int(os.Stdin.Fd())) {
+		return ws, err
+	}
+
+	return pickWorkspace(prj, os.Stdin, os.Stdout)
+}
+
+// pickWorkspace shows a numbered list of the workspaces of the project and reads the
+// selection from the reader. The current workspace, or the first workspace if the current
+// workspace doesn't exist, is selected by default.
+func pickWorkspace(prj *project.Project,
+	in io.Reader, out io.Writer) (*project.Workspace, error) {
+
+	def := 0
+	for i, ws := range prj.Workspaces {
+		if ws.Name == prj.CurrentWorkspaceName {
+			def = i
+		}
+	}
+
+	for i, ws := range prj.Workspaces {
+		mark := " "
+		if i == def {
+			mark = "*"
+		}
+		fmt.Fprintf(out, "%s %d) %s\n", mark, i+1, ws.Name)
+	}
+	fmt.Fprintf(out, "Select workspace [%d]: ", def+1)
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, errdefs.SystemError(err, "failed to read selection")
+	}
+
+	return selectWorkspace(prj, def, line)
+}
+
+// selectWorkspace returns the workspace for the selection, which can be the number in the list
+// starting at 1 or the name of the workspace. An empty selection selects the default.
+func selectWorkspace(prj *project.Project,
+	def int, selection string) (*project.Workspace, error) {
+
+	selection = strings.TrimSpace(selection)
+	if selection == "" {
+		if def < 0 || def >= len(prj.Workspaces) {
+			return nil, errdefs.InvalidArgument("no workspace selected")
+		}
+		return &prj.Workspaces[def], nil
+	}
+
+	idx, err := strconv.Atoi(selection)
+	if err != nil {
+		return prj.Workspace(selection)
+	}
+	if idx < 1 || idx > len(prj.Workspaces) {
+		return nil, errdefs.InvalidArgument("invalid selection: %d", idx)
+	}
+	return &prj.Workspaces[idx-1], nil
+}
+
+// printWarnings prints the warnings to stderr.
+func printWarnings(warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", w)
+	}
+}
+
 // printValue prints the content of the provided value in two columns.
 //  struct: field name, value
 //  map:    key, value
 //  slice:  index, value
 //  <type>: prefix, value
+// For machine readable output formats, the value is printed as an object. A value with a
+// prefix is printed as an object with the prefix as the only field.
 func printValue(fieldHdr string, valueHdr string, prefix string, value interface{}) {
 
+	if output != outputText {
+		data := outputData(reflect.ValueOf(value))
+		if prefix != "" {
+			data = outputObject{{prefix, data}}
+		}
+		printData(data)
+		return
+	}
+
 	w := new(tabwriter.Writer)
-	w.Init(os.Stdout, 8, 0, 1, ' ', 0)
+	w.Init(newHeaderWriter(os.Stdout), 8, 0, 1, ' ', 0)
 	defer w.Flush()
 
 	fmt.Fprintf(w, "%s\t%s\n", strings.ToUpper(fieldHdr), strings.ToUpper(valueHdr))
 	printValueElem(w, prefix, reflect.ValueOf(value), false)
 }
 
+// listField formats a field of a list entry based on its kind. Integers are printed as
+// decimals, times in the RFC3339 format, and booleans as yes or no.
+func listField(elem reflect.Value) string {
+
+	switch elem.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(elem.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(elem.Uint(), 10)
+	case reflect.Bool:
+		if elem.Bool() {
+			return "yes"
+		}
+		return "no"
+	case reflect.String:
+		return elem.String()
+	case reflect.Struct:
+		if elem.Type() == reflect.TypeOf(time.Time{}) {
+			return elem.Interface().(time.Time).Format(time.RFC3339Nano)
+		}
+	}
+	return fmt.Sprintf("%v", elem.Interface())
+}
+
 // printList prints a slice of structures using the field names as the header
+// For machine readable output formats, the list is printed as an array of objects.
 func printList(list interface{}, withIndex bool) {
 
 	if reflect.TypeOf(list).Kind() != reflect.Slice {
@@ -213,8 +728,13 @@ func printList(list interface{}, withIndex bool) {
 		panic("provided argument must be of the type: slice of structures")
 	}
 
+	if output != outputText {
+		printData(outputData(reflect.ValueOf(list)))
+		return
+	}
+
 	w := new(tabwriter.Writer)
-	w.Init(os.Stdout, 8, 0, 1, ' ', 0)
+	w.Init(newHeaderWriter(os.Stdout), 8, 0, 1, ' ', 0)
 	defer w.Flush()
 
 	if withIndex {
@@ -237,19 +757,97 @@ func printList(list interface{}, withIndex bool) {
 		if withIndex {
 			fmt.Fprintf(w, "%d\t", i)
 		}
-		format = "%v"
+		format = "%s"
 		item := items.Index(i)
 		for j := 0; j < item.NumField(); j++ {
 			if hdr.Field(j).Tag.Get("output") == "-" {
 				continue
 			}
-			fmt.Fprintf(w, format, item.Field(j).Interface())
-			format = "\t%v"
+			fmt.Fprintf(w, format, listField(item.Field(j)))
+			format = "\t%s"
 		}
 		fmt.Fprintf(w, "\n")
 	}
 }
 
+// transferSample holds the offset and time of the last progress update of a download
+// and the transfer rate calculated from the previous sample in bytes per second.
+type transferSample struct {
+	offset int64
+	at     time.Time
+	rate   float64
+}
+
+// updateTransfer returns the new sample for the provided status. The rate is unknown (0)
+// for the first sample and kept from the previous sample if no time has elapsed.
+func updateTransfer(prev transferSample, status runtime.ProgressStatus) transferSample {
+
+	at := status.UpdatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	next := transferSample{offset: status.Offset, at: at, rate: prev.rate}
+	if prev.at.IsZero() || status.Offset < prev.offset {
+		next.rate = 0
+		return next
+	}
+
+	elapsed := at.Sub(prev.at).Seconds()
+	if elapsed > 0 {
+		next.rate = float64(status.Offset-prev.offset) / elapsed
+	}
+	return next
+}
+
+// progressPercent returns the progress in percent, limited to 0..100.
+func progressPercent(offset, total int64) int {
+	if total <= 0 || offset <= 0 {
+		return 0
+	}
+	if offset >= total {
+		return 100
+	}
+	return int(offset * 100 / total)
+}
+
+// downloadStatus returns the download status line for the provided progress, for example:
+// "Downloading 45% (450.0MB / 1.0GB) 12.3MB/s"
+func downloadStatus(status runtime.ProgressStatus, sample transferSample) string {
+
+	line := fmt.Sprintf("Downloading %d%% (%s / %s)",
+		progressPercent(status.Offset, status.Total),
+		sizeToSIString(status.Offset),
+		sizeToSIString(status.Total))
+	if sample.rate > 0 {
+		line += " " + sizeToSIString(int64(sample.rate)) + "/s"
+	}
+	return line
+}
+
+// progressSummary returns the aggregated progress of all references, for example:
+// "Total: 45% (450.0MB / 1.0GB), 3 complete, 2 in progress"
+func progressSummary(statuses map[string]runtime.ProgressStatus) string {
+
+	var offset, total int64
+	var complete, running int
+	for _, status := range statuses {
+		offset += status.Offset
+		total += status.Total
+		switch status.Status {
+		case runtime.StatusComplete, runtime.StatusExists:
+			complete++
+		case runtime.StatusRunning, runtime.StatusPending:
+			running++
+		}
+	}
+
+	return fmt.Sprintf("Total: %d%% (%s / %s), %d complete, %d in progress",
+		progressPercent(offset, total),
+		sizeToSIString(offset), sizeToSIString(total),
+		complete, running)
+}
+
 // showImageProgress displays the progress of sequential or parallel jobs
 // Use this as a callback function in calls that provide a progress feedback
 func showImageProgress(progress <-chan []runtime.ProgressStatus) {
@@ -258,6 +856,7 @@ func showImageProgress(progress <-chan []runtime.ProgressStatus) {
 	ticks := 0
 
 	statCached := make(map[string]runtime.ProgressStatus)
+	statSamples := make(map[string]transferSample)
 	statRefs := []string{}
 
 	w := new(tabwriter.Writer)
@@ -270,14 +869,22 @@ func showImageProgress(progress <-chan []runtime.ProgressStatus) {
 				statRefs = append(statRefs, status.Reference)
 			}
 			statCached[status.Reference] = status
+			statSamples[status.Reference] =
+				updateTransfer(statSamples[status.Reference], status)
 		}
 		for ; lines > 0; lines = lines - 1 {
 			fmt.Fprintf(w, "\033[1A\033[2K")
 		}
-		lines = len(statRefs)
+		lines = len(statRefs) + 1
 		for _, ref := range statRefs {
 
 			status := statCached[ref]
+			sample := statSamples[ref]
+
+			if status.Status == runtime.StatusRetrying {
+				fmt.Fprintf(w, "%s\n", colorize(colorYellow, "Retrying: "+status.Details))
+				continue
+			}
 
 			decoded := strings.Index(ref, ":")
 			if decoded > 0 {
@@ -285,19 +892,20 @@ func showImageProgress(progress <-chan []runtime.ProgressStatus) {
 			}
 			if status.Status == runtime.StatusRunning {
 				if status.Offset == status.Total {
-					fmt.Fprintf(w, "%s: Extracting %c\n",
-						ref[:12],
-						"-\\|/"[ticks&3])
+					fmt.Fprintf(w, "%s: %s\n",
+						shortID(ref, 0),
+						colorize(colorYellow, "Extracting "+string("-\\|/"[ticks&3])))
 				} else {
-					fmt.Fprintf(w, "%s: Downloading (%s / %s)\n",
-						ref[:12],
-						sizeToSIString(status.Offset),
-						sizeToSIString(status.Total))
+					fmt.Fprintf(w, "%s: %s\n",
+						shortID(ref, 0),
+						colorize(colorYellow, downloadStatus(status, sample)))
 				}
 			} else {
-				fmt.Fprintf(w, "%s: %s\n", ref[:12], strings.Title(status.Status))
+				fmt.Fprintf(w, "%s: %s\n", shortID(ref, 0),
+					colorize(statusColor(status.Status), strings.Title(status.Status)))
 			}
 		}
+		fmt.Fprintf(w, "%s\n", progressSummary(statCached))
 		w.Flush()
 		ticks = ticks + 1
 	}
@@ -336,16 +944,13 @@ func showBuildProgress(progress <-chan []runtime.ProgressStatus) {
 			if decoded > 0 {
 				ref = ref[decoded+1:]
 			}
-			reflen := len(ref)
-			if reflen > 12 {
-				reflen = 12
-			}
 			if status.Status == runtime.StatusRunning {
 				fmt.Fprintf(w, "[%s] %s\n",
-					ref[:reflen],
-					status.Details)
+					shortID(ref, 0),
+					colorize(colorYellow, status.Details))
 			} else {
-				fmt.Fprintf(w, "[%s] %s\n", ref[:reflen], strings.Title(status.Status))
+				fmt.Fprintf(w, "[%s] %s\n", shortID(ref, 0),
+					colorize(statusColor(status.Status), strings.Title(status.Status)))
 			}
 		}
 		w.Flush()
@@ -5,7 +5,14 @@ import (
 
 	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
 )
 
 // compareString compares the provided strings and returns -1 if they match, or the position
@@ -290,4 +297,553 @@ func TestCliScanArgs(t *testing.T) {
 	testLine = "cmd1 arg11 ,  ,,, cmd2 arg21"
 	testCmds = [][]string{{"cmd1 arg11"}, {"cmd2 arg21"}}
 	compareCommands(t, "multi line, multi delims", testLine, testCmds)
+
+	testLine = `echo "a,b", cmd2`
+	testCmds = [][]string{{`echo "a,b"`}, {"cmd2"}}
+	compareCommands(t, "double quoted comma", testLine, testCmds)
+
+	testLine = `echo 'a,b' , cmd2`
+	testCmds = [][]string{{`echo 'a,b'`}, {"cmd2"}}
+	compareCommands(t, "single quoted comma", testLine, testCmds)
+
+	testLine = `echo a\,b, cmd2 \x`
+	testCmds = [][]string{{"echo a,b"}, {`cmd2 \x`}}
+	compareCommands(t, "escaped comma", testLine, testCmds)
+
+	testLine = `echo "it's a,b \"c,d\"", echo 'say "x,y"'`
+	testCmds = [][]string{{`echo "it's a,b \"c,d\""`}, {`echo 'say "x,y"'`}}
+	compareCommands(t, "nested quotes", testLine, testCmds)
+
+	testLine = `echo "a\,b" 'c\'`
+	testCmds = [][]string{{`echo "a\,b" 'c\'`}}
+	compareCommands(t, "escaped comma in quotes", testLine, testCmds)
+}
+
+func TestCliReadCommandsHeredoc(t *testing.T) {
+
+	input := "cmd1 arg11\n" +
+		"<<EOF\n" +
+		"for f in a b; do\n" +
+		"  echo \"'$f'\"\n" +
+		"done\n" +
+		"EOF\n" +
+		"cmd2 arg21\n"
+
+	commands, err := readCommands(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to read commands: %v", err)
+	}
+
+	exp := [][]string{
+		{"cmd1 arg11"},
+		{"/bin/sh", "-c", "for f in a b; do\n  echo \"'$f'\"\ndone"},
+		{"cmd2 arg21"},
+	}
+	if len(commands) != len(exp) {
+		t.Fatalf("Expected %d commands, got %d", len(exp), len(commands))
+	}
+	for i := range commands {
+		if !reflect.DeepEqual(commands[i].Args, exp[i]) {
+			t.Errorf("Command %d mismatch: %q (exp: %q)", i, commands[i].Args, exp[i])
+		}
+	}
+
+	commands, err = readCommands(strings.NewReader("<<'END'\necho \"a\"\nEND\n"))
+	if err != nil || len(commands) != 1 || commands[0].Args[2] != "echo \"a\"" {
+		t.Errorf("Failed to read heredoc with quoted delimiter: %v %v", commands, err)
+	}
+
+	_, err = readCommands(strings.NewReader("<<EOF\necho missing end\n"))
+	if err == nil {
+		t.Errorf("Missing heredoc delimiter should fail")
+	}
+}
+
+func TestCliReadCommandsFile(t *testing.T) {
+
+	file, err := ioutil.TempFile("", "cnetest")
+	if err != nil {
+		t.Fatalf("Failed to create temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("cmd1 arg11, arg12\n<<EOF\necho a\nEOF\n")
+	file.Close()
+
+	commands, err := readCommandsFile(file.Name())
+	if err != nil {
+		t.Fatalf("Failed to read commands: %v", err)
+	}
+	if len(commands) != 2 || commands[0].Args[0] != "cmd1 arg11, arg12" ||
+		commands[1].Args[2] != "echo a" {
+		t.Errorf("Unexpected commands: %q", commands)
+	}
+
+	_, err = readCommandsFile(file.Name() + "-missing")
+	if err == nil {
+		t.Errorf("Reading a missing file should fail")
+	}
+}
+
+func TestCliSIStringToSize(t *testing.T) {
+
+	testCases := []struct {
+		str  string
+		size int64
+		err  bool
+	}{
+		{"0", 0, false},
+		{"1024", 1024, false},
+		{"512k", 512000, false},
+		{"512K", 512000, false},
+		{"512M", 512000000, false},
+		{"2G", 2000000000, false},
+		{"2GB", 2000000000, false},
+		{"1.5G", 1500000000, false},
+		{"", 0, true},
+		{"G", 0, true},
+		{"-1M", 0, true},
+		{"12X", 0, true},
+	}
+
+	for _, tc := range testCases {
+		size, err := siStringToSize(tc.str)
+		if tc.err != (err != nil) {
+			t.Errorf("Conversion of '%s' returned unexpected error: %v", tc.str, err)
+		} else if size != tc.size {
+			t.Errorf("Conversion of '%s' returned %d, expected %d", tc.str, size, tc.size)
+		}
+	}
+}
+
+func TestCliShortID(t *testing.T) {
+
+	tests := []struct {
+		in  string
+		n   int
+		out string
+	}{
+		{"", 12, ""},
+		{"abc", 12, "abc"},
+		{"0123456789abcdef", 12, "0123456789ab"},
+		{"0123456789abcdef", 4, "0123"},
+		{"0123456789abcdef", 0, "0123456789ab"},
+	}
+
+	for _, tc := range tests {
+		if out := shortID(tc.in, tc.n); out != tc.out {
+			t.Errorf("shortID(%q, %d): expected %q, got %q", tc.in, tc.n, tc.out, out)
+		}
+	}
+}
+
+func TestCliAgoStringToTime(t *testing.T) {
+
+	tests := []struct {
+		in  string
+		ago time.Duration
+	}{
+		{"720h", 720 * time.Hour},
+		{"30d", 30 * 24 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+	}
+
+	for _, tc := range tests {
+		tm, err := agoStringToTime(tc.in)
+		if err != nil {
+			t.Errorf("agoStringToTime(%q) failed: %v", tc.in, err)
+			continue
+		}
+		diff := time.Since(tm) - tc.ago
+		if diff < 0 || diff > time.Minute {
+			t.Errorf("agoStringToTime(%q): expected %v ago, got %v", tc.in, tc.ago, tm)
+		}
+	}
+
+	tm, err := agoStringToTime("2020-01-02T03:04:05Z")
+	if err != nil || !tm.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Failed to parse timestamp: %v %v", tm, err)
+	}
+
+	for _, in := range []string{"", "abc", "10x", "-5d"} {
+		if _, err := agoStringToTime(in); err == nil {
+			t.Errorf("agoStringToTime(%q) should fail", in)
+		}
+	}
+}
+
+// TestPrintListMixedTypes tests printList for fields of different kinds
+func TestPrintListMixedTypes(t *testing.T) {
+
+	type testStruct struct {
+		Name    string
+		Size    int64
+		Inodes  uint32
+		Running bool
+		Created time.Time
+	}
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	testList := []testStruct{
+		{Name: "one", Size: 1024, Inodes: 7, Running: true, Created: created},
+		{Name: "two", Size: -1, Inodes: 0, Running: false, Created: created},
+	}
+
+	const expected = "" +
+		"NAME    SIZE    INODES  RUNNING CREATED\n" +
+		"one     1024    7       yes     2020-01-02T03:04:05Z\n" +
+		"two     -1      0       no      2020-01-02T03:04:05Z\n"
+
+	errPos, out := compareFuncOutput(
+		func() { printList(testList, false) }, expected)
+	if errPos != -1 {
+		t.Errorf("Failed to print list with mixed types (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
+}
+
+// TestPrintListJSON tests printList for the json output format
+func TestPrintListJSON(t *testing.T) {
+
+	type testStruct struct {
+		FieldA  string
+		FieldB  int
+		Private string `output:"-"`
+	}
+
+	testList := []testStruct{
+		{FieldA: "ValueA", FieldB: 1, Private: "secret"},
+		{FieldA: "ValueB", FieldB: 2, Private: "secret"},
+	}
+
+	const expected = `[
+  {
+    "FieldA": "ValueA",
+    "FieldB": 1
+  },
+  {
+    "FieldA": "ValueB",
+    "FieldB": 2
+  }
+]
+`
+	output = outputJSON
+	defer func() { output = outputText }()
+
+	errPos, out := compareFuncOutput(
+		func() { printList(testList, true) }, expected)
+	if errPos != -1 {
+		t.Errorf("Failed to print list as json (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
+}
+
+// TestPrintValueJSON tests printValue for the json output format
+func TestPrintValueJSON(t *testing.T) {
+
+	type testSubStruct struct {
+		FieldZ string
+		FieldA []string
+	}
+	type testStruct struct {
+		Name    string
+		Sub     testSubStruct
+		Labels  map[string]string
+		Created time.Time
+		Hidden  string `output:"-"`
+	}
+
+	testValue := testStruct{
+		Name:    "Name",
+		Sub:     testSubStruct{FieldZ: "ValueZ", FieldA: []string{"a", "b"}},
+		Labels:  map[string]string{"b": "2", "a": "1"},
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Hidden:  "hidden",
+	}
+
+	const expected = `{
+  "Name": "Name",
+  "Sub": {
+    "FieldZ": "ValueZ",
+    "FieldA": [
+      "a",
+      "b"
+    ]
+  },
+  "Labels": {
+    "a": "1",
+    "b": "2"
+  },
+  "Created": "2020-01-02T03:04:05Z"
+}
+`
+	output = outputJSON
+	defer func() { output = outputText }()
+
+	errPos, out := compareFuncOutput(
+		func() { printValue("Field", "Value", "", &testValue) }, expected)
+	if errPos != -1 {
+		t.Errorf("Failed to print value as json (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
+
+	const expectedPrefix = `{
+  "Prefix": "Value"
+}
+`
+	errPos, out = compareFuncOutput(
+		func() { printValue("Field", "Value", "Prefix", "Value") }, expectedPrefix)
+	if errPos != -1 {
+		t.Errorf("Failed to print value with prefix as json (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
+}
+
+// TestPrintValueYAML tests printValue for the yaml output format
+func TestPrintValueYAML(t *testing.T) {
+
+	type testStruct struct {
+		Name    string
+		Labels  map[string]string
+		List    []string
+		Created time.Time
+		Hidden  string `output:"-"`
+	}
+
+	testValue := testStruct{
+		Name:    "Name",
+		Labels:  map[string]string{"b": "2", "a": "1"},
+		List:    []string{"x", "y"},
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Hidden:  "hidden",
+	}
+
+	const expected = `Name: Name
+Labels:
+    a: "1"
+    b: "2"
+List:
+    - x
+    - "y"
+Created: "2020-01-02T03:04:05Z"
+`
+	output = outputYAML
+	defer func() { output = outputText }()
+
+	errPos, out := compareFuncOutput(
+		func() { printValue("Field", "Value", "", &testValue) }, expected)
+	if errPos != -1 {
+		t.Errorf("Failed to print value as yaml (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
+}
+
+func TestCliSelectWorkspace(t *testing.T) {
+
+	prj := project.NewProject("project", "/some/path")
+	for _, name := range []string{"one", "two", "three"} {
+		_, err := prj.CreateWorkspace(name, "image", "")
+		if err != nil {
+			t.Fatalf("Failed to create workspace %s", name)
+		}
+	}
+
+	tests := []struct {
+		selection string
+		name      string
+	}{
+		{"", "two"},
+		{"\n", "two"},
+		{"1\n", "one"},
+		{" 3 ", "three"},
+		{"one", "one"},
+	}
+	for _, tc := range tests {
+		ws, err := selectWorkspace(prj, 1, tc.selection)
+		if err != nil || ws.Name != tc.name {
+			t.Errorf("Selection %q: expected %s, got %v %v", tc.selection, tc.name, ws, err)
+		}
+	}
+
+	for _, sel := range []string{"0", "4", "four"} {
+		if _, err := selectWorkspace(prj, 1, sel); err == nil {
+			t.Errorf("Selection %q should fail", sel)
+		}
+	}
+
+	// the current workspace is selected by default
+	prj.CurrentWorkspaceName = "three"
+	var out bytes.Buffer
+	ws, err := pickWorkspace(prj, strings.NewReader("\n"), &out)
+	if err != nil || ws.Name != "three" {
+		t.Errorf("Expected the current workspace to be preselected, got %v %v", ws, err)
+	}
+	if !strings.Contains(out.String(), "* 3) three") {
+		t.Errorf("Current workspace not marked:\n%s", out.String())
+	}
+
+	// the current workspace is used without asking if it is set
+	ws, err = getWorkspace(prj, "")
+	if err != nil || ws.Name != "three" {
+		t.Errorf("Expected the current workspace, got %v %v", ws, err)
+	}
+	ws, err = getWorkspace(prj, "two")
+	if err != nil || ws.Name != "two" {
+		t.Errorf("Expected the specified workspace, got %v %v", ws, err)
+	}
+}
+
+func TestCliParseGeneration(t *testing.T) {
+
+	gen, err := parseGeneration("000102030405060708090a0b0c0d0e0f")
+	if err != nil {
+		t.Fatalf("Failed to parse generation: %v", err)
+	}
+	if gen != [16]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15} {
+		t.Errorf("Invalid generation: %v", gen)
+	}
+
+	for _, in := range []string{"", "0011", "xyz", "000102030405060708090a0b0c0d0e0f00"} {
+		if _, err := parseGeneration(in); err == nil {
+			t.Errorf("parseGeneration(%q) should fail", in)
+		}
+	}
+}
+
+func TestCliShowProgressShortRef(t *testing.T) {
+
+	statuses := []runtime.ProgressStatus{
+		{Reference: "layer:abc", Status: runtime.StatusRunning, Offset: 1, Total: 2},
+		{Reference: "local", Status: runtime.StatusComplete},
+	}
+
+	for _, show := range []func(<-chan []runtime.ProgressStatus){
+		showImageProgress, showBuildProgress} {
+
+		progress := make(chan []runtime.ProgressStatus, 1)
+		progress <- statuses
+		close(progress)
+
+		_, out := compareFuncOutput(func() { show(progress) }, "")
+		if !strings.Contains(out, "abc") || !strings.Contains(out, "local") {
+			t.Errorf("Short references not displayed:\n%s", out)
+		}
+	}
+}
+
+func TestCliListLayers(t *testing.T) {
+
+	ws := &project.Workspace{}
+	ws.Environment.Layers = []project.Layer{
+		{Name: "base", Type: "custom", Commands: []project.Command{{}, {}}},
+		{Name: "tools", Type: "custom", Disabled: true},
+	}
+
+	const expected = "" +
+		"INDEX   NAME    TYPE    ENABLED COMMANDS\n" +
+		"0       base    custom  yes     2\n" +
+		"1       tools   custom  no      0\n"
+
+	errPos, out := compareFuncOutput(func() { listLayers(ws) }, expected)
+	if errPos != -1 {
+		t.Errorf("Failed to list layers (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
+}
+
+func TestCliDownloadStatus(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := runtime.ProgressStatus{Offset: 100000000, Total: 1000000000, UpdatedAt: start}
+
+	// first sample has no rate
+	sample := updateTransfer(transferSample{}, status)
+	if line := downloadStatus(status, sample); line != "Downloading 10% (100.0MB / 1.0GB)" {
+		t.Errorf("Unexpected first status '%s'", line)
+	}
+
+	status.Offset = 450000000
+	status.UpdatedAt = start.Add(2 * time.Second)
+	sample = updateTransfer(sample, status)
+	if line := downloadStatus(status, sample); line != "Downloading 45% (450.0MB / 1.0GB) 175.0MB/s" {
+		t.Errorf("Unexpected status '%s'", line)
+	}
+
+	// no elapsed time keeps the previous rate
+	sample = updateTransfer(sample, status)
+	if sample.rate != 175000000 {
+		t.Errorf("Rate not retained: %f", sample.rate)
+	}
+
+	if progressPercent(10, 0) != 0 || progressPercent(20, 10) != 100 {
+		t.Errorf("Percentage not limited")
+	}
+}
+
+func TestCliProgressSummary(t *testing.T) {
+
+	statuses := map[string]runtime.ProgressStatus{
+		"layer1": {Status: runtime.StatusComplete, Offset: 300000000, Total: 300000000},
+		"layer2": {Status: runtime.StatusRunning, Offset: 150000000, Total: 600000000},
+		"layer3": {Status: runtime.StatusPending, Offset: 0, Total: 100000000},
+	}
+
+	const expected = "Total: 45% (450.0MB / 1.0GB), 1 complete, 2 in progress"
+	if line := progressSummary(statuses); line != expected {
+		t.Errorf("Unexpected summary '%s'", line)
+	}
+}
+
+func TestCliColorOutput(t *testing.T) {
+
+	defer func() { colorEnabled = false }()
+
+	if err := setColorMode("never"); err != nil || colorize(colorRed, "x") != "x" {
+		t.Errorf("Color not disabled")
+	}
+	if err := setColorMode("invalid"); err == nil {
+		t.Errorf("Invalid color mode accepted")
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	if err := setColorMode("auto"); err != nil || colorEnabled {
+		t.Errorf("NO_COLOR not respected")
+	}
+
+	if err := setColorMode("always"); err != nil || !colorEnabled {
+		t.Errorf("Color not enabled")
+	}
+	if colorize(statusColor(runtime.StatusError), "Error") != colorRed+"Error"+colorReset {
+		t.Errorf("Status not colorized")
+	}
+
+	var buf bytes.Buffer
+	w := newHeaderWriter(&buf)
+	io.WriteString(w, "NAME\n")
+	io.WriteString(w, "value\n")
+	if buf.String() != colorBold+"NAME"+colorReset+"\nvalue\n" {
+		t.Errorf("Unexpected header output %q", buf.String())
+	}
+}
+
+func TestCliShowHistory(t *testing.T) {
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []runtime.ImageHistory{
+		{CreatedAt: created, CreatedBy: "ADD /", Layer: "sha256:0123456789abcdef0123", Size: 1000},
+		{CreatedAt: created, CreatedBy: "ENV A=1", EmptyLayer: true},
+	}
+
+	const expected = "" +
+		"CREATED              LAYER        SIZE    CREATEDBY\n" +
+		"2020-01-01T00:00:00Z <empty>              ENV A=1\n" +
+		"2020-01-01T00:00:00Z 0123456789ab 1.0kB   ADD /\n"
+
+	errPos, out := compareFuncOutput(func() { showHistory(history) }, expected)
+	if errPos != -1 {
+		t.Errorf("Failed to show history (pos %d)", errPos)
+		t.Errorf("\n" + out)
+	}
 }
@@ -3,6 +3,7 @@ package cli
 import (
 	"errors"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -31,6 +32,7 @@ var createWorkspaceCmd = &cobra.Command{
 
 var createWorkspaceImage string
 var createWorkspaceInsert string
+var createWorkspaceRuntimeSocket string
 
 func createWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
@@ -44,10 +46,23 @@ func createWorkspaceRunE(cmd *cobra.Command, args []string) error {
 		wsName = args[0]
 	}
 
-	return initWorkspace(prj, wsName, createWorkspaceImage, createWorkspaceInsert)
+	var mounts []project.Mount
+	if createWorkspaceRuntimeSocket != "" {
+		if createWorkspaceRuntimeSocket != "ro" && createWorkspaceRuntimeSocket != "rw" {
+			return errdefs.InvalidArgument("invalid runtime socket mode '%s', must be 'ro' or 'rw'",
+				createWorkspaceRuntimeSocket)
+		}
+		printWarnings([]string{"Mounting the runtime socket gives the workspace full " +
+			"control over the container runtime and the host, even if mounted read-only."})
+		mounts = append(mounts,
+			container.RuntimeSocketMount(conf, createWorkspaceRuntimeSocket == "ro"))
+	}
+
+	return initWorkspace(prj, wsName, createWorkspaceInsert, createWorkspaceImage, mounts)
 }
 
-func initWorkspace(prj *project.Project, wsName, insert, imgName string) error {
+func initWorkspace(prj *project.Project, wsName, insert, imgName string,
+	mounts []project.Mount) error {
 
 	imgName = conf.FullImageName(imgName)
 
@@ -55,15 +70,16 @@ func initWorkspace(prj *project.Project, wsName, insert, imgName string) error {
 	if err != nil {
 		return err
 	}
+	ws.Mounts = mounts
 
 	if imgName != "" {
-		run, err := runtime.Open(conf.Runtime)
+		run, err := runtime.OpenLazy(conf.Runtime)
 		if err != nil {
 			return err
 		}
 		defer run.Close()
 
-		img, err := pullImage(run, imgName)
+		img, err := pullImage(run, imgName, "")
 		if err != nil {
 			return err
 		}
@@ -81,15 +97,49 @@ func initWorkspace(prj *project.Project, wsName, insert, imgName string) error {
 
 var createLayerSystem bool
 var createLayerInsert string
+var createLayerTemplate string
+var createLayerFile string
+var createLayerCommands []string
 
 var createLayerCmd = &cobra.Command{
-	Use:     "layer [FLAGS] NAME [CMDLINE]",
-	Short:   "Create a new layer",
+	Use:   "layer [FLAGS] NAME [CMDLINE | PACKAGE...]",
+	Short: "Create a new layer",
+	Long: `
+Create a new layer with the commands provided in CMDLINE, with the command
+option, or read from a file or stdin. With the template option, the commands for installing the provided packages are
+generated from one of the templates apt, apk, pip, or npm, for example:
+
+  cne create layer --template apt tools vim git
+
+Commands in CMDLINE and in the command option are separated by a ','. The
+command option can be repeated. When reading from a file or stdin, each line is
+a separate command. A block of lines enclosed by '<<EOF' and 'EOF' is
+executed as a single shell command, for example:
+
+  cne create layer setup <<'END'
+  <<EOF
+  for f in a b; do
+    echo "$f"
+  done
+  EOF
+  END`,
 	Aliases: []string{"l"},
 	Args:    cobra.MinimumNArgs(1),
 	RunE:    createLayerRunE,
 }
 
+// readCommandsFile reads the commands from the provided file in the same format as from stdin.
+func readCommandsFile(name string) ([]project.Command, error) {
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to open '%s'", name)
+	}
+	defer file.Close()
+
+	return readCommands(file)
+}
+
 func createLayerRunE(cmd *cobra.Command, args []string) error {
 
 	prj, err := loadProject()
@@ -97,7 +147,7 @@ func createLayerRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -113,14 +163,39 @@ func createLayerRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	hasOptCommands := createLayerFile != "" || len(createLayerCommands) > 0
 	isTerminal := term.IsTerminal(int(os.Stdin.Fd()))
-	if len(args) > 1 && !isTerminal {
+	if len(args) > 1 && !isTerminal && createLayerTemplate == "" && !hasOptCommands {
 		return errdefs.InvalidArgument("too many arguments")
 	}
+	if createLayerTemplate != "" && createLayerSystem {
+		return errdefs.InvalidArgument("template and system options are exclusive")
+	}
+	if createLayerFile != "" && len(createLayerCommands) > 0 {
+		return errdefs.InvalidArgument("file and command options are exclusive")
+	}
+	if hasOptCommands && (len(args) > 1 || createLayerTemplate != "") {
+		return errdefs.InvalidArgument(
+			"file and command options cannot be used with a template or CMDLINE")
+	}
 
 	var commands []project.Command
-	if len(args) > 1 {
+	if createLayerTemplate != "" {
+		commands, err = project.TemplateCommands(createLayerTemplate, args[1:])
+		if err != nil {
+			return err
+		}
+	} else if len(args) > 1 {
 		commands = scanLine(args[1])
+	} else if len(createLayerCommands) > 0 {
+		for _, c := range createLayerCommands {
+			commands = append(commands, scanLine(c)...)
+		}
+	} else if createLayerFile != "" {
+		commands, err = readCommandsFile(createLayerFile)
+		if err != nil {
+			return err
+		}
 	} else if !isTerminal {
 
 		commands, err = readCommands(os.Stdin)
@@ -184,6 +259,53 @@ func createLayerRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var createSnapshotCmd = &cobra.Command{
+	Use:   "snapshot NAME",
+	Short: "Create a named snapshot of the workspace container",
+	Long: `
+Create a snapshot of the current filesystem of the workspace container and save it
+with the provided name. Restoring the snapshot with 'cne restore snapshot' resets
+the container and the workspace configuration to this save point.`,
+	Args: cobra.ExactArgs(1),
+	RunE: createSnapshotRunE,
+}
+
+var createSnapshotWorkspace string
+
+func createSnapshotRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, createSnapshotWorkspace)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.NotFound("container", ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = ctr.CreateSnapshot(ws, args[0])
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
 func init() {
 
 	rootCmd.AddCommand(createCmd)
@@ -193,6 +315,10 @@ func init() {
 		&createWorkspaceImage, "image", "", "Base image for the workspace")
 	createWorkspaceCmd.Flags().StringVar(
 		&createWorkspaceInsert, "insert", "", "Insert before this workspace")
+	createWorkspaceCmd.Flags().StringVar(
+		&createWorkspaceRuntimeSocket, "mount-runtime-socket", "",
+		"Mount the runtime socket into the workspace ('ro' or 'rw')")
+	createWorkspaceCmd.Flags().Lookup("mount-runtime-socket").NoOptDefVal = "ro"
 
 	createCmd.AddCommand(createLayerCmd)
 	createLayerCmd.Flags().StringVar(
@@ -200,4 +326,16 @@ func init() {
 	createLayerCmd.Flags().BoolVarP(
 		&createLayerSystem, "system", "s", false,
 		"User the system handler of the same name")
+	createLayerCmd.Flags().StringVarP(
+		&createLayerTemplate, "template", "t", "",
+		"Generate the commands for installing the packages ("+
+			strings.Join(project.TemplateNames(), ", ")+")")
+	createLayerCmd.Flags().StringVarP(
+		&createLayerFile, "file", "f", "", "Read the commands from the file")
+	createLayerCmd.Flags().StringArrayVarP(
+		&createLayerCommands, "command", "c", nil, "Command line for the layer")
+
+	createCmd.AddCommand(createSnapshotCmd)
+	createSnapshotCmd.Flags().StringVarP(
+		&createSnapshotWorkspace, "workspace", "w", "", "Name of the workspace")
 }
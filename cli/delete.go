@@ -2,20 +2,20 @@ package cli
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/spf13/cobra"
 
 	"github.com/czankel/cne/container"
 	"github.com/czankel/cne/errdefs"
-	"github.com/czankel/cne/project"
 	"github.com/czankel/cne/runtime"
 )
 
 var deleteCmd = &cobra.Command{
 	Use:     "delete",
 	Short:   "Delete resources",
-	Aliases: []string{"d"},
+	Aliases: []string{"d", "rm"},
 	Args:    cobra.MinimumNArgs(1),
 }
 
@@ -23,32 +23,89 @@ var deleteImageCmd = &cobra.Command{
 	Use:     "image NAME",
 	Aliases: []string{"image", "i"},
 	Short:   "delete image",
-	Args:    cobra.ExactArgs(1),
-	RunE:    deleteImageRunE,
+	Long: `
+Delete an image. Images that are used by a container or as the origin of a
+workspace of the current project are only deleted with the force option.`,
+	Args: cobra.ExactArgs(1),
+	RunE: deleteImageRunE,
+}
+
+var rmiCmd = &cobra.Command{
+	Use:   "rmi NAME",
+	Short: "Delete an image (same as 'delete image')",
+	Long:  deleteImageCmd.Long,
+	Args:  cobra.ExactArgs(1),
+	RunE:  deleteImageRunE,
+}
+
+var deleteImageForce bool
+
+// deleteImage deletes the image unless it is referenced and force isn't set. It returns the
+// size of the deleted image.
+func deleteImage(run runtime.Runtime, name string,
+	refs map[string]bool, force bool) (int64, error) {
+
+	img, err := run.GetImage(name)
+	if err != nil {
+		return 0, err
+	}
+	if refs[name] && !force {
+		return 0, errdefs.InUse("image", name)
+	}
+
+	size := img.Size()
+	err = run.DeleteImage(name)
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
 }
 
 func deleteImageRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
 	defer run.Close()
 
-	return run.DeleteImage(conf.FullImageName(args[0]))
+	// outside of a project, only protect the images of existing containers
+	prj, err := loadProject()
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+
+	refs, err := imageRefs(run, prj)
+	if err != nil {
+		return err
+	}
+
+	name := conf.FullImageName(args[0])
+	size, err := deleteImage(run, name, refs, deleteImageForce)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted %s, freed %s\n", name, sizeToSIString(size))
+	return nil
 }
 
 var deleteWorkspaceCmd = &cobra.Command{
-	Use:     "workspace NAME",
+	Use:     "workspace [NAME]",
 	Aliases: []string{"workspace", "ws"},
 	Short:   "delete workspace",
-	Args:    cobra.ExactArgs(1),
-	RunE:    deleteWorkspaceRunE,
+	Long: `
+Delete the specified or current workspace from the project and the container of
+the workspace, unless the keep-container option is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: deleteWorkspaceRunE,
 }
 
+var deleteWorkspaceKeepContainer bool
+
 func deleteWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -59,19 +116,29 @@ func deleteWorkspaceRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	name := args[0]
-	ws, err := prj.Workspace(name)
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	ws, err := getWorkspace(prj, name)
 	if err != nil {
 		return err
 	}
 
-	// ignore error TODO: print warning for error other than not-found
-	ctr, err := container.Get(run, ws)
-	if err == nil {
-		ctr.Purge()
+	if !deleteWorkspaceKeepContainer {
+		ctr, err := container.Get(run, ws)
+		if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			err = ctr.Purge()
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	err = prj.DeleteWorkspace(name)
+	err = prj.DeleteWorkspace(ws.Name)
 	if err != nil {
 		return err
 	}
@@ -89,7 +156,7 @@ var deleteLayerCmd = &cobra.Command{
 
 func deleteLayerRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -142,7 +209,7 @@ var deleteContainerCmd = &cobra.Command{
 
 func deleteContainerRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -154,10 +221,11 @@ func deleteContainerRunE(cmd *cobra.Command, args []string) error {
 	}
 
 	// delete all containers that match the domain+id
-	ctrs, err := container.Containers(run, prj, &user)
+	ctrs, warnings, err := container.Containers(run, prj, &user)
 	if err != nil {
 		return err
 	}
+	printWarnings(warnings)
 	for _, c := range ctrs {
 		if c.Name == args[0] {
 			c.Purge()
@@ -186,12 +254,7 @@ func deleteCommandRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	var ws *project.Workspace
-	if deleteCommandWorkspace != "" {
-		ws, err = prj.Workspace(deleteCommandWorkspace)
-	} else {
-		ws, err = prj.CurrentWorkspace()
-	}
+	ws, err := getWorkspace(prj, deleteCommandWorkspace)
 	if err != nil {
 		return err
 	}
@@ -230,10 +293,52 @@ func deleteCommandRunE(cmd *cobra.Command, args []string) error {
 	return prj.Write()
 }
 
+var deleteMountCmd = &cobra.Command{
+	Use:   "mount CONTAINER",
+	Short: "delete the mount for the container path",
+	Args:  cobra.ExactArgs(1),
+	RunE:  deleteMountRunE,
+}
+
+var deleteMountWorkspace string
+
+func deleteMountRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, deleteMountWorkspace)
+	if err != nil {
+		return err
+	}
+
+	err = ws.DeleteMount(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = prj.Write()
+	if err != nil {
+		return err
+	}
+
+	return updateSpec(ws)
+}
+
 func init() {
 	rootCmd.AddCommand(deleteCmd)
 	deleteCmd.AddCommand(deleteImageCmd)
+	deleteImageCmd.Flags().BoolVarP(
+		&deleteImageForce, "force", "f", false, "Delete the image even if it is used")
+	rootCmd.AddCommand(rmiCmd)
+	rmiCmd.Flags().BoolVarP(
+		&deleteImageForce, "force", "f", false, "Delete the image even if it is used")
 	deleteCmd.AddCommand(deleteWorkspaceCmd)
+	deleteWorkspaceCmd.Flags().BoolVar(
+		&deleteWorkspaceKeepContainer, "keep-container", false,
+		"Don't delete the container of the workspace")
 	deleteCmd.AddCommand(deleteLayerCmd)
 	deleteCmd.AddCommand(deleteContainerCmd)
 	deleteCmd.AddCommand(deleteCommandCmd)
@@ -241,4 +346,7 @@ func init() {
 		&deleteCommandWorkspace, "workspace", "w", "", "Name of the workspace")
 	deleteCommandCmd.Flags().StringVarP(
 		&deleteCommandLayer, "layer", "l", "", "Name or index of the layer")
+	deleteCmd.AddCommand(deleteMountCmd)
+	deleteMountCmd.Flags().StringVarP(
+		&deleteMountWorkspace, "workspace", "w", "", "Name of the workspace")
 }
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+func TestCliDeleteImage(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	run.AddImage("docker.io/library/busybox:latest", 1000)
+	run.AddImage("docker.io/library/alpine:latest", 2000)
+
+	refs := map[string]bool{"docker.io/library/busybox:latest": true}
+
+	_, err := deleteImage(run, "docker.io/library/busybox:latest", refs, false)
+	if !errors.Is(err, errdefs.ErrInUse) {
+		t.Errorf("Expected in-use error for a referenced image, got %v", err)
+	}
+
+	size, err := deleteImage(run, "docker.io/library/busybox:latest", refs, true)
+	if err != nil || size != 1000 {
+		t.Errorf("Failed to force delete image: %d %v", size, err)
+	}
+
+	size, err = deleteImage(run, "docker.io/library/alpine:latest", refs, false)
+	if err != nil || size != 2000 {
+		t.Errorf("Failed to delete image: %d %v", size, err)
+	}
+
+	if _, err = deleteImage(run, "docker.io/library/alpine:latest", refs, false); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestCliDeleteImageProject(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	run := mock.Namespace("test")
+	run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	// a corrupt project must not be ignored, it could reference the image
+	err := ioutil.WriteFile(filepath.Join(dir, "cneproject"), []byte("{corrupt"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write project file: %v", err)
+	}
+	err = deleteImageRunE(deleteImageCmd, []string{"docker.io/library/busybox:latest"})
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Expected invalid argument error for a corrupt project, got %v", err)
+	}
+	if _, err := run.GetImage("docker.io/library/busybox:latest"); err != nil {
+		t.Errorf("Image deleted despite the corrupt project: %v", err)
+	}
+
+	// outside of a project, the image is deleted
+	os.Remove(filepath.Join(dir, "cneproject"))
+	err = deleteImageRunE(deleteImageCmd, []string{"docker.io/library/busybox:latest"})
+	if err != nil {
+		t.Errorf("Failed to delete image outside of a project: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the changed files in the workspace container",
+	Long: `
+Show the files that were added (A), modified (M), or deleted (D) in the container
+of the workspace since the last committed snapshot, for example, before amending
+the snapshot with 'cne commit'. The files of a deleted directory are not listed
+separately.`,
+	Args: cobra.NoArgs,
+	RunE: diffRunE,
+}
+
+var diffWorkspace string
+
+// changeKinds are the short names for the kinds of changes.
+var changeKinds = map[string]string{
+	runtime.ChangeAdd:    "A",
+	runtime.ChangeModify: "M",
+	runtime.ChangeDelete: "D",
+}
+
+// showDiff writes the changes of the container to the writer as they are reported, so the
+// memory doesn't grow with the number of changes.
+func showDiff(w io.Writer, ctr *container.Container) error {
+
+	if output != outputText {
+		list := []runtime.Change{}
+		err := ctr.Diff(func(c runtime.Change) error {
+			list = append(list, c)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		printList(list, false)
+		return nil
+	}
+
+	return ctr.Diff(func(c runtime.Change) error {
+		_, err := fmt.Fprintf(w, "%s %s\n", changeKinds[c.Kind], c.Path)
+		return err
+	})
+}
+
+func diffRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, diffWorkspace)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.New(errdefs.ErrNotFound, "container",
+			fmt.Sprintf("no container for workspace '%s', use 'cne build' to build it",
+				ws.Name))
+	} else if err != nil {
+		return err
+	}
+
+	return showDiff(os.Stdout, ctr)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(
+		&diffWorkspace, "workspace", "w", "", "Name of the workspace")
+}
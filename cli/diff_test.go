@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+func TestCliShowDiff(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	img := run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	ws := &project.Workspace{Name: "main", ProjectUUID: uuid.New().String()}
+	dom, _ := uuid.Parse(ws.ProjectUUID)
+	runCtr, err := run.NewContainer(dom, ws.ID(), ws.ConfigHash(), 0, "test", img, nil)
+	if err != nil {
+		t.Fatalf("Failed to define container: %v", err)
+	}
+	if _, err = runCtr.Create(); err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	run.SetChanges([]runtime.Change{
+		{Kind: runtime.ChangeAdd, Path: "/etc/motd"},
+		{Kind: runtime.ChangeModify, Path: "/etc/passwd"},
+		{Kind: runtime.ChangeDelete, Path: "/tmp/old"},
+	})
+
+	ctr, err := container.Get(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get container: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = showDiff(&buf, ctr); err != nil {
+		t.Fatalf("Failed to show diff: %v", err)
+	}
+
+	const expected = "A /etc/motd\nM /etc/passwd\nD /tmp/old\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
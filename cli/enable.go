@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var enableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Enable resources",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var disableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable resources",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var enableLayerCmd = &cobra.Command{
+	Use:     "layer NAME",
+	Short:   "Enable a disabled layer",
+	Aliases: []string{"l"},
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return enableLayer(args[0], true)
+	},
+}
+
+var disableLayerCmd = &cobra.Command{
+	Use:   "layer NAME",
+	Short: "Disable a layer without deleting its commands",
+	Long: `
+Disable the layer so that its commands are skipped when the workspace is built.
+The layers starting with the disabled layer are rebuilt the next time the
+workspace is built.`,
+	Aliases: []string{"l"},
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return enableLayer(args[0], false)
+	},
+}
+
+var enableLayerWorkspace string
+
+// enableLayer enables or disables the layer of the workspace and writes the project.
+func enableLayer(name string, enable bool) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, enableLayerWorkspace)
+	if err != nil {
+		return err
+	}
+
+	err = ws.EnableLayer(name, enable)
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(enableCmd)
+	rootCmd.AddCommand(disableCmd)
+	enableCmd.AddCommand(enableLayerCmd)
+	disableCmd.AddCommand(disableLayerCmd)
+	enableLayerCmd.Flags().StringVarP(
+		&enableLayerWorkspace, "workspace", "w", "", "Name of the workspace")
+	disableLayerCmd.Flags().StringVarP(
+		&enableLayerWorkspace, "workspace", "w", "", "Name of the workspace")
+}
@@ -1,10 +1,18 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"io"
+	"io/ioutil"
 	"os"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"github.com/containerd/console"
 
@@ -15,20 +23,305 @@ import (
 )
 
 var execCmd = &cobra.Command{
-	Use:   "exec CMD",
+	Use:   "exec CMD [ARG...]",
 	Short: "Execute a command in the container environment",
-	Args:  cobra.MinimumNArgs(1),
-	RunE:  execRunE,
+	Long: `
+Execute a command in the container environment. With the shell option, the first
+argument is executed as a shell script and additional arguments become positional
+parameters of the script starting with $0, for example:
+
+  cne exec -s 'echo $0 $1' a b
+
+If stdin is a terminal, stdin is attached and a pseudo-terminal is allocated for
+the command. The interactive and tty options override this behavior, for example,
+for piping input to a command:
+
+  echo foo | cne exec -i cat
+
+Processes share the cgroup of the container, so the cpus and memory options are
+rejected as not supported by the runtime.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: execRunE,
 }
 
 var execShell bool
 var execLayerName string
 var execTestOnly bool
+var execCPUs float64
+var execMemory string
+var execEnvHost bool
+var execGeneration string
+var execInteractive bool
+var execTTY bool
+var execEphemeral bool
+var execUser string
+var execWorkdir string
+var execEnvs []string
+var execEnvFiles []string
+
+// execStream returns the stream for executing a command. Stdin is only attached for
+// interactive commands, and a pseudo-terminal is only allocated if tty is set.
+func execStream(interactive, tty bool) runtime.Stream {
+
+	stream := runtime.Stream{
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Terminal: tty,
+	}
+	if interactive {
+		stream.Stdin = os.Stdin
+	}
+	return stream
+}
+
+// execTerminalMode returns whether stdin is attached and whether a pseudo-terminal is
+// allocated for the command. Options that aren't set explicitly follow stdin: both are enabled
+// if stdin is a terminal, and no pseudo-terminal is allocated if the input is piped.
+func execTerminalMode(cmd *cobra.Command, isTerminal bool) (bool, bool) {
+
+	flags := cmd.Flags()
+	interactive, _ := flags.GetBool("interactive")
+	tty, _ := flags.GetBool("tty")
+
+	if !flags.Changed("interactive") {
+		interactive = interactive || isTerminal
+	}
+	if !flags.Changed("tty") {
+		tty = isTerminal
+	}
+	return interactive, tty
+}
+
+// passwdEntry describes the ids of a user in the passwd file.
+type passwdEntry struct {
+	name string
+	uid  uint32
+	gid  uint32
+}
+
+// parsePasswd parses the entries of a passwd file and skips malformed lines.
+func parsePasswd(r io.Reader) []passwdEntry {
+
+	var entries []passwdEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 4 {
+			continue
+		}
+		uid, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		gid, err := strconv.ParseUint(fields[3], 10, 32)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, passwdEntry{fields[0], uint32(uid), uint32(gid)})
+	}
+	return entries
+}
+
+// resolveExecUser returns the uid and gid for the user provided as name, uid, or uid:gid.
+// Names are looked up in the provided passwd entries, and the gid defaults to the group of
+// the user in the passwd entries or to 0 if the user isn't listed.
+func resolveExecUser(spec string, passwd []passwdEntry) (uint32, uint32, error) {
+
+	userPart := spec
+	groupPart := ""
+	if pos := strings.Index(spec, ":"); pos >= 0 {
+		userPart = spec[:pos]
+		groupPart = spec[pos+1:]
+		if groupPart == "" {
+			return 0, 0, errdefs.InvalidArgument("missing group id in user '%s'", spec)
+		}
+	}
+	if userPart == "" {
+		return 0, 0, errdefs.InvalidArgument("missing user in '%s'", spec)
+	}
+
+	var entry *passwdEntry
+	var uid uint32
+	if id, err := strconv.ParseUint(userPart, 10, 32); err == nil {
+		uid = uint32(id)
+		for i := range passwd {
+			if passwd[i].uid == uid {
+				entry = &passwd[i]
+				break
+			}
+		}
+	} else {
+		for i := range passwd {
+			if passwd[i].name == userPart {
+				entry = &passwd[i]
+				break
+			}
+		}
+		if entry == nil {
+			return 0, 0, errdefs.InvalidArgument("no such user in the container: %s", userPart)
+		}
+		uid = entry.uid
+	}
+
+	var gid uint32
+	if groupPart != "" {
+		id, err := strconv.ParseUint(groupPart, 10, 32)
+		if err != nil {
+			return 0, 0, errdefs.InvalidArgument("invalid group id '%s'", groupPart)
+		}
+		gid = uint32(id)
+	} else if entry != nil {
+		gid = entry.gid
+	}
+
+	return uid, gid, nil
+}
+
+// probeContainer executes a command for inspecting the container. The command runs in the
+// default working directory of the container with the default environment, as the working
+// directory of the host might not exist in the container.
+func probeContainer(ctr *container.Container, ws *project.Workspace, stream runtime.Stream,
+	args []string) (uint32, error) {
+
+	probeUsr := user
+	probeUsr.Pwd = container.DefaultProcessSpec().Cwd
+	return ctr.Exec(ws, &probeUsr, stream, args, nil, nil)
+}
+
+// containerPasswd returns the passwd entries of the container or nil if the passwd file
+// cannot be read.
+func containerPasswd(ctr *container.Container, ws *project.Workspace) []passwdEntry {
+
+	var buf bytes.Buffer
+	stream := runtime.Stream{Stdout: &buf, Stderr: ioutil.Discard}
+	code, err := probeContainer(ctr, ws, stream, []string{"cat", "/etc/passwd"})
+	if err != nil || code != 0 {
+		return nil
+	}
+	return parsePasswd(&buf)
+}
+
+// validateWorkdir checks that the working directory is an absolute path in the container.
+func validateWorkdir(dir string) error {
+	if !path.IsAbs(dir) {
+		return errdefs.InvalidArgument("working directory must be an absolute path: %s", dir)
+	}
+	return nil
+}
+
+// containerDirExists returns true if the directory exists in the container.
+func containerDirExists(ctr *container.Container, ws *project.Workspace, dir string) bool {
+
+	stream := runtime.Stream{Stdout: ioutil.Discard, Stderr: ioutil.Discard}
+	code, err := probeContainer(ctr, ws, stream, []string{"test", "-d", dir})
+	return err == nil && code == 0
+}
+
+// parseExecEnv returns the environment variables for the KEY=VALUE or KEY entries. Entries
+// without a value are imported from the caller's environment and skipped if not set.
+func parseExecEnv(entries []string, lookup func(string) (string, bool)) ([]string, error) {
+
+	envs := []string{}
+	for _, e := range entries {
+		if strings.HasPrefix(e, "=") {
+			return nil, errdefs.InvalidArgument("invalid environment variable: '%s'", e)
+		}
+		if strings.Contains(e, "=") {
+			envs = append(envs, e)
+		} else if val, ok := lookup(e); ok {
+			envs = append(envs, e+"="+val)
+		}
+	}
+	return envs, nil
+}
+
+// overrideEnv returns the environment variables that aren't overridden by the provided
+// variables.
+func overrideEnv(envs, overrides []string) []string {
+
+	keys := map[string]bool{}
+	for _, e := range overrides {
+		keys[strings.SplitN(e, "=", 2)[0]] = true
+	}
+
+	filtered := []string{}
+	for _, e := range envs {
+		if !keys[strings.SplitN(e, "=", 2)[0]] {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// mergeEnv returns the environment variables with the provided variables overriding
+// variables with the same name.
+func mergeEnv(envs, overrides []string) []string {
+	return append(overrideEnv(envs, overrides), overrides...)
+}
+
+// readEnvFile reads the environment variable entries from the file. Empty lines and lines
+// starting with '#' are skipped.
+func readEnvFile(name string) ([]string, error) {
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to open environment file '%s'", name)
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errdefs.SystemError(err, "failed to read environment file '%s'", name)
+	}
+	return entries, nil
+}
+
+// defaultShell is the shell that is used if the selected shell doesn't exist in the container.
+const defaultShell = "/bin/sh"
+
+// shellFallbackScript executes the shell passed as $0 with the remaining arguments, or the
+// default shell with a warning if the shell doesn't exist in the container.
+const shellFallbackScript = `if [ -x "$0" ]; then exec "$0" "$@"; fi; ` +
+	`echo "WARNING: shell '$0' not found, using ` + defaultShell + `" >&2; ` +
+	`exec ` + defaultShell + ` "$@"`
 
-// execCommandsInShell executes the provided commands in a shell.
+// shellArgs returns the arguments for executing the script in the shell. Additional arguments
+// become the positional parameters of the script starting with $0. Any other shell than the
+// default shell is started through the default shell, which falls back to the default shell
+// if the shell doesn't exist in the container.
+func shellArgs(shell, script string, params []string) []string {
+	if shell == "" || shell == defaultShell {
+		return append([]string{defaultShell, "-c", script}, params...)
+	}
+	return append([]string{defaultShell, "-c", shellFallbackScript, shell, "-c", script},
+		params...)
+}
+
+// execCommandsInShell executes the commands of the first argument in the shell of the
+// workspace or, if not set, the default shell and passes the remaining arguments as
+// positional parameters.
 func execCommandsInShell(wsName, layerName string, args []string) (int, error) {
-	args = append([]string{"/bin/sh", "-c"}, args...)
-	return execCommands(wsName, layerName, args)
+
+	prj, err := loadProject()
+	if err != nil {
+		return 0, err
+	}
+
+	ws, err := getWorkspace(prj, wsName)
+	if err != nil {
+		return 0, err
+	}
+
+	// pass the name of the resolved workspace, so the workspace isn't picked again
+	return execCommands(ws.Name, layerName, shellArgs(ws.Shell, args[0], args[1:]))
 }
 
 // execCommands executes the provided commands in the current or provided workspace.
@@ -37,7 +330,7 @@ func execCommandsInShell(wsName, layerName string, args []string) (int, error) {
 // similar return value as if the command was executed directly.
 func execCommands(wsName, layerName string, args []string) (int, error) {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return 0, err
 	}
@@ -48,33 +341,52 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 		return 0, err
 	}
 
-	var ws *project.Workspace
-	if wsName == "" {
-		ws, err = prj.CurrentWorkspace()
-		if err != nil {
-			return 0, err
-		}
+	ws, err := getWorkspace(prj, wsName)
+	if err != nil {
+		return 0, err
 	}
 
-	stream := runtime.Stream{
-		Stdin:    os.Stdin,
-		Stdout:   os.Stdout,
-		Stderr:   os.Stderr,
-		Terminal: true,
-	}
+	stream, logBuf := logStream(execStream(execInteractive, execTTY))
+	defer saveLogBufferWarning(ws, logBuf)
 
-	con := console.Current()
-	defer con.Reset()
+	if stream.Terminal {
+		con := console.Current()
+		defer con.Reset()
 
-	// TODO: check return errors?
-	con.SetRaw()
-	winSz, _ := con.Size()
-	con.Resize(winSz)
+		// TODO: check return errors?
+		con.SetRaw()
+		winSz, _ := con.Size()
+		con.Resize(winSz)
+	}
+
+	if execGeneration != "" && execLayerName != "" {
+		return 0, errdefs.InvalidArgument("generation and layer options are exclusive")
+	}
+	if execEphemeral && execLayerName != "" {
+		return 0, errdefs.InvalidArgument("ephemeral and layer options are exclusive")
+	}
+	if execUser != "" && execLayerName != "" {
+		return 0, errdefs.InvalidArgument("user and layer options are exclusive")
+	}
+	if execWorkdir != "" && execLayerName != "" {
+		return 0, errdefs.InvalidArgument("workdir and layer options are exclusive")
+	}
+	if execWorkdir != "" {
+		err = validateWorkdir(execWorkdir)
+		if err != nil {
+			return 0, err
+		}
+	}
 
 	if execLayerName == "" {
 
-		ctr, err := container.Get(run, ws)
-		if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		var ctr *container.Container
+		if execGeneration != "" {
+			ctr, err = getContainerGeneration(run, ws, execGeneration)
+		} else {
+			ctr, err = container.Get(run, ws)
+		}
+		if err != nil && (execGeneration != "" || !errors.Is(err, errdefs.ErrNotFound)) {
 			return 0, err
 		}
 		if ctr == nil {
@@ -85,11 +397,63 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 			prj.Write()
 		}
 
-		code, err := ctr.Exec(&user, stream, args)
+		if execEphemeral {
+			ctr, err = ctr.Ephemeral()
+			if err != nil {
+				return 0, err
+			}
+			defer ctr.Delete()
+		}
+
+		execUsr := user
+		if execUser != "" {
+			uid, gid, err := resolveExecUser(execUser, containerPasswd(ctr, ws))
+			if err != nil {
+				return 0, err
+			}
+			execUsr.UID = uid
+			execUsr.GID = gid
+			execUsr.IsSudo = false
+		}
+		if execWorkdir != "" {
+			if !containerDirExists(ctr, ws, execWorkdir) {
+				return 0, errdefs.NotFound("directory", execWorkdir)
+			}
+			execUsr.Pwd = execWorkdir
+		}
+
+		envs := []string{}
+		if execEnvHost {
+			envs = conf.HostEnv.Filter(os.Environ())
+		}
+
+		entries := []string{}
+		for _, f := range execEnvFiles {
+			fileEntries, err := readEnvFile(f)
+			if err != nil {
+				return 0, err
+			}
+			entries = append(entries, fileEntries...)
+		}
+		argEnvs, err := parseExecEnv(append(entries, execEnvs...), os.LookupEnv)
+		if err != nil {
+			return 0, err
+		}
+		envs = mergeEnv(mergeEnv(envs, ws.EnvList()), argEnvs)
+
+		res, err := execResources()
+		if err != nil {
+			return 0, err
+		}
+
+		code, err := ctr.Exec(ws, &execUsr, stream, args, envs, res)
 		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
 			return 0, errors.New(args[0] + ": no such command")
 		}
 		if err != nil {
+			return 0, err
+		}
+		if code != 0 {
 			return int(code), nil
 		}
 
@@ -110,7 +474,7 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 		if err != nil {
 			return 0, err
 		}
-		code, err := ctr.BuildExec(&user, stream, args, []string{})
+		code, err := ctr.BuildExec(&user, stream, args, ws.EnvList())
 		if err != nil {
 			return 0, err
 		}
@@ -140,11 +504,39 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 	return 0, nil
 }
 
+// execResources returns the resource overrides of the command line or nil if none.
+func execResources() (*container.ProcessResources, error) {
+
+	if execCPUs == 0 && execMemory == "" {
+		return nil, nil
+	}
+
+	res := &container.ProcessResources{CPUs: execCPUs}
+	if execMemory != "" {
+		var err error
+		res.Memory, err = siStringToSize(execMemory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
 func execRunE(cmd *cobra.Command, args []string) error {
 
 	var code int
-	var err error
 
+	// reject unsupported resource overrides before building or copying the container
+	res, err := execResources()
+	if err != nil {
+		return err
+	}
+	err = res.Validate()
+	if err != nil {
+		return err
+	}
+
+	execInteractive, execTTY = execTerminalMode(cmd, term.IsTerminal(int(os.Stdin.Fd())))
 	if execShell {
 		code, err = execCommandsInShell("", "", args)
 	} else {
@@ -163,5 +555,27 @@ func init() {
 		"Execute a command in this layer to rebuild the layer and amend the project")
 	execCmd.Flags().BoolVar(&execTestOnly, "test-only", false,
 		"Don't amend the layer")
+	execCmd.Flags().Float64Var(&execCPUs, "cpus", 0,
+		"Number of CPUs for the command (not supported by the runtime)")
+	execCmd.Flags().StringVar(&execMemory, "memory", "",
+		"Memory limit for the command, e.g. 512M or 2G (not supported by the runtime)")
+	execCmd.Flags().BoolVar(&execEnvHost, "env-host", true,
+		"Pass the host environment, filtered by the HostEnv configuration")
+	execCmd.Flags().StringVar(&execGeneration, "generation", "",
+		"Execute the command in the container with this generation")
+	execCmd.Flags().BoolVarP(&execInteractive, "interactive", "i", false,
+		"Keep stdin attached to the command (default if stdin is a terminal)")
+	execCmd.Flags().BoolVarP(&execTTY, "tty", "t", false,
+		"Allocate a pseudo-terminal for the command (default if stdin is a terminal)")
+	execCmd.Flags().BoolVar(&execEphemeral, "ephemeral", false,
+		"Execute the command in a temporary copy of the container and discard any changes")
+	execCmd.Flags().StringVarP(&execUser, "user", "u", "",
+		"Execute the command as this user (name, uid, or uid:gid)")
+	execCmd.Flags().StringVarP(&execWorkdir, "workdir", "w", "",
+		"Working directory of the command inside the container")
+	execCmd.Flags().StringArrayVarP(&execEnvs, "env", "e", nil,
+		"Set the environment variable KEY=VALUE or pass KEY from the current environment")
+	execCmd.Flags().StringArrayVar(&execEnvFiles, "env-file", nil,
+		"Read environment variables from the file")
 	rootCmd.AddCommand(execCmd)
 }
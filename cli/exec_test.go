@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+func TestExecStream(t *testing.T) {
+
+	tests := []struct {
+		interactive bool
+		tty         bool
+	}{
+		{false, false},
+		{true, false},
+		{false, true},
+		{true, true},
+	}
+
+	for _, tc := range tests {
+		stream := execStream(tc.interactive, tc.tty)
+		if (stream.Stdin != nil) != tc.interactive {
+			t.Errorf("interactive=%t tty=%t: unexpected stdin: %v",
+				tc.interactive, tc.tty, stream.Stdin)
+		}
+		if stream.Terminal != tc.tty {
+			t.Errorf("interactive=%t tty=%t: unexpected terminal: %t",
+				tc.interactive, tc.tty, stream.Terminal)
+		}
+		if stream.Stdout == nil || stream.Stderr == nil {
+			t.Errorf("interactive=%t tty=%t: output not attached", tc.interactive, tc.tty)
+		}
+	}
+}
+
+func TestExecResolveUser(t *testing.T) {
+
+	passwd := parsePasswd(strings.NewReader(
+		"root:x:0:0:root:/root:/bin/bash\n" +
+			"malformed\n" +
+			"dev:x:1000:100::/home/dev:/bin/sh\n"))
+	if len(passwd) != 2 {
+		t.Fatalf("Expected 2 passwd entries, got %v", passwd)
+	}
+
+	tests := []struct {
+		spec string
+		uid  uint32
+		gid  uint32
+	}{
+		{"root", 0, 0},
+		{"dev", 1000, 100},
+		{"1000", 1000, 100},
+		{"1000:5", 1000, 5},
+		{"dev:5", 1000, 5},
+		{"2000", 2000, 0},
+	}
+	for _, tc := range tests {
+		uid, gid, err := resolveExecUser(tc.spec, passwd)
+		if err != nil || uid != tc.uid || gid != tc.gid {
+			t.Errorf("%s: expected %d:%d, got %d:%d %v", tc.spec, tc.uid, tc.gid, uid, gid, err)
+		}
+	}
+
+	// numeric ids don't require the passwd file
+	uid, gid, err := resolveExecUser("1000:100", nil)
+	if err != nil || uid != 1000 || gid != 100 {
+		t.Errorf("Expected 1000:100 without passwd, got %d:%d %v", uid, gid, err)
+	}
+
+	for _, spec := range []string{"nobody", "dev:staff", "dev:", ":100", "nobody:100"} {
+		if _, _, err := resolveExecUser(spec, passwd); err == nil {
+			t.Errorf("%s: expected error", spec)
+		}
+	}
+}
+
+func TestExecValidateWorkdir(t *testing.T) {
+
+	if err := validateWorkdir("/home/dev"); err != nil {
+		t.Errorf("Absolute path should be valid: %v", err)
+	}
+	for _, dir := range []string{"home/dev", "./dev", ""} {
+		if err := validateWorkdir(dir); err == nil {
+			t.Errorf("Relative path '%s' should fail", dir)
+		}
+	}
+}
+
+func TestExecParseEnv(t *testing.T) {
+
+	lookup := func(key string) (string, bool) {
+		if key == "PROXY" {
+			return "http://proxy:8080", true
+		}
+		return "", false
+	}
+
+	envs, err := parseExecEnv([]string{"DEBUG=1", "PROXY", "UNSET", "EMPTY=", "A=b=c"}, lookup)
+	if err != nil {
+		t.Fatalf("Failed to parse environment: %v", err)
+	}
+	expected := []string{"DEBUG=1", "PROXY=http://proxy:8080", "EMPTY=", "A=b=c"}
+	if !reflect.DeepEqual(envs, expected) {
+		t.Errorf("Expected %v, got %v", expected, envs)
+	}
+
+	if _, err := parseExecEnv([]string{"=1"}, lookup); err == nil {
+		t.Errorf("Entry without a key should fail")
+	}
+
+	envs = overrideEnv([]string{"DEBUG=0", "HOME=/home/dev"}, expected)
+	if !reflect.DeepEqual(envs, []string{"HOME=/home/dev"}) {
+		t.Errorf("Overridden variable not removed: %v", envs)
+	}
+}
+
+func TestExecReadEnvFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cne-env")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "env")
+	err = ioutil.WriteFile(name, []byte("# comment\nDEBUG=1\n\n  PROXY  \n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	entries, err := readEnvFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !reflect.DeepEqual(entries, []string{"DEBUG=1", "PROXY"}) {
+		t.Errorf("Unexpected entries: %v", entries)
+	}
+
+	if _, err := readEnvFile(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Missing file should fail")
+	}
+}
+
+func TestExecShellArgs(t *testing.T) {
+
+	// workspaces without a shell use /bin/sh
+	expected := []string{"/bin/sh", "-c", "echo $0 $1", "a", "b"}
+	if args := shellArgs("", "echo $0 $1", []string{"a", "b"}); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+	args := shellArgs("/bin/sh", "echo $0 $1", []string{"a", "b"})
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		t.Skipf("Failed to run shell: %v", err)
+	}
+	if string(out) != "a b\n" {
+		t.Errorf("Unexpected shell output: %q", out)
+	}
+
+	// other shells fall back to /bin/sh if they don't exist
+	for _, shell := range []string{"/bin/bash", "/nonexistent/shell"} {
+		var stderr bytes.Buffer
+		args = shellArgs(shell, "echo $0 $1", []string{"a", "b"})
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stderr = &stderr
+		out, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("Failed to run shell '%s': %v", shell, err)
+		}
+		if string(out) != "a b\n" {
+			t.Errorf("Unexpected output for shell '%s': %q", shell, out)
+		}
+		_, statErr := os.Stat(shell)
+		if (statErr != nil) != strings.Contains(stderr.String(), "WARNING") {
+			t.Errorf("Unexpected warning for shell '%s': %q", shell, stderr.String())
+		}
+	}
+}
+
+func TestExecExitCode(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	_, run := setupBuildProject(t, dir)
+
+	// exit code of a process that was terminated by SIGINT
+	run.SetExecFunc(func(stream runtime.Stream, args []string) uint32 { return 130 })
+	code, err := execCommands("", "", []string{"sleep", "10"})
+	if err != nil || code != 130 {
+		t.Errorf("Expected exit code 130, got %d %v", code, err)
+	}
+
+	run.SetExecFunc(nil)
+	code, err = execCommands("", "", []string{"true"})
+	if err != nil || code != 0 {
+		t.Errorf("Expected exit code 0, got %d %v", code, err)
+	}
+}
+
+func TestExecResources(t *testing.T) {
+
+	savedPath := projectPath
+	defer func() { projectPath, execCPUs, execMemory = savedPath, 0, "" }()
+
+	// the resources are rejected before the project is loaded
+	projectPath = "/nonexistent"
+	execCPUs = 2
+	err := execRunE(execCmd, []string{"true"})
+	if !errors.Is(err, errdefs.ErrNotImplemented) {
+		t.Errorf("CPU limit should not be supported: %v", err)
+	}
+
+	execCPUs, execMemory = 0, "512M"
+	err = execRunE(execCmd, []string{"true"})
+	if !errors.Is(err, errdefs.ErrNotImplemented) {
+		t.Errorf("Memory limit should not be supported: %v", err)
+	}
+
+	execMemory = "invalid"
+	if err = execRunE(execCmd, []string{"true"}); err == nil {
+		t.Errorf("Invalid memory limit should fail")
+	}
+}
+
+func TestExecTerminalMode(t *testing.T) {
+
+	tests := []struct {
+		args        []string
+		isTerminal  bool
+		interactive bool
+		tty         bool
+	}{
+		{[]string{}, true, true, true},
+		{[]string{}, false, false, false},
+		{[]string{"-i"}, false, true, false},
+		{[]string{"-t=false"}, true, true, false},
+		{[]string{"-i=false", "-t"}, false, false, true},
+	}
+
+	for _, tc := range tests {
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolP("interactive", "i", false, "")
+		cmd.Flags().BoolP("tty", "t", false, "")
+		if err := cmd.Flags().Parse(tc.args); err != nil {
+			t.Fatalf("%v: failed to parse flags: %v", tc.args, err)
+		}
+
+		interactive, tty := execTerminalMode(cmd, tc.isTerminal)
+		if interactive != tc.interactive || tty != tc.tty {
+			t.Errorf("%v terminal=%t: expected %t/%t, got %t/%t", tc.args, tc.isTerminal,
+				tc.interactive, tc.tty, interactive, tty)
+		}
+
+		stream := execStream(interactive, tty)
+		if !tc.isTerminal && len(tc.args) == 0 && stream.Terminal {
+			t.Errorf("Piped input should not allocate a terminal")
+		}
+	}
+}
+
+func TestExecProbe(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	_, run := setupBuildProject(t, dir)
+
+	// the probe for the user doesn't run in the directory of the host
+	defer func() { execUser = "" }()
+	execUser = "0"
+	if _, err := execCommands("", "", []string{"true"}); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	procs := run.Processes()
+	if len(procs) != 2 {
+		t.Fatalf("Expected the probe and the command, got %v", run.Commands())
+	}
+	if procs[0].Args[0] != "cat" || procs[0].Cwd != "/" {
+		t.Errorf("Probe '%v' should run in '/', not '%s'", procs[0].Args, procs[0].Cwd)
+	}
+	hasPath := false
+	for _, e := range procs[0].Env {
+		hasPath = hasPath || strings.HasPrefix(e, "PATH=")
+	}
+	if !hasPath {
+		t.Errorf("Probe should have the default environment: %v", procs[0].Env)
+	}
+
+	// the probe for the working directory also doesn't run in the directory of the host
+	execUser = ""
+	defer func() { execWorkdir = "" }()
+	execWorkdir = "/work"
+	if _, err := execCommands("", "", []string{"true"}); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	procs = run.Processes()[2:]
+	if len(procs) != 2 {
+		t.Fatalf("Expected the probe and the command, got %v", run.Commands())
+	}
+	if procs[0].Args[0] != "test" || procs[0].Cwd != "/" {
+		t.Errorf("Probe '%v' should run in '/', not '%s'", procs[0].Args, procs[0].Cwd)
+	}
+	if procs[1].Cwd != "/work" {
+		t.Errorf("Command should run in '/work', not '%s'", procs[1].Cwd)
+	}
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find PATTERN",
+	Short: "Find workspaces and layers matching a pattern",
+	Long: `
+Search the workspace names, origins, layer names, and layer commands of the project
+for the pattern. The pattern is matched as a case-insensitive substring or, with the
+--regex option, as a case-insensitive regular expression.`,
+	Args: cobra.ExactArgs(1),
+	RunE: findRunE,
+}
+
+var findRegex bool
+
+// findEntry describes a match and its location in the project.
+type findEntry struct {
+	Workspace string
+	Layer     string
+	Field     string
+	Value     string
+}
+
+// newFindMatcher returns a case-insensitive matcher for the pattern.
+func newFindMatcher(pattern string, regex bool) (func(string) bool, error) {
+
+	if regex {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, errdefs.InvalidArgument("invalid pattern '%s': %v", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	pattern = strings.ToLower(pattern)
+	return func(s string) bool {
+		return strings.Contains(strings.ToLower(s), pattern)
+	}, nil
+}
+
+// findInProject returns all workspace and layer fields that match.
+func findInProject(prj *project.Project, match func(string) bool) []findEntry {
+
+	entries := []findEntry{}
+	for _, ws := range prj.Workspaces {
+		if match(ws.Name) {
+			entries = append(entries, findEntry{ws.Name, "-", "name", ws.Name})
+		}
+		if match(ws.Environment.Origin) {
+			entries = append(entries,
+				findEntry{ws.Name, "-", "origin", ws.Environment.Origin})
+		}
+		for _, l := range ws.Environment.Layers {
+			if match(l.Name) {
+				entries = append(entries, findEntry{ws.Name, l.Name, "layer", l.Name})
+			}
+			for _, c := range l.Commands {
+				cmdLine := strings.Join(c.Args, " ")
+				if match(cmdLine) {
+					entries = append(entries,
+						findEntry{ws.Name, l.Name, "command", cmdLine})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+func findRunE(cmd *cobra.Command, args []string) error {
+
+	match, err := newFindMatcher(args[0], findRegex)
+	if err != nil {
+		return err
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	printList(findInProject(prj, match), false)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+	findCmd.Flags().BoolVar(&findRegex, "regex", false,
+		"Match the pattern as a regular expression")
+}
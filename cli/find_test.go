@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/czankel/cne/project"
+)
+
+func TestFindInProject(t *testing.T) {
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "ubuntu:20.04", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+	ws.Environment.Layers = []project.Layer{
+		{Name: "base", Commands: []project.Command{
+			{Args: []string{"apt-get", "install", "gcc"}}}},
+		{Name: "tools", Commands: []project.Command{
+			{Args: []string{"make", "install"}}}},
+	}
+
+	match, err := newFindMatcher("GCC", false)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+	entries := findInProject(prj, match)
+	if len(entries) != 1 || entries[0].Layer != "base" || entries[0].Field != "command" {
+		t.Errorf("Expected the command in the base layer, got %v", entries)
+	}
+
+	match, err = newFindMatcher("^(make|ubuntu)", true)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+	entries = findInProject(prj, match)
+	if len(entries) != 2 || entries[0].Field != "origin" || entries[1].Layer != "tools" {
+		t.Errorf("Expected the origin and the tools command, got %v", entries)
+	}
+
+	_, err = newFindMatcher("(", true)
+	if err == nil {
+		t.Errorf("Invalid regular expression should fail")
+	}
+}
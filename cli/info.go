@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/runtime"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Display system-wide information",
+	Long: `
+Display the version of cne, the runtime and its daemon, the configured
+registries, the number of images, containers, and snapshots, and the
+user. If the runtime cannot be accessed, only the information from the
+configuration is shown.`,
+	Args: cobra.NoArgs,
+	RunE: infoRunE,
+}
+
+// runtimeInfo describes the runtime and the objects managed by the runtime.
+type runtimeInfo struct {
+	Name       string
+	Socket     string
+	Namespace  string
+	Version    string
+	Revision   string
+	Status     string
+	Images     int
+	Containers int
+	Snapshots  int
+}
+
+// userInfo describes the user that runs cne.
+type userInfo struct {
+	Username string
+	UID      uint32
+	GID      uint32
+	IsSudo   bool
+}
+
+// systemInfo describes cne, the runtime, and the configuration.
+type systemInfo struct {
+	Version    string
+	Runtime    runtimeInfo
+	Registries map[string]string
+	User       userInfo
+}
+
+const runtimeStatusAvailable = "available"
+
+// getSystemInfo returns the system information. Errors for accessing the runtime are reported
+// in the runtime status.
+func getSystemInfo(run runtime.Runtime, conf *config.Config, user config.User) *systemInfo {
+
+	info := &systemInfo{
+		Version: config.CneVersion,
+		Runtime: runtimeInfo{
+			Name:      conf.Runtime.Name,
+			Socket:    conf.Runtime.SocketName,
+			Namespace: run.Namespace(),
+		},
+		Registries: map[string]string{},
+		User: userInfo{
+			Username: user.Username,
+			UID:      user.UID,
+			GID:      user.GID,
+			IsSudo:   user.IsSudo,
+		},
+	}
+
+	for name, reg := range conf.Registry {
+		info.Registries[name] = reg.Domain + "/" + reg.RepoName
+	}
+
+	runInfo, err := run.Version()
+	if err == nil {
+		err = getRuntimeCounts(run, &info.Runtime)
+	}
+	if err != nil {
+		info.Runtime.Status = err.Error()
+		return info
+	}
+
+	info.Runtime.Version = runInfo.Version
+	info.Runtime.Revision = runInfo.Revision
+	info.Runtime.Status = runtimeStatusAvailable
+
+	return info
+}
+
+// getRuntimeCounts sets the number of images, containers, and snapshots of the runtime.
+func getRuntimeCounts(run runtime.Runtime, info *runtimeInfo) error {
+
+	imgs, err := run.Images()
+	if err != nil {
+		return err
+	}
+	ctrs, _, err := run.Containers()
+	if err != nil {
+		return err
+	}
+	snaps, err := run.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	info.Images = len(imgs)
+	info.Containers = len(ctrs)
+	info.Snapshots = len(snaps)
+	return nil
+}
+
+func infoRunE(cmd *cobra.Command, args []string) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	info := getSystemInfo(run, conf, user)
+	if output == outputText && info.Runtime.Status != runtimeStatusAvailable {
+		info.Runtime.Status = colorize(colorRed, info.Runtime.Status)
+	}
+	printValue("Field", "Value", "", info)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
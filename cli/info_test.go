@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+func TestCliSystemInfo(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	testConf := &config.Config{
+		Runtime: config.Runtime{Name: "mock", SocketName: "/run/mock.sock"},
+		Registry: map[string]*config.Registry{
+			"docker.io": {Domain: "docker.io", RepoName: "library"},
+		},
+	}
+	testUser := config.User{Username: "test", UID: 1000, GID: 1000}
+
+	info := getSystemInfo(run, testConf, testUser)
+	if info.Runtime.Status != runtimeStatusAvailable || info.Runtime.Version != "1.0.0" ||
+		info.Runtime.Images != 1 || info.Runtime.Namespace != "test" {
+		t.Errorf("Unexpected runtime information: %v", info.Runtime)
+	}
+	if info.Registries["docker.io"] != "docker.io/library" || info.User.Username != "test" {
+		t.Errorf("Unexpected configuration information: %v", info)
+	}
+
+	run.Fail("Version", errdefs.Unavailable(runtime.SocketResource, "/run/mock.sock",
+		errdefs.InternalError("down")))
+	info = getSystemInfo(run, testConf, testUser)
+	if !strings.Contains(info.Runtime.Status, "unavailable") || info.Runtime.Images != 0 ||
+		info.Runtime.Socket != "/run/mock.sock" {
+		t.Errorf("Expected configuration only for unavailable runtime, got %v", info.Runtime)
+	}
+	if info.Registries["docker.io"] != "docker.io/library" {
+		t.Errorf("Expected registries for unavailable runtime, got %v", info.Registries)
+	}
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/czankel/cne/project"
 )
 
-var initProjectImage string
+var initProjectFrom string
 
 var initCmd = &cobra.Command{
 	Use:   "init [NAME]",
@@ -19,7 +19,10 @@ var initCmd = &cobra.Command{
 	Long: `
 The init command creates a new project in the current directory.
 The project name is optional. If omitted, the name of the current directory
-is used as the project name.`,
+is used as the project name. The from option creates the first workspace of
+the project from the provided base image, for example:
+
+  cne init --from ubuntu:22.04`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: initProjectRunE,
 }
@@ -50,9 +53,18 @@ func initProjectRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if initProjectImage != "" {
+	// create the project file for the user that invoked sudo
+	if user.IsSudo {
+		err = prj.Chown(int(user.UID), int(user.GID))
+		if err != nil {
+			prj.Delete()
+			return err
+		}
+	}
+
+	if initProjectFrom != "" {
 		err = initWorkspace(prj, project.WorkspaceDefaultName,
-			"" /* Insert */, initProjectImage)
+			"" /* Insert */, initProjectFrom, nil)
 		if err != nil {
 			prj.Delete()
 			return err
@@ -65,5 +77,8 @@ func initProjectRunE(cmd *cobra.Command, args []string) error {
 func init() {
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().StringVar(
-		&initProjectImage, "image", "", "Base image")
+		&initProjectFrom, "from", "", "Base image for the first workspace")
+	initCmd.Flags().StringVar(
+		&initProjectFrom, "image", "", "Base image for the first workspace")
+	initCmd.Flags().MarkDeprecated("image", "use --from instead")
 }
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/runtime"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show detailed information about runtime resources",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var inspectSnapshotCmd = &cobra.Command{
+	Use:     "snapshot NAME",
+	Aliases: []string{"s"},
+	Short:   "Show detailed information about a snapshot",
+	Args:    cobra.ExactArgs(1),
+	RunE:    inspectSnapshotRunE,
+}
+
+func inspectSnapshotRunE(cmd *cobra.Command, args []string) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	snap, err := run.GetSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+
+	snapInfo := struct {
+		Name      string
+		Kind      string
+		Parent    string
+		CreatedAt time.Time
+		Size      int64
+		Inodes    int64
+		Labels    map[string]string
+	}{
+		Name:      snap.Name(),
+		Kind:      snap.Kind(),
+		Parent:    snap.Parent(),
+		CreatedAt: snap.CreatedAt(),
+		Labels:    snap.Labels(),
+	}
+	snapInfo.Size, err = snap.Size()
+	if err != nil {
+		return err
+	}
+	snapInfo.Inodes, err = snap.Inodes()
+	if err != nil {
+		return err
+	}
+
+	printValue("Snapshot", "Value", "", snapInfo)
+	return nil
+}
+
+var inspectContainerCmd = &cobra.Command{
+	Use:     "container [WORKSPACE]",
+	Aliases: []string{"c"},
+	Short:   "Show detailed information about the container of a workspace",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    inspectContainerRunE,
+}
+
+var inspectContainerGeneration string
+var inspectContainerCtrdID bool
+
+func inspectContainerRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	wsName := ""
+	if len(args) > 0 {
+		wsName = args[0]
+	}
+	ws, err := getWorkspace(prj, wsName)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	var ctr *container.Container
+	if inspectContainerGeneration != "" {
+		ctr, err = getContainerGeneration(run, ws, inspectContainerGeneration)
+	} else {
+		ctr, err = container.Get(run, ws)
+	}
+	if err != nil {
+		return err
+	}
+
+	if inspectContainerCtrdID {
+		fmt.Println(ctr.RuntimeID)
+		return nil
+	}
+
+	ctrInfo := struct {
+		Name       string
+		Workspace  string
+		Project    string
+		Domain     string
+		ID         string
+		RuntimeID  string
+		Generation string
+		UID        uint32
+		CreatedAt  time.Time
+	}{
+		Name:       ctr.Name,
+		Workspace:  ws.Name,
+		Project:    ctr.DomainName,
+		Domain:     hex.EncodeToString(ctr.Domain[:]),
+		ID:         hex.EncodeToString(ctr.ID[:]),
+		RuntimeID:  ctr.RuntimeID,
+		Generation: hex.EncodeToString(ctr.Generation[:]),
+		UID:        ctr.UID,
+		CreatedAt:  ctr.CreatedAt,
+	}
+
+	printValue("Container", "Value", "", ctrInfo)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.AddCommand(inspectSnapshotCmd)
+	inspectCmd.AddCommand(inspectContainerCmd)
+	inspectContainerCmd.Flags().StringVar(&inspectContainerGeneration, "generation", "",
+		"Inspect the container with this generation")
+	inspectContainerCmd.Flags().BoolVar(&inspectContainerCtrdID, "ctrd-id", false,
+		"Only print the containerd ID of the container")
+}
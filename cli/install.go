@@ -32,7 +32,7 @@ var installAptCmd = &cobra.Command{
 
 func installAptRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -1,9 +1,15 @@
 package cli
 
 import (
+	"encoding/hex"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
 
 	"github.com/spf13/cobra"
 
@@ -98,6 +104,51 @@ func listCommandsRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var listLayersCmd = &cobra.Command{
+	Use:     "layers",
+	Aliases: []string{"layer"},
+	Short:   "list layers",
+	Args:    cobra.NoArgs,
+	RunE:    listLayersRunE,
+}
+
+var listLayersWorkspace string
+
+// listLayers lists the layers of the workspace.
+func listLayers(ws *project.Workspace) {
+
+	layerList := make([]struct {
+		Name     string
+		Type     string
+		Enabled  bool
+		Commands int
+	}, len(ws.Environment.Layers))
+
+	for i, l := range ws.Environment.Layers {
+		layerList[i].Name = l.Name
+		layerList[i].Type = l.Type
+		layerList[i].Enabled = !l.Disabled
+		layerList[i].Commands = len(l.Commands)
+	}
+	printList(layerList, true)
+}
+
+func listLayersRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, listLayersWorkspace)
+	if err != nil {
+		return err
+	}
+
+	listLayers(ws)
+	return nil
+}
+
 var listImagesCmd = &cobra.Command{
 	Use:     "images",
 	Aliases: []string{"image", "i"},
@@ -106,8 +157,6 @@ var listImagesCmd = &cobra.Command{
 	RunE:    listImagesRunE,
 }
 
-const displayHashLength = 8
-
 // splitRepoNameTag splits the provided full name to the image name and tag
 // and resolves any respository aliases from the registered repositories.
 // The default repository is omitted in the name.
@@ -129,6 +178,51 @@ func splitRepoNameTag(name string) (string, string) {
 	return dispName, name[tPos+1:]
 }
 
+// hydrateImagesWorkers is the number of images that are read concurrently for the image list.
+const hydrateImagesWorkers = 8
+
+// imageInfo holds the image information for the image list.
+type imageInfo struct {
+	name      string
+	digest    digest.Digest
+	createdAt time.Time
+	size      int64
+}
+
+// hydrateImages reads the image information that requires access to the content store with up
+// to the given number of concurrent workers. The returned list is sorted by the image name.
+func hydrateImages(images []runtime.Image, workers int) []imageInfo {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	infos := make([]imageInfo, len(images))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i, img := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, img runtime.Image) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			infos[i] = imageInfo{
+				name:      img.Name(),
+				digest:    img.Digest(),
+				createdAt: img.CreatedAt(),
+				size:      img.Size(),
+			}
+		}(i, img)
+	}
+	wg.Wait()
+
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].name < infos[j].name })
+
+	return infos
+}
+
 func listImages(run runtime.Runtime) error {
 
 	images, err := run.Images()
@@ -144,15 +238,15 @@ func listImages(run runtime.Runtime) error {
 		Size      string
 	}, len(images), len(images))
 
-	for i, img := range images {
-		name, tag := splitRepoNameTag(img.Name())
+	for i, info := range hydrateImages(images, hydrateImagesWorkers) {
+		name, tag := splitRepoNameTag(info.name)
 		imgList[i].Name = name
 		imgList[i].Tag = tag
-		digest := img.Digest().String()
+		digest := info.digest.String()
 		dPos := strings.Index(digest, ":")
-		imgList[i].ID = digest[dPos+1 : dPos+1+displayHashLength]
-		imgList[i].CreatedAt = timeToAgoString(img.CreatedAt())
-		imgList[i].Size = sizeToSIString(img.Size())
+		imgList[i].ID = shortID(digest[dPos+1:], 0)
+		imgList[i].CreatedAt = timeToAgoString(info.createdAt)
+		imgList[i].Size = sizeToSIString(info.size)
 	}
 	printList(imgList, false)
 
@@ -161,7 +255,7 @@ func listImages(run runtime.Runtime) error {
 
 func listImagesRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -207,7 +301,7 @@ func listSnapshots(run runtime.Runtime) error {
 
 func listSnapshotsRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -226,23 +320,55 @@ var listContainersCmd = &cobra.Command{
 
 var listContainersAll bool
 
-func listContainers(run runtime.Runtime, prj *project.Project) error {
+// listContainers lists the containers of the project or, if prj is nil, of all projects.
+// If raw is set, it also lists the IDs of containers that cannot be read by the runtime.
+func listContainers(run runtime.Runtime, prj *project.Project, raw bool) error {
 
-	ctrs, err := container.Containers(run, prj, &user)
+	ctrs, warnings, err := container.Containers(run, prj, &user)
 	if err != nil {
 		return err
 	}
+	printWarnings(warnings)
+
+	type ctrEntry struct {
+		Name       string
+		Project    string
+		Workspace  string
+		Generation string
+		CreatedAt  string
+		UID        string
+	}
+	ctrList := make([]ctrEntry, len(ctrs), len(ctrs))
 
-	ctrList := make([]struct {
-		Name      string
-		CreatedAt string
-		UID       uint32
-	}, len(ctrs), len(ctrs))
-
+	listed := map[string]bool{}
 	for i, c := range ctrs {
 		ctrList[i].Name = c.Name
+		ctrList[i].Project = c.DomainName
+		ctrList[i].Generation = shortID(hex.EncodeToString(c.Generation[:]), 0)
 		ctrList[i].CreatedAt = timeToAgoString(c.CreatedAt)
-		ctrList[i].UID = c.UID
+		ctrList[i].UID = strconv.FormatUint(uint64(c.UID), 10)
+		if prj != nil {
+			for _, ws := range prj.Workspaces {
+				if ws.ID() == c.ID {
+					ctrList[i].Workspace = ws.Name
+				}
+			}
+		}
+		listed[hex.EncodeToString(c.Domain[:])+"-"+hex.EncodeToString(c.ID[:])] = true
+	}
+
+	if raw {
+		ids, err := run.ContainerIDs()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if !listed[id] {
+				ctrList = append(ctrList, ctrEntry{
+					Name: id, Project: "-", Workspace: "-", Generation: "-",
+					CreatedAt: "-", UID: "-"})
+			}
+		}
 	}
 
 	printList(ctrList, false)
@@ -254,7 +380,7 @@ func listContainersRunE(cmd *cobra.Command, args []string) error {
 
 	var prj *project.Project
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -267,7 +393,7 @@ func listContainersRunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return listContainers(run, prj)
+	return listContainers(run, prj, listContainersAll)
 }
 
 var listResourcesCmd = &cobra.Command{
@@ -284,7 +410,7 @@ func listResourcesRunE(cmd *cobra.Command, args []string) error {
 
 	var prj *project.Project
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -304,7 +430,7 @@ func listResourcesRunE(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("\nCONTAINERS\n----------\n")
-	err = listContainers(run, prj)
+	err = listContainers(run, prj, false)
 	if err != nil {
 		return err
 	}
@@ -324,13 +450,17 @@ func init() {
 	listCmd.AddCommand(listImagesCmd)
 	listCmd.AddCommand(listContainersCmd)
 	listContainersCmd.Flags().BoolVarP(
-		&listContainersAll, "all", "A", false, "list containers of all projects")
+		&listContainersAll, "all", "A", false,
+		"list containers of all projects including containers that cannot be read")
 	listCmd.AddCommand(listSnapshotsCmd)
 	listCmd.AddCommand(listCommandsCmd)
 	listCommandsCmd.Flags().StringVarP(
 		&listCommandsWorkspace, "workspace", "w", "", "Name of the workspace")
 	listCommandsCmd.Flags().StringVarP(
 		&listCommandsLayer, "layer", "l", "", "Name or index of the layer")
+	listCmd.AddCommand(listLayersCmd)
+	listLayersCmd.Flags().StringVarP(
+		&listLayersWorkspace, "workspace", "w", "", "Name of the workspace")
 	listCmd.AddCommand(listResourcesCmd)
 	listResourcesCmd.Flags().BoolVarP(
 		&listResourcesAll, "all", "A", false, "list resources from all projects")
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+
+	"github.com/czankel/cne/runtime"
+)
+
+// slowImage simulates the latency of reading the image information from the content store.
+type slowImage struct {
+	testImage
+	delay time.Duration
+}
+
+func (img *slowImage) Digest() digest.Digest {
+	time.Sleep(img.delay)
+	return img.testImage.Digest()
+}
+
+func (img *slowImage) CreatedAt() time.Time {
+	time.Sleep(img.delay)
+	return img.testImage.CreatedAt()
+}
+
+func (img *slowImage) Size() int64 {
+	time.Sleep(img.delay)
+	return img.testImage.Size()
+}
+
+func newSlowImages(count int, delay time.Duration) []runtime.Image {
+
+	images := make([]runtime.Image, count)
+	for i := range images {
+		// reverse order to verify that the list is sorted
+		images[i] = &slowImage{
+			testImage: testImage{
+				name:   fmt.Sprintf("docker.io/library/image%02d:latest", count-i),
+				digest: digest.FromString(fmt.Sprintf("image%d", i)),
+				size:   int64(i),
+			},
+			delay: delay,
+		}
+	}
+	return images
+}
+
+func TestHydrateImages(t *testing.T) {
+
+	images := newSlowImages(20, time.Millisecond)
+
+	infos := hydrateImages(images, hydrateImagesWorkers)
+	if len(infos) != len(images) {
+		t.Fatalf("Expected %d images, got %d", len(images), len(infos))
+	}
+	for i, info := range infos {
+		if i > 0 && infos[i-1].name >= info.name {
+			t.Errorf("Image list not sorted: %s before %s", infos[i-1].name, info.name)
+		}
+		img := images[len(images)-1-i]
+		if info.name != img.Name() || info.digest != img.Digest() || info.size != img.Size() {
+			t.Errorf("Unexpected image information for %s: %v", img.Name(), info)
+		}
+	}
+}
+
+func BenchmarkHydrateImages(b *testing.B) {
+
+	images := newSlowImages(50, 100*time.Microsecond)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hydrateImages(images, 1)
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hydrateImages(images, hydrateImagesWorkers)
+		}
+	})
+}
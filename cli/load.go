@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var loadCmd = &cobra.Command{
+	Use:   "load",
+	Short: "Load images from an archive",
+	Long: `
+Load all images from an OCI or Docker image archive, such as an archive written
+by 'cne save' or 'docker save'. The archive is read from the standard input if
+no input file is specified.`,
+	Args: cobra.NoArgs,
+	RunE: loadRunE,
+}
+
+var loadInput string
+
+// loadImages imports the images from the archive file or, if the file name is empty, from
+// stdin.
+func loadImages(run runtime.Runtime, fileName string) ([]runtime.Image, error) {
+
+	var r io.Reader = os.Stdin
+	if fileName == "" {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, errdefs.InvalidArgument("no input file specified")
+		}
+	} else {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, errdefs.SystemError(err, "failed to open file '%s'", fileName)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	return run.ImportImage(r)
+}
+
+func loadRunE(cmd *cobra.Command, args []string) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	imgs, err := loadImages(run, loadInput)
+	if err != nil {
+		return err
+	}
+
+	for _, img := range imgs {
+		fmt.Printf("Loaded image %s\n", img.Name())
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(loadCmd)
+	loadCmd.Flags().StringVarP(
+		&loadInput, "input", "i", "", "Name of the archive file")
+}
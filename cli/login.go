@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/containerd/console"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login REGISTRY",
+	Short: "Log in to a registry",
+	Long: `
+Log in to a registry and store the credentials in the user configuration.
+REGISTRY can be one of the configured registries or the domain of a registry.
+The credentials are used for pulling and pushing images.`,
+	Args: cobra.ExactArgs(1),
+	RunE: loginRunE,
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout REGISTRY",
+	Short: "Log out from a registry",
+	Long: `
+Remove the credentials for the registry from the user configuration.`,
+	Args: cobra.ExactArgs(1),
+	RunE: logoutRunE,
+}
+
+var loginUsername string
+
+// findRegistry returns the name and configuration of the registry that matches the provided
+// name or domain. It returns an empty name if no registry matches.
+func findRegistry(registries map[string]*config.Registry, name string) (string, *config.Registry) {
+
+	if reg, ok := registries[name]; ok {
+		return name, reg
+	}
+	for n, reg := range registries {
+		if reg.Domain == name {
+			return n, reg
+		}
+	}
+	return "", nil
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// parseChallenge returns the scheme and parameters of the WWW-Authenticate header.
+func parseChallenge(header string) (string, map[string]string) {
+
+	params := map[string]string{}
+	scheme := strings.SplitN(header, " ", 2)
+	if len(scheme) == 2 {
+		for _, m := range challengeParamRegexp.FindAllStringSubmatch(scheme[1], -1) {
+			params[strings.ToLower(m[1])] = m[2]
+		}
+	}
+	return strings.ToLower(scheme[0]), params
+}
+
+// verifyLogin verifies the credentials for the registry at the base URL. For registries
+// with token authentication, the credentials are verified against the token endpoint.
+func verifyLogin(client *http.Client, baseURL, username, password string) error {
+
+	resp, err := client.Get(baseURL + "/v2/")
+	if err != nil {
+		return errdefs.SystemError(err, "failed to connect to registry '%s'", baseURL)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return errdefs.InvalidArgument("unexpected response from registry: %s", resp.Status)
+	}
+
+	var authURL string
+	scheme, params := parseChallenge(resp.Header.Get("Www-Authenticate"))
+	switch scheme {
+	case "basic":
+		authURL = baseURL + "/v2/"
+	case "bearer":
+		u, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return errdefs.InvalidArgument("invalid token endpoint '%s'", params["realm"])
+		}
+		query := u.Query()
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		query.Set("account", username)
+		u.RawQuery = query.Encode()
+		authURL = u.String()
+	default:
+		return errdefs.InvalidArgument("unsupported authentication scheme '%s'", scheme)
+	}
+
+	req, err := http.NewRequest("GET", authURL, nil)
+	if err != nil {
+		return errdefs.InvalidArgument("invalid authentication URL '%s'", authURL)
+	}
+	req.SetBasicAuth(username, password)
+	resp, err = client.Do(req)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to connect to '%s'", authURL)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return errdefs.InvalidArgument("login failed: invalid username or password")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errdefs.InvalidArgument("unexpected response from registry: %s", resp.Status)
+	}
+	return nil
+}
+
+// readCredentials prompts for the username, unless provided, and the password. The password
+// isn't echoed if stdin is a terminal.
+func readCredentials(username string) (string, string, error) {
+
+	reader := bufio.NewReader(os.Stdin)
+	if username == "" {
+		fmt.Print("Username: ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", "", errdefs.InvalidArgument("failed to read username")
+		}
+		username = strings.TrimSpace(line)
+	}
+
+	fmt.Print("Password: ")
+	isTerminal := term.IsTerminal(int(os.Stdin.Fd()))
+	if isTerminal {
+		con, err := console.ConsoleFromFile(os.Stdin)
+		if err == nil && con.DisableEcho() == nil {
+			defer con.Reset()
+		}
+	}
+	line, err := reader.ReadString('\n')
+	if isTerminal {
+		fmt.Println()
+	}
+	if err != nil && line == "" {
+		return "", "", errdefs.InvalidArgument("failed to read password")
+	}
+	password := strings.TrimRight(line, "\r\n")
+
+	if username == "" || password == "" {
+		return "", "", errdefs.InvalidArgument("username and password are required")
+	}
+	return username, password, nil
+}
+
+func loginRunE(cmd *cobra.Command, args []string) error {
+
+	name, reg := findRegistry(conf.Registry, args[0])
+	if reg == nil {
+		name, reg = args[0], &config.Registry{Domain: args[0]}
+	}
+
+	username, password, err := readCredentials(loginUsername)
+	if err != nil {
+		return err
+	}
+
+	err = verifyLogin(http.DefaultClient,
+		"https://"+config.RegistryHost(reg.Domain), username, password)
+	if err != nil {
+		return err
+	}
+
+	userConf, err := config.LoadUserConfig()
+	if err != nil {
+		return err
+	}
+	if userConf.Registry == nil {
+		userConf.Registry = map[string]*config.Registry{}
+	}
+	userReg, ok := userConf.Registry[name]
+	if !ok {
+		userReg = &config.Registry{Domain: reg.Domain, RepoName: reg.RepoName}
+		userConf.Registry[name] = userReg
+	}
+	userReg.Auth = &config.Auth{Username: username, Password: password}
+
+	err = userConf.WriteUserConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged in to %s\n", name)
+	return nil
+}
+
+func logoutRunE(cmd *cobra.Command, args []string) error {
+
+	userConf, err := config.LoadUserConfig()
+	if err != nil {
+		return err
+	}
+
+	name, reg := findRegistry(userConf.Registry, args[0])
+	if reg == nil || reg.Auth == nil {
+		return errdefs.NotFound("login", args[0])
+	}
+	reg.Auth = nil
+
+	err = userConf.WriteUserConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged out from %s\n", name)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().StringVarP(&loginUsername, "username", "u", "", "Username for the registry")
+	rootCmd.AddCommand(logoutCmd)
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+func TestParseChallenge(t *testing.T) {
+
+	tests := []struct {
+		header string
+		scheme string
+		params map[string]string
+	}{
+		{"", "", map[string]string{}},
+		{"Basic", "basic", map[string]string{}},
+		{`Basic realm="Registry"`, "basic", map[string]string{"realm": "Registry"}},
+		{`Bearer realm="https://auth.example.com/token",service="registry.example.com"`,
+			"bearer", map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com"}},
+		{`Bearer Realm="https://auth.example.com/token", Scope="repository:a/b:pull"`,
+			"bearer", map[string]string{
+				"realm": "https://auth.example.com/token",
+				"scope": "repository:a/b:pull"}},
+		{`Bearer realm=unquoted`, "bearer", map[string]string{}},
+	}
+	for _, tc := range tests {
+		scheme, params := parseChallenge(tc.header)
+		if scheme != tc.scheme {
+			t.Errorf("Header '%s': expected scheme '%s', got '%s'", tc.header, tc.scheme, scheme)
+		}
+		if len(params) != len(tc.params) {
+			t.Errorf("Header '%s': expected parameters %v, got %v", tc.header, tc.params, params)
+			continue
+		}
+		for k, v := range tc.params {
+			if params[k] != v {
+				t.Errorf("Header '%s': expected '%s' for '%s', got '%s'",
+					tc.header, v, k, params[k])
+			}
+		}
+	}
+}
+
+func TestVerifyLogin(t *testing.T) {
+
+	const username = "user"
+	const password = "secret"
+
+	// the registry uses the challenge of the path, /basic or /bearer, and the token endpoint
+	// verifies the credentials and the account
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		user, pass, ok := r.BasicAuth()
+		valid := ok && user == username && pass == password
+		switch r.URL.Path {
+		case "/basic/v2/":
+			if valid {
+				return
+			}
+			w.Header().Set("Www-Authenticate", `Basic realm="test"`)
+		case "/bearer/v2/":
+			w.Header().Set("Www-Authenticate",
+				`Bearer realm="`+srv.URL+`/token",service="test"`)
+		case "/token":
+			query := r.URL.Query()
+			if valid && query.Get("service") == "test" && query.Get("account") == username {
+				return
+			}
+		case "/open/v2/":
+			return
+		case "/digest/v2/":
+			w.Header().Set("Www-Authenticate", `Digest realm="test"`)
+		case "/broken/v2/":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		password string
+		err      error
+	}{
+		{"/open", password, nil},
+		{"/basic", password, nil},
+		{"/basic", "wrong", errdefs.ErrInvalidArgument},
+		{"/bearer", password, nil},
+		{"/bearer", "wrong", errdefs.ErrInvalidArgument},
+		{"/digest", password, errdefs.ErrInvalidArgument},
+		{"/broken", password, errdefs.ErrInvalidArgument},
+	}
+	for _, tc := range tests {
+		err := verifyLogin(srv.Client(), srv.URL+tc.path, username, tc.password)
+		if tc.err == nil && err != nil {
+			t.Errorf("Path %s: unexpected error: %v", tc.path, err)
+		} else if tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("Path %s: expected error '%v', got '%v'", tc.path, tc.err, err)
+		}
+	}
+
+	srv.Close()
+	err := verifyLogin(srv.Client(), srv.URL, username, password)
+	if !errors.Is(err, errdefs.ErrSystemError) {
+		t.Errorf("Login to a closed registry should fail: %v", err)
+	}
+}
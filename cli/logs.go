@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+// logBufferSize is the maximum size of the output retained from the last session.
+const logBufferSize = 64 * 1024
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the output of the container",
+	Long: `
+Show the output of the main process of the container for the current workspace.
+The follow option keeps streaming the output until the process exits. The buffer
+option shows the most recent output (up to 64KB) of the last session instead.`,
+	Args: cobra.NoArgs,
+	RunE: logsRunE,
+}
+
+var logsBuffer bool
+var logsFollow bool
+
+// logBufferPath returns the path of the file that keeps the buffered output of the last
+// session for the workspace. The log buffers are kept in the runtime directory of the user,
+// if set, or in the cache directory in the home directory of the user. The file is identified
+// by the workspace ID, so it is kept when the workspace is renamed.
+func logBufferPath(ws *project.Workspace) string {
+
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(user.HomeDir, ".cache")
+	}
+	return filepath.Join(dir, "cne", fmt.Sprintf("%s-%x.log", ws.ProjectUUID, ws.ID()))
+}
+
+// logStream returns the stream with the output also written to a new log buffer, which keeps
+// the recent output of the session.
+func logStream(stream runtime.Stream) (runtime.Stream, *runtime.LogBuffer) {
+
+	logBuf := runtime.NewLogBuffer(logBufferSize)
+	stream.Stdout = io.MultiWriter(stream.Stdout, logBuf)
+	stream.Stderr = io.MultiWriter(stream.Stderr, logBuf)
+	return stream, logBuf
+}
+
+// saveLogBuffer saves the content of the log buffer for the workspace.
+func saveLogBuffer(ws *project.Workspace, logBuf *runtime.LogBuffer) error {
+
+	path := logBufferPath(ws)
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to create log directory")
+	}
+
+	// don't write the log to a directory or through a link that another user controls
+	fileInfo, err := os.Lstat(dir)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to get status of log directory")
+	}
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !fileInfo.IsDir() || !ok || stat.Uid != uint32(os.Geteuid()) ||
+		fileInfo.Mode().Perm()&0077 != 0 {
+		return errdefs.PermissionDenied("log directory", dir)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to save log buffer")
+	}
+	defer file.Close()
+
+	_, err = file.Write(logBuf.Bytes())
+	if err != nil {
+		return errdefs.SystemError(err, "failed to save log buffer")
+	}
+	return nil
+}
+
+// saveLogBufferWarning saves the content of the log buffer for the workspace and prints a
+// warning if it cannot be saved, which doesn't affect the result of the session.
+func saveLogBufferWarning(ws *project.Workspace, logBuf *runtime.LogBuffer) {
+	if err := saveLogBuffer(ws, logBuf); err != nil {
+		printWarnings([]string{err.Error()})
+	}
+}
+
+// showLogs shows the output of the main process of the workspace container.
+func showLogs(ws *project.Workspace, follow bool) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	notRunning := errdefs.New(errdefs.ErrNotFound, "container",
+		fmt.Sprintf("no running container for workspace '%s', "+
+			"use 'cne build' and 'cne start' to run it", ws.Name))
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return notRunning
+	} else if err != nil {
+		return err
+	}
+
+	stream := runtime.Stream{Stdout: os.Stdout, Stderr: os.Stderr}
+	err = ctr.Logs(stream, follow)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return notRunning
+	}
+	return err
+}
+
+// showLogBuffer shows the buffered output of the last session of the workspace.
+func showLogBuffer(ws *project.Workspace) error {
+
+	out, err := ioutil.ReadFile(logBufferPath(ws))
+	if os.IsNotExist(err) {
+		return errdefs.NotFound("log buffer", ws.Name)
+	} else if err != nil {
+		return errdefs.SystemError(err, "failed to read log buffer")
+	}
+
+	os.Stdout.Write(out)
+	return nil
+}
+
+func logsRunE(cmd *cobra.Command, args []string) error {
+
+	if logsBuffer && logsFollow {
+		return errdefs.InvalidArgument("buffer and follow options are exclusive")
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+	ws, err := getWorkspace(prj, "")
+	if err != nil {
+		return err
+	}
+
+	if logsBuffer {
+		return showLogBuffer(ws)
+	}
+	return showLogs(ws, logsFollow)
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().BoolVar(&logsBuffer, "buffer", false,
+		"Show the buffered output of the last session")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false,
+		"Follow the output until the process exits")
+}
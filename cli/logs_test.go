@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+func TestLogsExecBuffer(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	prj, run := setupBuildProject(t, dir)
+	ws := &prj.Workspaces[0]
+
+	// the output of the process is written to the stream of the process
+	run.SetExecFunc(func(stream runtime.Stream, args []string) uint32 {
+		fmt.Fprintln(stream.Stdout, "output")
+		fmt.Fprintln(stream.Stderr, "error")
+		return 0
+	})
+	if _, err := execCommands("", "", []string{"true"}); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	out, err := ioutil.ReadFile(logBufferPath(ws))
+	if err != nil {
+		t.Fatalf("Failed to read log buffer: %v", err)
+	}
+	if string(out) != "output\nerror\n" {
+		t.Errorf("Unexpected log buffer: %q", out)
+	}
+}
+
+func TestLogsSaveBuffer(t *testing.T) {
+
+	dir, cleanup := setupCliTest(t)
+	defer cleanup()
+
+	prj := project.NewProject("project", dir)
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	logBuf := runtime.NewLogBuffer(logBufferSize)
+	logBuf.Write([]byte("output\n"))
+	if err = saveLogBuffer(ws, logBuf); err != nil {
+		t.Fatalf("Failed to save log buffer: %v", err)
+	}
+
+	// the log buffer is kept when the workspace is renamed
+	ws.Rename("renamed")
+	out, err := ioutil.ReadFile(logBufferPath(ws))
+	if err != nil || string(out) != "output\n" {
+		t.Errorf("Log buffer of the renamed workspace not found: %q %v", out, err)
+	}
+
+	// the log directory must only be accessible by the user
+	if err = os.Chmod(filepath.Dir(logBufferPath(ws)), 0777); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	if err = saveLogBuffer(ws, logBuf); !errors.Is(err, errdefs.ErrPermissionDenied) {
+		t.Errorf("Log directory accessible by other users should be rejected: %v", err)
+	}
+
+	// the log file must not be a link
+	if err = os.Chmod(filepath.Dir(logBufferPath(ws)), 0700); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	path := logBufferPath(ws)
+	os.Remove(path)
+	if err = os.Symlink(filepath.Join(dir, "target"), path); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+	if err = saveLogBuffer(ws, logBuf); err == nil {
+		t.Errorf("Log file that is a link should be rejected")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "target")); !os.IsNotExist(err) {
+		t.Errorf("Log buffer should not be written through a link: %v", err)
+	}
+}
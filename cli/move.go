@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+var moveCmd = &cobra.Command{
+	Use:   "move",
+	Short: "Move resources",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var moveLayerCmd = &cobra.Command{
+	Use:   "layer NAME",
+	Short: "Move a layer before or after another layer",
+	Long: `
+Move the layer before or after another layer. The layers starting with the first
+changed position are rebuilt the next time the workspace is built.`,
+	Aliases: []string{"l"},
+	Args:    cobra.ExactArgs(1),
+	RunE:    moveLayerRunE,
+}
+
+var moveLayerWorkspace string
+var moveLayerBefore string
+var moveLayerAfter string
+
+func moveLayerRunE(cmd *cobra.Command, args []string) error {
+
+	if (moveLayerBefore == "") == (moveLayerAfter == "") {
+		return errdefs.InvalidArgument("exactly one of the before and after options is required")
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, moveLayerWorkspace)
+	if err != nil {
+		return err
+	}
+
+	if moveLayerBefore != "" {
+		err = ws.MoveLayer(args[0], moveLayerBefore, false)
+	} else {
+		err = ws.MoveLayer(args[0], moveLayerAfter, true)
+	}
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+	moveCmd.AddCommand(moveLayerCmd)
+	moveLayerCmd.Flags().StringVarP(
+		&moveLayerWorkspace, "workspace", "w", "", "Name of the workspace")
+	moveLayerCmd.Flags().StringVar(
+		&moveLayerBefore, "before", "", "Move the layer before this layer")
+	moveLayerCmd.Flags().StringVar(
+		&moveLayerAfter, "after", "", "Move the layer after this layer")
+}
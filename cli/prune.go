@@ -0,0 +1,657 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove unused resources",
+	Long: `
+Remove resources that are no longer referenced. The until option additionally
+limits the resources to those created before the provided time or duration,
+such as 720h or 30d. The filter option limits the resources by their labels
+with label=KEY[=VALUE] or label!=KEY[=VALUE] and can be repeated.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+var pruneUntil string
+var pruneFilters []string
+
+// labelFilter matches labels with the key and, if provided, the value. A negated filter
+// matches labels without the key or value.
+type labelFilter struct {
+	key      string
+	value    string
+	hasValue bool
+	negate   bool
+}
+
+// pruneFilter selects resources by their creation time and labels.
+type pruneFilter struct {
+	until  time.Time
+	labels []labelFilter
+}
+
+// newPruneFilter returns a filter for the provided until time or duration and label filters.
+func newPruneFilter(until string, filters []string) (*pruneFilter, error) {
+
+	filter := &pruneFilter{}
+
+	if until != "" {
+		t, err := agoStringToTime(until)
+		if err != nil {
+			return nil, err
+		}
+		filter.until = t
+	}
+
+	for _, f := range filters {
+		var lf labelFilter
+		var expr string
+		if strings.HasPrefix(f, "label!=") {
+			lf.negate = true
+			expr = f[len("label!="):]
+		} else if strings.HasPrefix(f, "label=") {
+			expr = f[len("label="):]
+		} else {
+			return nil, errdefs.InvalidArgument("invalid filter: '%s'", f)
+		}
+
+		kv := strings.SplitN(expr, "=", 2)
+		if kv[0] == "" {
+			return nil, errdefs.InvalidArgument("invalid filter: '%s'", f)
+		}
+		lf.key = kv[0]
+		if len(kv) == 2 {
+			lf.value = kv[1]
+			lf.hasValue = true
+		}
+		filter.labels = append(filter.labels, lf)
+	}
+
+	return filter, nil
+}
+
+// match returns true if a resource with the provided creation time and labels is selected
+// by the filter.
+func (filter *pruneFilter) match(createdAt time.Time, labels map[string]string) bool {
+
+	if !filter.until.IsZero() && !createdAt.Before(filter.until) {
+		return false
+	}
+
+	for _, lf := range filter.labels {
+		val, ok := labels[lf.key]
+		found := ok && (!lf.hasValue || val == lf.value)
+		if found == lf.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// prunedEntry describes a removed resource.
+type prunedEntry struct {
+	Name      string
+	CreatedAt string
+}
+
+// imageRefs returns the names of all images that are used by containers or, if a project is
+// provided, by the workspaces of the project.
+func imageRefs(run runtime.Runtime, prj *project.Project) (map[string]bool, error) {
+
+	refs := map[string]bool{}
+
+	runCtrs, _, err := run.Containers()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range runCtrs {
+		if img := c.Image(); img != nil {
+			refs[img.Name()] = true
+		}
+	}
+
+	if prj != nil {
+		for _, ws := range prj.Workspaces {
+			if ws.Environment.Origin != "" {
+				refs[conf.FullImageName(ws.Environment.Origin)] = true
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// pruneImages deletes all images selected by the filter that are not referenced, or all
+// selected images if all is set, and returns the deleted images. Images with the same digest
+// as a referenced image, such as the tag of an image that is referenced by its digest, are
+// also considered referenced. If dryRun is set, the images are only returned.
+func pruneImages(run runtime.Runtime, refs map[string]bool,
+	filter *pruneFilter, all, dryRun bool) ([]runtime.Image, error) {
+
+	imgs, err := run.Images()
+	if err != nil {
+		return nil, err
+	}
+
+	digests := map[digest.Digest]bool{}
+	for _, img := range imgs {
+		if refs[img.Name()] && img.Digest() != "" {
+			digests[img.Digest()] = true
+		}
+	}
+
+	var pruned []runtime.Image
+	for _, img := range imgs {
+		if !all && (refs[img.Name()] || digests[img.Digest()]) {
+			continue
+		}
+		if !filter.match(img.CreatedAt(), img.Labels()) {
+			continue
+		}
+		if !dryRun {
+			err = run.DeleteImage(img.Name())
+			if err != nil {
+				return pruned, err
+			}
+		}
+		pruned = append(pruned, img)
+	}
+
+	return pruned, nil
+}
+
+// pruneContainers deletes all containers of the project that are selected by the filter and
+// don't belong to the current configuration of a workspace, or all selected containers if all
+// is set. It returns the deleted containers.
+// Note that containers don't have labels and are excluded by any label filter.
+func pruneContainers(run runtime.Runtime, prj *project.Project,
+	filter *pruneFilter, all bool) ([]container.Container, error) {
+
+	ctrs, warnings, err := container.Containers(run, prj, &user)
+	if err != nil {
+		return nil, err
+	}
+	printWarnings(warnings)
+
+	var pruned []container.Container
+	for _, c := range ctrs {
+		if !all && containerInUse(prj, &c) {
+			continue
+		}
+		if !filter.match(c.CreatedAt, nil) {
+			continue
+		}
+		err = c.Purge()
+		if err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, c)
+	}
+
+	return pruned, nil
+}
+
+// containerInUse returns true if the container belongs to the current configuration of a
+// workspace in the project.
+func containerInUse(prj *project.Project, ctr *container.Container) bool {
+
+	for _, ws := range prj.Workspaces {
+		if ws.ID() == ctr.ID && ws.ConfigHash() == ctr.Generation {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneSnapshots deletes all committed snapshots selected by the filter that are neither part
+// of an image nor the parent of another snapshot and returns the deleted snapshots. Parent
+// snapshots are deleted as well when they are no longer referenced.
+// If dangling is set, it also deletes active and view snapshots that aren't owned by any
+// container, such as snapshots left in the extracting stage by an interrupted image pull.
+// Snapshots of commits and named snapshots of the provided workspaces are always kept.
+// If dryRun is set, it only returns the snapshots that would be deleted.
+func pruneSnapshots(run runtime.Runtime, workspaces []*project.Workspace, filter *pruneFilter,
+	dangling, dryRun bool) ([]runtime.Snapshot, error) {
+
+	snaps, err := run.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := map[string]bool{}
+	workspaceSnapshots(inUse, workspaces)
+
+	// exclude snapshots created extracting images
+	imgs, err := run.Images()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range imgs {
+		rootfs, err := i.RootFS()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rootfs {
+			inUse[r.String()] = true
+		}
+	}
+
+	// exclude the active snapshots of containers, which use the container ID as the name
+	if dangling {
+		ids, err := run.ContainerIDs()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			inUse[id] = true
+		}
+	}
+
+	children := map[string]int{}
+	for _, s := range snaps {
+		children[s.Parent()]++
+	}
+
+	var pruned []runtime.Snapshot
+	deleted := map[string]bool{}
+	for done := false; !done; {
+		done = true
+		for _, s := range snaps {
+			name := s.Name()
+			if deleted[name] || inUse[name] || children[name] > 0 ||
+				(s.Kind() != "committed" && !dangling) ||
+				!filter.match(s.CreatedAt(), s.Labels()) {
+				continue
+			}
+			if !dryRun {
+				err = run.DeleteSnapshot(name)
+				if err != nil {
+					return pruned, err
+				}
+			}
+			deleted[name] = true
+			children[s.Parent()]--
+			pruned = append(pruned, s)
+			done = false
+		}
+	}
+
+	return pruned, nil
+}
+
+// workspaceSnapshots adds the snapshots of the commits and the named snapshots of the
+// workspaces to the provided map.
+func workspaceSnapshots(snaps map[string]bool, workspaces []*project.Workspace) {
+
+	for _, ws := range workspaces {
+		for _, c := range ws.Environment.Commits {
+			snaps[c.Snapshot] = true
+		}
+		for _, s := range ws.Snapshots {
+			snaps[s.Snapshot] = true
+		}
+	}
+}
+
+// pruneCache deletes the cached snapshots of the layers of the provided workspaces. Snapshots
+// built on the same parent snapshot as a layer snapshot are cached variants of the layer, for
+// example, from builds with different commands. For each layer, it keeps the most recently
+// used variants up to the provided number and deletes the other variants and all snapshots
+// built on them. The filter selects variants by the time of their last use. Variants used by a workspace, an image, or a container are always kept.
+// Only variants built on a layer snapshot of the workspaces are considered, as snapshots built
+// directly on an image can be layers of workspaces of other projects.
+// It returns the deleted snapshots.
+func pruneCache(run runtime.Runtime, workspaces []*project.Workspace,
+	keep int, filter *pruneFilter) ([]runtime.Snapshot, error) {
+
+	snaps, err := run.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	snapMap := map[string]runtime.Snapshot{}
+	children := map[string][]runtime.Snapshot{}
+	for _, s := range snaps {
+		snapMap[s.Name()] = s
+		children[s.Parent()] = append(children[s.Parent()], s)
+	}
+
+	inUse := map[string]bool{}
+	for _, ws := range workspaces {
+		for _, l := range ws.Environment.Layers {
+			if l.Digest != "" {
+				inUse[l.Digest] = true
+			}
+		}
+	}
+	workspaceSnapshots(inUse, workspaces)
+
+	imgs, err := run.Images()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range imgs {
+		rootfs, err := i.RootFS()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rootfs {
+			inUse[r.String()] = true
+		}
+	}
+
+	// a snapshot is used if it, or any snapshot built on it, is used or active
+	var used func(s runtime.Snapshot) bool
+	used = func(s runtime.Snapshot) bool {
+		if inUse[s.Name()] || s.Kind() != "committed" {
+			return true
+		}
+		for _, c := range children[s.Name()] {
+			if used(c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var pruned []runtime.Snapshot
+	var deleteTree func(s runtime.Snapshot) error
+	deleteTree = func(s runtime.Snapshot) error {
+		for _, c := range children[s.Name()] {
+			err := deleteTree(c)
+			if err != nil {
+				return err
+			}
+		}
+		err := run.DeleteSnapshot(s.Name())
+		if err != nil {
+			return err
+		}
+		pruned = append(pruned, s)
+		return nil
+	}
+
+	layers := map[string]bool{}
+	for _, ws := range workspaces {
+		for _, l := range ws.Environment.Layers {
+			if l.Digest != "" {
+				layers[l.Digest] = true
+			}
+		}
+	}
+
+	parents := map[string]bool{}
+	for _, ws := range workspaces {
+		for _, l := range ws.Environment.Layers {
+			s, ok := snapMap[l.Digest]
+			if !ok || !layers[s.Parent()] || parents[s.Parent()] {
+				continue
+			}
+			parents[s.Parent()] = true
+
+			variants := children[s.Parent()]
+			sort.Slice(variants, func(i, j int) bool {
+				return variants[i].LastUsedAt().After(variants[j].LastUsedAt())
+			})
+			for i, v := range variants {
+				if i < keep || used(v) || !filter.match(v.LastUsedAt(), v.Labels()) {
+					continue
+				}
+				err = deleteTree(v)
+				if err != nil {
+					return pruned, err
+				}
+			}
+		}
+	}
+
+	return pruned, nil
+}
+
+var pruneImagesCmd = &cobra.Command{
+	Use:     "images",
+	Aliases: []string{"image", "i"},
+	Short:   "Remove images that are not used by any container or workspace",
+	Args:    cobra.NoArgs,
+	RunE:    pruneImagesRunE,
+}
+
+var pruneImagesAll bool
+var pruneImagesDryRun bool
+
+// prunedImage describes a removed image.
+type prunedImage struct {
+	Name      string
+	CreatedAt string
+	Size      string
+}
+
+func pruneImagesRunE(cmd *cobra.Command, args []string) error {
+
+	filter, err := newPruneFilter(pruneUntil, pruneFilters)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	prj, err := loadProject()
+	if err != nil {
+		prj = nil // only protect images of containers outside of a project
+	}
+
+	refs, err := imageRefs(run, prj)
+	if err != nil {
+		return err
+	}
+
+	imgs, err := pruneImages(run, refs, filter, pruneImagesAll, pruneImagesDryRun)
+
+	var total int64
+	list := make([]prunedImage, len(imgs))
+	for i, img := range imgs {
+		list[i].Name = img.Name()
+		list[i].CreatedAt = timeToAgoString(img.CreatedAt())
+		list[i].Size = sizeToSIString(img.Size())
+		total += img.Size()
+	}
+	printList(list, false)
+
+	if output == outputText {
+		if pruneImagesDryRun {
+			fmt.Printf("Would reclaim %s\n", sizeToSIString(total))
+		} else {
+			fmt.Printf("Reclaimed %s\n", sizeToSIString(total))
+		}
+	}
+
+	return err
+}
+
+var pruneContainersCmd = &cobra.Command{
+	Use:     "containers",
+	Aliases: []string{"container", "c"},
+	Short:   "Remove containers that don't belong to the current configuration of a workspace",
+	Args:    cobra.NoArgs,
+	RunE:    pruneContainersRunE,
+}
+
+var pruneContainersAll bool
+
+func pruneContainersRunE(cmd *cobra.Command, args []string) error {
+
+	filter, err := newPruneFilter(pruneUntil, pruneFilters)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ctrs, err := pruneContainers(run, prj, filter, pruneContainersAll)
+
+	list := make([]prunedEntry, len(ctrs))
+	for i, c := range ctrs {
+		list[i].Name = c.Name
+		list[i].CreatedAt = timeToAgoString(c.CreatedAt)
+	}
+	printList(list, false)
+
+	return err
+}
+
+var pruneSnapshotsCmd = &cobra.Command{
+	Use:     "snapshots",
+	Aliases: []string{"snapshot", "s"},
+	Short:   "Remove snapshots that are not used by any image, container or other snapshot",
+	Long: `
+Remove committed snapshots that are not used by any image, container, or other
+snapshot. Snapshots of commits and named snapshots of the workspaces in the
+project are kept. The dangling option also removes active snapshots that aren't owned by
+any container, such as snapshots that were left in the extracting stage by an
+interrupted image pull. Note that this includes snapshots of image pulls that are
+still in progress, which can be avoided with the until option.`,
+	Args: cobra.NoArgs,
+	RunE: pruneSnapshotsRunE,
+}
+
+var pruneSnapshotsDangling bool
+var pruneSnapshotsDryRun bool
+
+func pruneSnapshotsRunE(cmd *cobra.Command, args []string) error {
+
+	filter, err := newPruneFilter(pruneUntil, pruneFilters)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	// protect the committed and named snapshots of the workspaces in the project, if any
+	var workspaces []*project.Workspace
+	prj, err := loadProject()
+	if err == nil {
+		for i := range prj.Workspaces {
+			workspaces = append(workspaces, &prj.Workspaces[i])
+		}
+	}
+
+	snaps, err := pruneSnapshots(run, workspaces, filter,
+		pruneSnapshotsDangling, pruneSnapshotsDryRun)
+
+	list := make([]prunedEntry, len(snaps))
+	for i, s := range snaps {
+		list[i].Name = s.Name()
+		list[i].CreatedAt = timeToAgoString(s.CreatedAt())
+	}
+	printList(list, false)
+
+	return err
+}
+
+var pruneCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Remove older cached layer snapshots of the workspaces",
+	Long: `
+Remove cached snapshots of the layers of all workspaces in the project. Layer
+snapshots that were built on the same parent snapshot are variants of the layer,
+for example, from builds with different commands. The most recently used variants
+of each layer, up to the number defined by keep-per-layer, are kept, and the until
+option selects variants by the time of their last use. Variants of the
+first layer of a workspace are built on the image, which other projects can share,
+and are always kept.`,
+	Args: cobra.NoArgs,
+	RunE: pruneCacheRunE,
+}
+
+var pruneCacheKeepPerLayer int
+
+func pruneCacheRunE(cmd *cobra.Command, args []string) error {
+
+	filter, err := newPruneFilter(pruneUntil, pruneFilters)
+	if err != nil {
+		return err
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	workspaces := make([]*project.Workspace, len(prj.Workspaces))
+	for i := range prj.Workspaces {
+		workspaces[i] = &prj.Workspaces[i]
+	}
+
+	snaps, err := pruneCache(run, workspaces, pruneCacheKeepPerLayer, filter)
+
+	list := make([]prunedEntry, len(snaps))
+	for i, s := range snaps {
+		list[i].Name = s.Name()
+		list[i].CreatedAt = timeToAgoString(s.CreatedAt())
+	}
+	printList(list, false)
+
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.PersistentFlags().StringVar(
+		&pruneUntil, "until", "", "Only remove resources created before the time or duration")
+	pruneCmd.PersistentFlags().StringArrayVar(
+		&pruneFilters, "filter", nil, "Only remove resources matching the label filter")
+	pruneCmd.AddCommand(pruneImagesCmd)
+	pruneImagesCmd.Flags().BoolVarP(
+		&pruneImagesAll, "all", "A", false, "Also remove images that are in use")
+	pruneImagesCmd.Flags().BoolVar(
+		&pruneImagesDryRun, "dry-run", false, "Only list the images that would be removed")
+	pruneCmd.AddCommand(pruneContainersCmd)
+	pruneContainersCmd.Flags().BoolVarP(
+		&pruneContainersAll, "all", "A", false, "Also remove containers that are in use")
+	pruneCmd.AddCommand(pruneSnapshotsCmd)
+	pruneSnapshotsCmd.Flags().BoolVar(
+		&pruneSnapshotsDangling, "dangling", false,
+		"Also remove active snapshots that aren't owned by any container")
+	pruneSnapshotsCmd.Flags().BoolVar(
+		&pruneSnapshotsDryRun, "dry-run", false, "Only list the snapshots that would be removed")
+	pruneCmd.AddCommand(pruneCacheCmd)
+	pruneCacheCmd.Flags().IntVar(
+		&pruneCacheKeepPerLayer, "keep-per-layer", 1, "Number of most recently used cached snapshots kept per layer")
+}
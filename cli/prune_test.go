@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+func TestPruneFilter(t *testing.T) {
+
+	_, err := newPruneFilter("", []string{"name=test"})
+	if err == nil {
+		t.Errorf("Invalid filter should fail")
+	}
+	_, err = newPruneFilter("-1h", nil)
+	if err == nil {
+		t.Errorf("Negative duration should fail")
+	}
+
+	filter, err := newPruneFilter("30d", []string{"label!=keep", "label=tier=dev"})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	old := time.Now().Add(-31 * 24 * time.Hour)
+	dev := map[string]string{"tier": "dev"}
+	if !filter.match(old, dev) {
+		t.Errorf("Old entry with matching labels should match")
+	}
+	if filter.match(time.Now(), dev) {
+		t.Errorf("New entry should not match")
+	}
+	if filter.match(old, map[string]string{"tier": "dev", "keep": ""}) {
+		t.Errorf("Entry labeled keep should not match")
+	}
+	if filter.match(old, map[string]string{"tier": "prod"}) {
+		t.Errorf("Entry with different label value should not match")
+	}
+}
+
+func TestPruneImagesUntil(t *testing.T) {
+
+	now := time.Now()
+	run := &testRuntime{imgs: []runtime.Image{
+		&testImage{name: "old", createdAt: now.Add(-800 * time.Hour)},
+		&testImage{name: "new", createdAt: now.Add(-1 * time.Hour)},
+		&testImage{name: "used", createdAt: now.Add(-800 * time.Hour)},
+		&testImage{name: "keep", createdAt: now.Add(-800 * time.Hour),
+			labels: map[string]string{"keep": "true"}},
+	}}
+	refs := map[string]bool{"used": true}
+
+	filter, err := newPruneFilter("720h", []string{"label!=keep"})
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	_, err = pruneImages(run, refs, filter, false, false)
+	if err != nil {
+		t.Fatalf("Failed to prune images: %v", err)
+	}
+	if len(run.deleted) != 1 || run.deleted[0] != "old" {
+		t.Errorf("Expected only the old image to be removed, removed: %v", run.deleted)
+	}
+
+	run.deleted = nil
+	_, err = pruneImages(run, refs, filter, true, false)
+	if err != nil {
+		t.Fatalf("Failed to prune images: %v", err)
+	}
+	if len(run.deleted) != 2 || run.deleted[1] != "used" {
+		t.Errorf("Expected the old and used image to be removed, removed: %v", run.deleted)
+	}
+}
+
+func TestPruneImagesDigest(t *testing.T) {
+
+	run := &testRuntime{imgs: []runtime.Image{
+		&testImage{name: "docker.io/library/busybox@sha256:0123", digest: "sha256:abcd"},
+		&testImage{name: "docker.io/library/busybox:latest", digest: "sha256:abcd"},
+		&testImage{name: "docker.io/library/alpine:latest", digest: "sha256:ef01"},
+	}}
+	refs := map[string]bool{"docker.io/library/busybox@sha256:0123": true}
+
+	filter, _ := newPruneFilter("", nil)
+	imgs, err := pruneImages(run, refs, filter, false, true)
+	if err != nil {
+		t.Fatalf("Failed to prune images: %v", err)
+	}
+	if len(imgs) != 1 || imgs[0].Name() != "docker.io/library/alpine:latest" {
+		t.Errorf("Expected only the alpine image to be pruned, got %v", imgs)
+	}
+	if len(run.deleted) != 0 {
+		t.Errorf("Dry run removed images: %v", run.deleted)
+	}
+}
+
+func TestPruneSnapshotsUntil(t *testing.T) {
+
+	now := time.Now()
+	old := now.Add(-800 * time.Hour)
+	run := &testRuntime{
+		imgs: []runtime.Image{&testImage{name: "img", rootfs: []digest.Digest{"base"}}},
+		snaps: []runtime.Snapshot{
+			&testSnapshot{name: "base", kind: "committed", createdAt: old},
+			&testSnapshot{name: "layer", parent: "base", kind: "committed", createdAt: old},
+			&testSnapshot{name: "top", parent: "layer", kind: "committed", createdAt: old},
+			&testSnapshot{name: "new", parent: "base", kind: "committed", createdAt: now},
+			&testSnapshot{name: "active", parent: "base", kind: "active", createdAt: old},
+		},
+	}
+
+	filter, err := newPruneFilter("720h", nil)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	_, err = pruneSnapshots(run, nil, filter, false, false)
+	if err != nil {
+		t.Fatalf("Failed to prune snapshots: %v", err)
+	}
+	if len(run.deleted) != 2 || run.deleted[0] != "top" || run.deleted[1] != "layer" {
+		t.Errorf("Expected top and layer snapshots to be removed, removed: %v", run.deleted)
+	}
+}
+
+func TestPruneSnapshotsDangling(t *testing.T) {
+
+	now := time.Now()
+	run := &testRuntime{
+		snaps: []runtime.Snapshot{
+			&testSnapshot{name: "base", kind: "committed", createdAt: now},
+			&testSnapshot{name: "layer", parent: "base", kind: "committed", createdAt: now},
+			&testSnapshot{name: "ctr", parent: "layer", kind: "active", createdAt: now},
+			&testSnapshot{name: "extract-1 sha256:abc", parent: "base", kind: "active",
+				createdAt: now},
+		},
+		ctrIDs: []string{"ctr"},
+	}
+
+	filter, err := newPruneFilter("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	snaps, err := pruneSnapshots(run, nil, filter, true, true)
+	if err != nil {
+		t.Fatalf("Failed to prune snapshots: %v", err)
+	}
+	if len(run.deleted) != 0 {
+		t.Errorf("Dry run removed snapshots: %v", run.deleted)
+	}
+	if len(snaps) != 1 || snaps[0].Name() != "extract-1 sha256:abc" {
+		t.Errorf("Expected only the extract snapshot to be listed, listed: %v", snaps)
+	}
+
+	_, err = pruneSnapshots(run, nil, filter, true, false)
+	if err != nil {
+		t.Fatalf("Failed to prune snapshots: %v", err)
+	}
+	if len(run.deleted) != 1 || run.deleted[0] != "extract-1 sha256:abc" {
+		t.Errorf("Expected only the extract snapshot to be removed, removed: %v", run.deleted)
+	}
+}
+
+func TestPruneSnapshotsWorkspace(t *testing.T) {
+
+	old := time.Now().Add(-800 * time.Hour)
+	run := &testRuntime{
+		snaps: []runtime.Snapshot{
+			&testSnapshot{name: "base", kind: "committed", createdAt: old},
+			&testSnapshot{name: "named", parent: "base", kind: "committed", createdAt: old},
+			&testSnapshot{name: "commit", parent: "base", kind: "committed", createdAt: old},
+			&testSnapshot{name: "unused", parent: "base", kind: "committed", createdAt: old},
+		},
+	}
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+	ws.Snapshots = []project.Snapshot{{Name: "snap", Snapshot: "named"}}
+	ws.Environment.Commits = []project.Commit{{Message: "commit", Snapshot: "commit"}}
+
+	filter, err := newPruneFilter("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	_, err = pruneSnapshots(run, []*project.Workspace{ws}, filter, false, false)
+	if err != nil {
+		t.Fatalf("Failed to prune snapshots: %v", err)
+	}
+	if len(run.deleted) != 1 || run.deleted[0] != "unused" {
+		t.Errorf("Expected only the unused snapshot to be removed, removed: %v", run.deleted)
+	}
+}
+
+func TestPruneCacheKeepPerLayer(t *testing.T) {
+
+	now := time.Now()
+	snap := func(name, parent string, age time.Duration) runtime.Snapshot {
+		return &testSnapshot{name: name, parent: parent, kind: "committed",
+			createdAt: now.Add(-age)}
+	}
+	run := &testRuntime{
+		snaps: []runtime.Snapshot{
+			snap("image", "", 20*time.Hour),
+			snap("base", "image", 10*time.Hour),
+			snap("other1", "image", 11*time.Hour),
+			snap("other2", "image", 12*time.Hour),
+			&testSnapshot{name: "v1", parent: "base", kind: "committed",
+				createdAt: now.Add(-4 * time.Hour), lastUsedAt: now},
+			snap("v1-top", "v1", 4*time.Hour),
+			snap("v2", "base", 3*time.Hour),
+			snap("v3", "base", 2*time.Hour),
+			snap("v3-top", "v3", 2*time.Hour),
+			snap("v4", "base", 1*time.Hour),
+			snap("v4-top", "v4", 1*time.Hour),
+			&testSnapshot{name: "v2-active", parent: "v2", kind: "active"},
+		},
+	}
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+	ws.Environment.Layers = []project.Layer{
+		{Name: "base", Digest: "base"},
+		{Name: "layer", Digest: "v4"},
+		{Name: "top", Digest: "v4-top"},
+	}
+
+	filter, err := newPruneFilter("", nil)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+
+	_, err = pruneCache(run, []*project.Workspace{ws}, 2, filter)
+	if err != nil {
+		t.Fatalf("Failed to prune cache: %v", err)
+	}
+
+	// v1 and v4 were used most recently and v2 is used by a container, and snapshots built
+	// on the image can be layers of other projects
+	expected := []string{"v3-top", "v3"}
+	if len(run.deleted) != len(expected) {
+		t.Fatalf("Expected %v to be removed, removed: %v", expected, run.deleted)
+	}
+	for i, name := range expected {
+		if run.deleted[i] != name {
+			t.Errorf("Expected %v to be removed, removed: %v", expected, run.deleted)
+		}
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/czankel/cne/runtime"
 )
 
-func pullImage(run runtime.Runtime, imageName string) (runtime.Image, error) {
+func pullImage(run runtime.Runtime, imageName, platform string) (runtime.Image, error) {
 
 	var wg sync.WaitGroup
 
@@ -21,7 +21,10 @@ func pullImage(run runtime.Runtime, imageName string) (runtime.Image, error) {
 		showImageProgress(progress)
 	}()
 
-	img, err := run.PullImage(imageName, progress)
+	run.SetCredentials(conf.RegistryCredentials)
+	run.SetInsecureRegistries(conf.InsecureRegistry)
+	run.SetRegistryMirrors(conf.RegistryMirrors)
+	img, err := run.PullImage(imageName, platform, progress)
 	wg.Wait()
 
 	return img, err
@@ -34,23 +37,29 @@ var pullCmd = &cobra.Command{
 Pull an image from a registry to the local system.
 REGISTRY can be one of the configured registries or directly
 specify the domain and repository. If omitted, the default
-registry is used.`,
+registry is used. The platform option selects the image for
+another platform, such as linux/arm64, from a multi-platform
+image.`,
 	Args: cobra.ExactArgs(1),
 	RunE: pullImageRunE,
 }
 
+var pullPlatform string
+
 func pullImageRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
 	defer run.Close()
-	_, err = pullImage(run, conf.FullImageName(args[0]))
+	_, err = pullImage(run, conf.FullImageName(args[0]), pullPlatform)
 
 	return err
 }
 
 func init() {
 	rootCmd.AddCommand(pullCmd)
+	pullCmd.Flags().StringVar(
+		&pullPlatform, "platform", "", "Platform in the form os/arch[/variant]")
 }
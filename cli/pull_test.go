@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+func TestCliPullImage(t *testing.T) {
+
+	defer mock.Reset()
+
+	run, err := runtime.Open(config.Runtime{Name: "mock", Namespace: "test"})
+	if err != nil {
+		t.Fatalf("Failed to open runtime: %v", err)
+	}
+	defer run.Close()
+
+	compareFuncOutput(func() {
+		_, err = pullImage(run, "docker.io/library/busybox:latest", "")
+	}, "")
+	if err != nil {
+		t.Errorf("Failed to pull image: %v", err)
+	}
+	if _, err = run.GetImage("docker.io/library/busybox:latest"); err != nil {
+		t.Errorf("Image not pulled: %v", err)
+	}
+
+	mock.Namespace("test").Fail("PullImage", errdefs.Canceled("pull image", "other"))
+	compareFuncOutput(func() {
+		_, err = pullImage(run, "other", "")
+	}, "")
+	if !errors.Is(err, errdefs.ErrCanceled) {
+		t.Errorf("Expected injected error, got %v", err)
+	}
+}
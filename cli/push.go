@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/runtime"
+)
+
+func pushImage(run runtime.Runtime, imageName, remoteName string) error {
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	progress := make(chan []runtime.ProgressStatus)
+
+	go func() {
+		defer wg.Done()
+		showImageProgress(progress)
+	}()
+
+	run.SetCredentials(conf.RegistryCredentials)
+	run.SetInsecureRegistries(conf.InsecureRegistry)
+	err := run.PushImage(imageName, remoteName, progress)
+	wg.Wait()
+
+	return err
+}
+
+var pushCmd = &cobra.Command{
+	Use:   "push [REGISTRY]PACKAGE[:TAG] [[REGISTRY]PACKAGE[:TAG]]",
+	Short: "Push an image to a registry",
+	Long: `
+Push an image from the local system to a registry. The optional second
+argument specifies the remote reference if it differs from the local name.
+REGISTRY can be one of the configured registries or directly specify the
+domain and repository. If omitted, the default registry is used.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: pushImageRunE,
+}
+
+func pushImageRunE(cmd *cobra.Command, args []string) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	remoteName := ""
+	if len(args) > 1 {
+		remoteName = conf.FullImageName(args[1])
+	}
+
+	return pushImage(run, conf.FullImageName(args[0]), remoteName)
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+}
@@ -28,7 +28,7 @@ var removeAptCmd = &cobra.Command{
 
 func removeAptRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "Rename a resource",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var renameWorkspaceCmd = &cobra.Command{
+	Use:     "workspace OLD NEW",
+	Aliases: []string{"ws"},
+	Short:   "Rename a workspace",
+	Long: `
+Rename a workspace. The container of the workspace is preserved and doesn't
+have to be rebuilt.`,
+	Args: cobra.ExactArgs(2),
+	RunE: renameWorkspaceRunE,
+}
+
+func renameWorkspaceRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	err = prj.RenameWorkspace(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+	renameCmd.AddCommand(renameWorkspaceCmd)
+}
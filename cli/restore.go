@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a resource",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var restoreSnapshotCmd = &cobra.Command{
+	Use:   "snapshot NAME",
+	Short: "Restore a named snapshot of the workspace container",
+	Long: `
+Restore the filesystem of the workspace container and the workspace configuration
+from a snapshot that was created with 'cne create snapshot'. Any changes to the
+container since the snapshot are discarded, and the container is stopped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: restoreSnapshotRunE,
+}
+
+var restoreSnapshotWorkspace string
+
+func restoreSnapshotRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, restoreSnapshotWorkspace)
+	if err != nil {
+		return err
+	}
+
+	if _, err = ws.FindSnapshot(args[0]); err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	ctr, err := container.Find(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.NotFound("container", ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = ctr.RestoreSnapshot(ws, args[0])
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.AddCommand(restoreSnapshotCmd)
+	restoreSnapshotCmd.Flags().StringVarP(
+		&restoreSnapshotWorkspace, "workspace", "w", "", "Name of the workspace")
+}
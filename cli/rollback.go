@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll back the workspace container to the previous generation",
+	Long: `
+Roll back the container of the workspace to the generation that was committed
+before the current generation, for example, to undo a 'cne commit'. Any changes
+to the container since the last commit are discarded, and the container is
+stopped.`,
+	Args: cobra.NoArgs,
+	RunE: rollbackRunE,
+}
+
+var rollbackWorkspace string
+
+func rollbackRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, rollbackWorkspace)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	ctr, err := container.Find(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.NotFound("container", ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = ctr.Rollback(ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.New(errdefs.ErrNotFound, "generation",
+			fmt.Sprintf("no previous generation of workspace '%s' to roll back to", ws.Name))
+	}
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.Flags().StringVarP(
+		&rollbackWorkspace, "workspace", "w", "", "Name of the workspace")
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var saveCmd = &cobra.Command{
+	Use:   "save NAME",
+	Short: "Save an image to an archive",
+	Long: `
+Save an image to an OCI image archive, which can be loaded with 'cne load' or
+'docker load' on another system without using a registry. The archive is
+written to the standard output if no file is specified.`,
+	Args: cobra.ExactArgs(1),
+	RunE: saveRunE,
+}
+
+var saveFile string
+
+// saveImage writes the image archive to the file or, if the file name is empty, to stdout.
+// A partially written file is removed if the export fails.
+func saveImage(run runtime.Runtime, name, fileName string) error {
+
+	if fileName == "" {
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			return errdefs.InvalidArgument("refusing to write the archive to a terminal")
+		}
+		return run.ExportImage(name, os.Stdout)
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to create file '%s'", fileName)
+	}
+
+	err = run.ExportImage(name, file)
+	if cerr := file.Close(); err == nil && cerr != nil {
+		err = errdefs.SystemError(cerr, "failed to write file '%s'", fileName)
+	}
+	if err != nil {
+		os.Remove(fileName)
+	}
+	return err
+}
+
+func saveRunE(cmd *cobra.Command, args []string) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	return saveImage(run, conf.FullImageName(args[0]), saveFile)
+}
+
+func init() {
+	rootCmd.AddCommand(saveCmd)
+	saveCmd.Flags().StringVarP(
+		&saveFile, "file", "f", "", "Name of the archive file")
+}
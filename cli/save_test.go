@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+func TestCliSaveImage(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	dir, err := ioutil.TempDir("", "cne-save-test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "busybox.tar")
+	if err := saveImage(run, "docker.io/library/busybox:latest", fileName); err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Archive not written: %v", err)
+	}
+	defer file.Close()
+
+	names := []string{}
+	tr := tar.NewReader(file)
+	for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 2 || names[1] != "index.json" {
+		t.Errorf("Unexpected archive content %v", names)
+	}
+
+	run.Fail("ExportImage", errdefs.InternalError("injected"))
+	fileName = filepath.Join(dir, "failed.tar")
+	if err := saveImage(run, "docker.io/library/busybox:latest", fileName); err == nil {
+		t.Errorf("Injected error not returned")
+	}
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("Partial archive not removed")
+	}
+}
+
+func TestCliLoadImages(t *testing.T) {
+
+	defer mock.Reset()
+	src := mock.Namespace("source")
+	src.AddImage("docker.io/library/busybox:latest", 1000)
+
+	dir, err := ioutil.TempDir("", "cne-load-test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "busybox.tar")
+	if err = saveImage(src, "docker.io/library/busybox:latest", fileName); err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+
+	dst := mock.Namespace("destination")
+	imgs, err := loadImages(dst, fileName)
+	if err != nil {
+		t.Fatalf("Failed to load images: %v", err)
+	}
+	if len(imgs) != 1 || imgs[0].Name() != "docker.io/library/busybox:latest" {
+		t.Errorf("Unexpected images %v", imgs)
+	}
+	if _, err = dst.GetImage("docker.io/library/busybox:latest"); err != nil {
+		t.Errorf("Image not registered: %v", err)
+	}
+
+	if _, err = loadImages(dst, filepath.Join(dir, "missing.tar")); err == nil {
+		t.Errorf("Missing archive loaded")
+	}
+}
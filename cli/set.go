@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set workspace properties and configurations",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var setPostBuildCmd = &cobra.Command{
+	Use:   "post-build [CMD]",
+	Short: "Set the command that is executed after a successful build",
+	Long: `
+Set the command that is executed inside the container after all layers have been
+built. The build fails if the command returns a non-zero exit code, unless the
+ignore-errors option is set. Omit the command to remove the post-build command.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: setPostBuildRunE,
+}
+
+var setPostBuildWorkspace string
+var setPostBuildIgnoreErrors bool
+
+func setPostBuildRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, setPostBuildWorkspace)
+	if err != nil {
+		return err
+	}
+
+	ws.PostBuild = project.PostBuild{}
+	if len(args) > 0 {
+		ws.PostBuild.Args = args
+		ws.PostBuild.IgnoreErrors = setPostBuildIgnoreErrors
+	}
+
+	return prj.Write()
+}
+
+var setEnvCmd = &cobra.Command{
+	Use:   "env KEY=VALUE...",
+	Short: "Set environment variables of the workspace",
+	Long: `
+Set environment variables for all commands that are executed in the workspace,
+including the commands of the layers. Changing the variables requires rebuilding
+the workspace container.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: setEnvRunE,
+}
+
+var setEnvWorkspace string
+
+func setEnvRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, setEnvWorkspace)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return errdefs.InvalidArgument("invalid environment variable: '%s'", arg)
+		}
+		err = ws.SetEnv(kv[0], kv[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return prj.Write()
+}
+
+var setResourcesCmd = &cobra.Command{
+	Use:   "resources",
+	Short: "Set the resource limits of the workspace container",
+	Long: `
+Set the CPU and memory limits of the workspace container, which apply to all
+commands executed in the container including the commands of the layers. Only
+the provided limits are changed, and a value of 0 removes the limit. The limits
+take effect when the container is started the next time.`,
+	Args: cobra.NoArgs,
+	RunE: setResourcesRunE,
+}
+
+var setResourcesWorkspace string
+var setResourcesCPUs float64
+var setResourcesCPUShares uint64
+var setResourcesMemory string
+
+func setResourcesRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, setResourcesWorkspace)
+	if err != nil {
+		return err
+	}
+
+	res := ws.Resources
+	if cmd.Flags().Changed("cpus") {
+		if setResourcesCPUs < 0 {
+			return errdefs.InvalidArgument("invalid number of CPUs: %v", setResourcesCPUs)
+		}
+		res.CPUs = setResourcesCPUs
+	}
+	if cmd.Flags().Changed("cpu-shares") {
+		res.CPUShares = setResourcesCPUShares
+	}
+	if cmd.Flags().Changed("memory") {
+		res.Memory, err = siStringToSize(setResourcesMemory)
+		if err != nil {
+			return err
+		}
+	}
+	ws.Resources = res
+
+	err = prj.Write()
+	if err != nil {
+		return err
+	}
+
+	return updateSpec(ws)
+}
+
+var setShellCmd = &cobra.Command{
+	Use:   "shell SHELL",
+	Short: "Set the shell of the workspace",
+	Long: `
+Set the shell for executing commands with 'exec --shell' in the workspace
+instead of /bin/sh. The shell must be an absolute path. If the shell doesn't
+exist in the container, /bin/sh is used instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: setShellRunE,
+}
+
+var setShellWorkspace string
+
+func setShellRunE(cmd *cobra.Command, args []string) error {
+
+	if !filepath.IsAbs(args[0]) {
+		return errdefs.InvalidArgument("shell '%s' must be an absolute path", args[0])
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, setShellWorkspace)
+	if err != nil {
+		return err
+	}
+
+	ws.Shell = args[0]
+
+	return prj.Write()
+}
+
+var setConfigCmd = &cobra.Command{
+	Use:   "config NAME VALUE",
+	Short: "Set a configuration value",
+	Long: `
+Set a value of the user or system configuration. The name is the path of the
+configuration field using '/' as the separator, such as 'runtime/name', and is
+case-insensitive. The value must match the type of the field. By default, the
+value is written to the user configuration file. The system option modifies
+the system-wide configuration file stored in /etc, and requires system
+permissions.`,
+	Args:        cobra.ExactArgs(2),
+	RunE:        setConfigRunE,
+	Annotations: map[string]string{skipValidation: ""},
+}
+
+var setConfigSystem bool
+
+func setConfigRunE(cmd *cobra.Command, args []string) error {
+	return setConfig(args[0], args[1], setConfigSystem)
+}
+
+// setConfig sets the configuration field in the user or system configuration file and
+// prints the new and old value.
+func setConfig(name, value string, system bool) error {
+
+	var err error
+
+	if system {
+		conf, err = config.LoadSystemConfig()
+	} else {
+		conf, err = config.LoadUserConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	oldVal, path, err := conf.SetByName(name, value)
+	if err != nil {
+		return err
+	}
+
+	if path == "Runtime/Name" && !isRuntimeName(value) {
+		return errdefs.InvalidArgument("invalid runtime '%s', must be one of: %s",
+			value, strings.Join(runtime.Runtimes(), ", "))
+	}
+
+	if system {
+		err = conf.WriteSystemConfig()
+	} else {
+		err = conf.WriteUserConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	printList([]struct {
+		Configuration string
+		Value         string
+		Old           string
+	}{{path, value, oldVal}}, false)
+	return nil
+}
+
+// isRuntimeName returns true if the name is one of the registered runtimes.
+func isRuntimeName(name string) bool {
+	for _, n := range runtime.Runtimes() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+	setCmd.AddCommand(setPostBuildCmd)
+	setPostBuildCmd.Flags().StringVarP(
+		&setPostBuildWorkspace, "workspace", "w", "", "Name of the workspace")
+	setPostBuildCmd.Flags().BoolVar(
+		&setPostBuildIgnoreErrors, "ignore-errors", false,
+		"Don't fail the build if the command fails")
+	setCmd.AddCommand(setEnvCmd)
+	setEnvCmd.Flags().StringVarP(
+		&setEnvWorkspace, "workspace", "w", "", "Name of the workspace")
+	setCmd.AddCommand(setResourcesCmd)
+	setResourcesCmd.Flags().StringVarP(
+		&setResourcesWorkspace, "workspace", "w", "", "Name of the workspace")
+	setResourcesCmd.Flags().Float64Var(
+		&setResourcesCPUs, "cpus", 0, "Number of CPUs")
+	setResourcesCmd.Flags().Uint64Var(
+		&setResourcesCPUShares, "cpu-shares", 0, "Relative CPU weight")
+	setResourcesCmd.Flags().StringVar(
+		&setResourcesMemory, "memory", "", "Memory limit, e.g. 512M or 2G")
+	setCmd.AddCommand(setShellCmd)
+	setShellCmd.Flags().StringVarP(
+		&setShellWorkspace, "workspace", "w", "", "Name of the workspace")
+	setCmd.AddCommand(setConfigCmd)
+	setConfigCmd.Flags().BoolVar(
+		&setConfigSystem, "system", false, "Set the system configuration")
+}
@@ -1,13 +1,15 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/errdefs"
-	"github.com/czankel/cne/project"
 	"github.com/czankel/cne/runtime"
 	"github.com/czankel/cne/support"
 )
@@ -26,18 +28,45 @@ var showConfigCmd = &cobra.Command{
 for all environments of the current user.
 By default, this command returns the configuration derived from all
 configuration files. The system option returns only the syste-wide
-configuration and the user option the configuration for the user.`,
-	RunE: showConfigRunE,
-	Args: cobra.RangeArgs(0, 1),
+configuration and the user option the configuration for the user.
+Environment variables, such as CNE_RUNTIME_SOCKETNAME for runtime/socketname,
+override the values of the configuration files.
+The schema option prints the JSON Schema of the configuration file for the
+YAML (default) or TOML format, which editors can use for validation and
+completion.`,
+	RunE:        showConfigRunE,
+	Annotations: map[string]string{skipValidation: ""},
+	Args:        cobra.RangeArgs(0, 1),
 }
 
 var showSystemConfig bool
 var showUserConfig bool
+var showConfigSchema string
+
+// showSchema prints the JSON Schema of the configuration file for the format.
+func showSchema(format string) error {
+
+	if format != "yaml" && format != "toml" {
+		return errdefs.InvalidArgument("invalid schema format '%s', use yaml or toml", format)
+	}
+
+	schema := config.ConfigSchema(runtime.Runtimes(), format == "yaml")
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return errdefs.InternalError("failed to create schema: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
 
 func showConfigRunE(cmd *cobra.Command, args []string) error {
 
 	var err error
 
+	if showConfigSchema != "" {
+		return showSchema(showConfigSchema)
+	}
+
 	if showUserConfig == showSystemConfig {
 		conf, err = config.Load()
 	} else if showSystemConfig {
@@ -97,13 +126,11 @@ func showWorkspaceRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	var ws *project.Workspace
-
+	name := ""
 	if len(args) > 0 {
-		ws, err = prj.Workspace(args[0])
-	} else {
-		ws, err = prj.CurrentWorkspace()
+		name = args[0]
 	}
+	ws, err := getWorkspace(prj, name)
 	if err != nil {
 		return err
 	}
@@ -116,8 +143,43 @@ func showWorkspaceRunE(cmd *cobra.Command, args []string) error {
 var showImageCmd = &cobra.Command{
 	Use:   "image [NAME]",
 	Short: "Show image details",
-	RunE:  showImageRunE,
-	Args:  cobra.ExactArgs(1),
+	Long: `
+Show the details of an image. The history option shows the steps that were used
+for building the image, starting with the most recent step. Steps that didn't
+create a layer are shown as <empty>.`,
+	RunE: showImageRunE,
+	Args: cobra.ExactArgs(1),
+}
+
+var showImageHistory bool
+
+// showHistory prints the history of the image, starting with the most recent entry.
+func showHistory(history []runtime.ImageHistory) {
+
+	type historyEntry struct {
+		Created   string
+		Layer     string
+		Size      string
+		CreatedBy string
+	}
+
+	list := make([]historyEntry, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		h := history[i]
+		entry := historyEntry{
+			Layer:     "<empty>",
+			CreatedBy: h.CreatedBy,
+		}
+		if !h.CreatedAt.IsZero() {
+			entry.Created = h.CreatedAt.Format(time.RFC3339)
+		}
+		if !h.EmptyLayer {
+			entry.Layer = shortID(h.Layer.Encoded(), 0)
+			entry.Size = sizeToSIString(h.Size)
+		}
+		list = append(list, entry)
+	}
+	printList(list, false)
 }
 
 type OS struct {
@@ -129,7 +191,7 @@ type OS struct {
 
 func showImageRunE(cmd *cobra.Command, args []string) error {
 
-	run, err := runtime.Open(conf.Runtime)
+	run, err := runtime.OpenLazy(conf.Runtime)
 	if err != nil {
 		return err
 	}
@@ -138,12 +200,21 @@ func showImageRunE(cmd *cobra.Command, args []string) error {
 	imgName := conf.FullImageName(args[0])
 	img, err := run.GetImage(imgName)
 	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
-		img, err = pullImage(run, imgName)
+		img, err = pullImage(run, imgName, "")
 	}
 	if err != nil {
 		return err
 	}
 
+	if showImageHistory {
+		history, err := img.History()
+		if err != nil {
+			return err
+		}
+		showHistory(history)
+		return nil
+	}
+
 	fullName := "<unknown>"
 	info, err := support.GetImageInfo(img)
 	if info != nil {
@@ -183,7 +254,12 @@ func init() {
 		&showSystemConfig, "system", "", false, "Show only system configurations")
 	showConfigCmd.Flags().BoolVarP(
 		&showUserConfig, "user", "", false, "Show only user configurations")
+	showConfigCmd.Flags().StringVar(
+		&showConfigSchema, "schema", "", "Show the JSON Schema for the format (yaml, toml)")
+	showConfigCmd.Flags().Lookup("schema").NoOptDefVal = "yaml"
 	showCmd.AddCommand(showProjectCmd)
 	showCmd.AddCommand(showWorkspaceCmd)
 	showCmd.AddCommand(showImageCmd)
+	showImageCmd.Flags().BoolVar(
+		&showImageHistory, "history", false, "Show the history of the image")
 }
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var startCmd = &cobra.Command{
+	Use:   "start [WORKSPACE]",
+	Short: "Start the main process of the workspace container",
+	Long: `
+Start the entrypoint and command defined by the image of the workspace as the main
+process of the container and return without waiting for the process to exit.
+The container is built if it doesn't exist.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: startRunE,
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop [WORKSPACE]",
+	Short: "Stop the main process and all other processes of the workspace container",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  stopRunE,
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [WORKSPACE]",
+	Short: "Attach to the main process of the workspace container",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  attachRunE,
+}
+
+// getContainer is a helper function returning the runtime and container of the specified or
+// current workspace. If build is set, the container is built if it doesn't exist.
+// The caller must close the runtime.
+func getContainer(args []string, build bool) (runtime.Runtime, *container.Container, error) {
+	run, _, ctr, err := getWorkspaceContainer(args, build)
+	return run, ctr, err
+}
+
+// getWorkspaceContainer is a helper function like getContainer that also returns the
+// workspace.
+func getWorkspaceContainer(args []string,
+	build bool) (runtime.Runtime, *project.Workspace, *container.Container, error) {
+
+	prj, err := loadProject()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	wsName := ""
+	if len(args) > 0 {
+		wsName = args[0]
+	}
+	ws, err := getWorkspace(prj, wsName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctr, err := container.Get(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) && build {
+		ctr, err = buildContainer(run, ws)
+		if err == nil {
+			err = prj.Write()
+		}
+	}
+	if err != nil {
+		run.Close()
+		return nil, nil, nil, err
+	}
+
+	return run, ws, ctr, nil
+}
+
+// getContainerGeneration is a helper function returning the container of the workspace with
+// the provided hex encoded generation.
+func getContainerGeneration(run runtime.Runtime,
+	ws *project.Workspace, generation string) (*container.Container, error) {
+
+	gen, err := parseGeneration(generation)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr, err := container.GetGeneration(run, ws, gen)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return nil, errdefs.NotFound("container generation", generation)
+	}
+	return ctr, err
+}
+
+func startRunE(cmd *cobra.Command, args []string) error {
+
+	run, ctr, err := getContainer(args, true)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	return ctr.Start()
+}
+
+func stopRunE(cmd *cobra.Command, args []string) error {
+
+	run, ctr, err := getContainer(args, false)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	return ctr.Stop()
+}
+
+func attachRunE(cmd *cobra.Command, args []string) error {
+
+	run, ws, ctr, err := getWorkspaceContainer(args, false)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	// the main process is started without a terminal
+	stream, logBuf := logStream(runtime.Stream{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+
+	code, err := ctr.Attach(stream)
+	saveLogBufferWarning(ws, logBuf)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(int(code))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(attachCmd)
+}
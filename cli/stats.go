@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [WORKSPACE]",
+	Short: "Show the resource usage of the workspace container",
+	Long: `
+Show the CPU time, memory usage, and number of processes of the workspace container.
+The watch option refreshes the statistics every second.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: statsRunE,
+}
+
+var statsWatch bool
+
+// statsInterval is the refresh interval for watching the statistics.
+const statsInterval = time.Second
+
+// printStats prints the statistics of the container or a note if the container isn't running.
+func printStats(ctr *container.Container) error {
+
+	stats, err := ctr.Stats()
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		fmt.Printf("Container %s is not running\n", ctr.Name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	statsInfo := struct {
+		CPU         string
+		Memory      string
+		MemoryLimit string
+		Pids        string
+	}{
+		CPU:         time.Duration(stats.CPU).String(),
+		Memory:      sizeToSIString(int64(stats.MemoryUsage)),
+		MemoryLimit: sizeToSIString(int64(stats.MemoryLimit)),
+		Pids:        strconv.FormatUint(stats.Pids, 10),
+	}
+	printValue("Stats", "Value", "", statsInfo)
+	return nil
+}
+
+func statsRunE(cmd *cobra.Command, args []string) error {
+
+	run, ctr, err := getContainer(args, false)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	if !statsWatch {
+		return printStats(ctr)
+	}
+
+	for {
+		if output == outputText {
+			fmt.Print("\033[H\033[2J")
+		}
+		err = printStats(ctr)
+		if err != nil {
+			return err
+		}
+		time.Sleep(statsInterval)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().BoolVar(&statsWatch, "watch", false,
+		"Refresh the statistics every second")
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the current workspace",
+	Long: `
+Show a summary of the current workspace: the origin image, the number of layers,
+and whether the container is built and up to date with the workspace configuration.
+An outdated container has to be rebuilt with 'cne build'.`,
+	Args: cobra.NoArgs,
+	RunE: statusRunE,
+}
+
+var statusWorkspace string
+
+// Status values of the workspace container.
+const (
+	statusNotBuilt = "not built"
+	statusUpToDate = "up to date"
+	statusOutdated = "outdated, use 'cne build' to update"
+)
+
+type workspaceStatus struct {
+	Workspace          string
+	Origin             string
+	Layers             int
+	Built              bool
+	Container          string
+	Generation         string
+	ExpectedGeneration string
+	Status             string
+}
+
+// getWorkspaceStatus collects the status of the workspace and its container.
+func getWorkspaceStatus(run runtime.Runtime, ws *project.Workspace) (*workspaceStatus, error) {
+
+	gen := ws.ConfigHash()
+	status := &workspaceStatus{
+		Workspace:          ws.Name,
+		Origin:             ws.Environment.Origin,
+		Layers:             len(ws.Environment.Layers),
+		ExpectedGeneration: hex.EncodeToString(gen[:]),
+		Status:             statusNotBuilt,
+	}
+
+	ctr, err := container.Find(run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return status, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	status.Built = true
+	status.Container = ctr.Name
+	status.Generation = hex.EncodeToString(ctr.Generation[:])
+	if ctr.Generation == gen {
+		status.Status = statusUpToDate
+	} else {
+		status.Status = statusOutdated
+	}
+
+	return status, nil
+}
+
+func statusRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, statusWorkspace)
+	if err != nil {
+		return err
+	}
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	status, err := getWorkspaceStatus(run, ws)
+	if err != nil {
+		return err
+	}
+
+	if output == outputText {
+		switch status.Status {
+		case statusUpToDate:
+			status.Status = colorize(colorGreen, status.Status)
+		case statusOutdated:
+			status.Status = colorize(colorYellow, status.Status)
+		}
+	}
+	printValue("Field", "Value", "", status)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVarP(
+		&statusWorkspace, "workspace", "w", "", "Name of the workspace")
+}
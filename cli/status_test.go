@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+func TestCliWorkspaceStatus(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	img := run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	ws := &project.Workspace{
+		Name:        "main",
+		ProjectUUID: uuid.New().String(),
+		Environment: project.Environment{Origin: "busybox"},
+	}
+
+	status, err := getWorkspaceStatus(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if status.Built || status.Status != statusNotBuilt {
+		t.Errorf("Expected workspace not built, got %v", status)
+	}
+
+	dom, _ := uuid.Parse(ws.ProjectUUID)
+	runCtr, err := run.NewContainer(dom, ws.ID(), ws.ConfigHash(), 0, "test", img, nil)
+	if err != nil {
+		t.Fatalf("Failed to define container: %v", err)
+	}
+	if _, err = runCtr.Create(); err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	status, err = getWorkspaceStatus(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if !status.Built || status.Status != statusUpToDate ||
+		status.Generation != status.ExpectedGeneration {
+		t.Errorf("Expected workspace up to date, got %v", status)
+	}
+
+	if _, err = ws.CreateLayer(false, "tools", -1); err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+
+	status, err = getWorkspaceStatus(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if status.Layers != 1 || status.Status != statusOutdated {
+		t.Errorf("Expected outdated workspace with one layer, got %v", status)
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+// tagImage creates the target reference for the source image and pulls the source image if
+// it doesn't exist.
+func tagImage(run runtime.Runtime, source, target string) (runtime.Image, error) {
+
+	img, err := run.GetImage(source)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		img, err = pullImage(run, source, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return img.Tag(target)
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag SOURCE TARGET",
+	Short: "Create a new reference for an image",
+	Long: `
+Create the TARGET reference for the SOURCE image. The source image is pulled if it
+doesn't exist. Names without a registry use the default registry.`,
+	Args: cobra.ExactArgs(2),
+	RunE: tagRunE,
+}
+
+func tagRunE(cmd *cobra.Command, args []string) error {
+
+	run, err := runtime.OpenLazy(conf.Runtime)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	source := conf.FullImageName(args[0])
+	target := conf.FullImageName(args[1])
+
+	_, err = tagImage(run, source, target)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tagged %s as %s\n", source, target)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+}
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/czankel/cne/runtime"
+)
+
+func TestTagImage(t *testing.T) {
+
+	run := &testRuntime{imgs: []runtime.Image{&testImage{name: "docker.io/library/source:1"}}}
+
+	img, err := tagImage(run, "docker.io/library/source:1", "docker.io/library/target:2")
+	if err != nil {
+		t.Fatalf("Failed to tag image: %v", err)
+	}
+	if img.Name() != "docker.io/library/target:2" {
+		t.Errorf("Expected the tagged image, got %s", img.Name())
+	}
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+)
+
+var unsetCmd = &cobra.Command{
+	Use:   "unset",
+	Short: "Unset workspace properties and configurations",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var unsetEnvCmd = &cobra.Command{
+	Use:   "env KEY...",
+	Short: "Remove environment variables from the workspace",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  unsetEnvRunE,
+}
+
+var unsetEnvWorkspace string
+
+func unsetEnvRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, unsetEnvWorkspace)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range args {
+		err = ws.UnsetEnv(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return prj.Write()
+}
+
+var unsetShellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Remove the shell of the workspace to use the shell of the user",
+	Args:  cobra.NoArgs,
+	RunE:  unsetShellRunE,
+}
+
+var unsetShellWorkspace string
+
+func unsetShellRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, err := getWorkspace(prj, unsetShellWorkspace)
+	if err != nil {
+		return err
+	}
+
+	ws.Shell = ""
+
+	return prj.Write()
+}
+
+var unsetConfigCmd = &cobra.Command{
+	Use:   "config NAME",
+	Short: "Remove a configuration value",
+	Long: `
+Remove a value from the user or system configuration file, so the value falls
+back to the system or default configuration. The name is the path of the
+configuration field using '/' as the separator, such as 'runtime/name'.`,
+	Args:        cobra.ExactArgs(1),
+	RunE:        unsetConfigRunE,
+	Annotations: map[string]string{skipValidation: ""},
+}
+
+var unsetConfigSystem bool
+
+func unsetConfigRunE(cmd *cobra.Command, args []string) error {
+
+	name := args[0]
+
+	// validate the name against the merged configuration
+	all, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if _, _, err = all.GetAllByName(name); err != nil {
+		return err
+	}
+
+	if unsetConfigSystem {
+		conf, err = config.LoadSystemConfig()
+	} else {
+		conf, err = config.LoadUserConfig()
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = conf.UnsetByName(name)
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+
+	if err == nil {
+		if unsetConfigSystem {
+			err = conf.WriteSystemConfig()
+		} else {
+			err = conf.WriteUserConfig()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	conf, err = config.Load()
+	if err != nil {
+		return err
+	}
+	path, val, err := conf.GetAllByName(name)
+	if err != nil {
+		fmt.Printf("Configuration '%s' removed\n", name)
+		return nil
+	}
+	printValue("Configuration", "Value", path, val)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(unsetCmd)
+	unsetCmd.AddCommand(unsetEnvCmd)
+	unsetEnvCmd.Flags().StringVarP(
+		&unsetEnvWorkspace, "workspace", "w", "", "Name of the workspace")
+	unsetCmd.AddCommand(unsetShellCmd)
+	unsetShellCmd.Flags().StringVarP(
+		&unsetShellWorkspace, "workspace", "w", "", "Name of the workspace")
+	unsetCmd.AddCommand(unsetConfigCmd)
+	unsetConfigCmd.Flags().BoolVar(
+		&unsetConfigSystem, "system", false, "Unset the system configuration")
+}
@@ -3,7 +3,6 @@ package cli
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/errdefs"
 )
 
@@ -62,41 +61,7 @@ The system option modifies the system-wide configuration file stored in
 }
 
 func updateConfigRunE(cmd *cobra.Command, args []string) error {
-
-	var err error
-
-	if updateSystemConfig {
-		conf, err = config.LoadSystemConfig()
-	} else {
-		conf, err = config.LoadUserConfig()
-	}
-	if err != nil {
-		return err
-	}
-
-	name := args[0]
-	newVal := args[1]
-	oldVal, path, err := conf.SetByName(name, newVal)
-	if err != nil {
-		return err
-	}
-
-	if updateSystemConfig {
-		err = conf.WriteSystemConfig()
-	} else {
-		err = conf.WriteUserConfig()
-	}
-
-	if err != nil {
-		return err
-	}
-
-	printList([]struct {
-		Configuration string
-		Value         string
-		Old           string
-	}{{path, newVal, oldVal}}, false)
-	return nil
+	return setConfig(args[0], args[1], updateSystemConfig)
 }
 
 var updateProjectCmd = &cobra.Command{
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a resource",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Validate the configuration",
+	Long: `
+Validate the configuration derived from all configuration files and report
+all problems, such as an unknown runtime, a missing runtime socket, invalid
+registries, or conflicting runtime profiles. The command doesn't access the
+runtime and returns an error if the configuration is invalid.`,
+	Args:        cobra.NoArgs,
+	RunE:        validateConfigRunE,
+	Annotations: map[string]string{skipValidation: ""},
+}
+
+// validateConfig prints all problems of the configuration and returns an error if the
+// configuration is invalid.
+func validateConfig(conf *config.Config) error {
+
+	problems := conf.Problems(runtime.Runtimes())
+	if len(problems) == 0 {
+		fmt.Println("Configuration is valid")
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Printf("%s %v\n", colorize(colorRed, "ERROR:"), p)
+	}
+	return errdefs.InvalidArgument("configuration has %d problem(s)", len(problems))
+}
+
+func validateConfigRunE(cmd *cobra.Command, args []string) error {
+
+	// validate the configuration files without the runtime profile or options
+	conf, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return validateConfig(conf)
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.AddCommand(validateConfigCmd)
+}
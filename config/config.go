@@ -5,12 +5,21 @@ package config
 
 import (
 	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/user"
+	"path"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 
 	"github.com/czankel/cne/errdefs"
 )
@@ -18,40 +27,435 @@ import (
 // CneVersion is set in Makefile by a linker option to the git hash/version
 var CneVersion string
 
+// Runtime describes the configuration of a runtime. An unset CreateNamespace enables creating
+// the namespace, so an explicit 'false' is kept when the configuration is written.
 type Runtime struct {
-	Name       string `toml:"Name,omitempty"`
-	SocketName string `toml:"SocketName,omitempty"`
-	Namespace  string `cne:"ReadOnly" toml:"Namespace,omitempty"`
+	Name            string `toml:"Name,omitempty" yaml:",omitempty"`
+	SocketName      string `toml:"SocketName,omitempty" yaml:",omitempty"`
+	Namespace       string `cne:"ReadOnly" toml:"Namespace,omitempty" yaml:",omitempty"`
+	CreateNamespace *bool  `toml:"CreateNamespace,omitempty" yaml:",omitempty"`
+	Plugin          string `toml:"Plugin,omitempty" yaml:",omitempty"`
+	Proxy           Proxy  `toml:"Proxy,omitempty" yaml:",omitempty"`
+	PullRetry       Retry  `toml:"PullRetry,omitempty" yaml:",omitempty"`
 }
 
+// CreateNamespaceEnabled returns true if the namespace should be created if it doesn't exist.
+func (run *Runtime) CreateNamespaceEnabled() bool {
+	return run.CreateNamespace == nil || *run.CreateNamespace
+}
+
+// Retry describes how often a failed operation is attempted and the delay before the first
+// retry, which doubles for every further retry. Only transient errors are retried.
+type Retry struct {
+	Attempts int `toml:"Attempts,omitempty" yaml:",omitempty"` // Maximum number of attempts
+	Delay    int `toml:"Delay,omitempty" yaml:",omitempty"`    // Delay in milliseconds
+}
+
+// Proxy describes the proxies for accessing registries. Empty fields fall back to the
+// HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.
+type Proxy struct {
+	HTTP    string `toml:"HTTP,omitempty" yaml:",omitempty"`    // Proxy for HTTP requests
+	HTTPS   string `toml:"HTTPS,omitempty" yaml:",omitempty"`   // Proxy for HTTPS requests
+	NoProxy string `toml:"NoProxy,omitempty" yaml:",omitempty"` // Hosts that bypass the proxy
+}
+
+// Auth describes the credentials for accessing a registry. The token is used instead of the
+// username and password if no username is provided.
+type Auth struct {
+	Username string `toml:"Username,omitempty" yaml:",omitempty"`
+	Password string `toml:"Password,omitempty" yaml:",omitempty" output:"-"`
+	Token    string `toml:"Token,omitempty" yaml:",omitempty" output:"-"`
+}
+
+// Registry describes a registry. Mirrors are endpoints, such as 'https://mirror.example.com',
+// that are tried in order for pulling images before the registry itself.
 type Registry struct {
-	Domain   string
-	RepoName string
+	Domain   string   `toml:"Domain,omitempty" yaml:",omitempty"`
+	RepoName string   `toml:"RepoName,omitempty" yaml:",omitempty"`
+	Auth     *Auth    `toml:"Auth,omitempty" yaml:",omitempty"`
+	Insecure bool     `toml:"Insecure,omitempty" yaml:",omitempty"` // Allow plain HTTP and skip TLS verification
+	Mirrors  []string `toml:"Mirrors,omitempty" yaml:",omitempty"`
 }
 
+// Mount describes a bind mount of a host directory into the container.
+// Relative paths are relative to the home directory of the user.
+type Mount struct {
+	Source      string
+	Destination string
+	ReadOnly    bool `toml:"ReadOnly,omitempty" yaml:",omitempty"`
+}
+
+// HostEnv defines the environment variables of the host that are passed into the container.
+// Entries are names or shell patterns, such as '*_TOKEN'. All variables are passed if the
+// allowlist is empty. Variables that match the denylist are never passed.
+type HostEnv struct {
+	Allow []string `toml:"Allow,omitempty" yaml:",omitempty"`
+	Deny  []string `toml:"Deny,omitempty" yaml:",omitempty"`
+}
+
+// Config describes the configuration. RuntimeProfiles are additional named runtime
+// configurations and DefaultRuntimeProfile selects the profile that is used if no profile is
+// specified. The Runtime configuration is used as the 'default' profile.
 type Config struct {
-	Runtime  Runtime `toml:"Runtime,omitempty"`
-	Registry map[string]*Registry
+	Runtime               Runtime              `toml:"Runtime,omitempty" yaml:",omitempty"`
+	RuntimeProfiles       map[string]*Runtime  `toml:"RuntimeProfiles,omitempty" yaml:",omitempty"`
+	DefaultRuntimeProfile string               `toml:"DefaultRuntimeProfile,omitempty" yaml:",omitempty"`
+	Registry              map[string]*Registry `yaml:",omitempty"`
+	DefaultMounts         []Mount              `toml:"DefaultMounts,omitempty" yaml:",omitempty"`
+	HostEnv               HostEnv              `toml:"HostEnv,omitempty" yaml:",omitempty"`
+	ShortIDLength         int                  `toml:"ShortIDLength,omitempty" yaml:",omitempty"`
+}
+
+// matchEnv returns true if the name of the variable matches any of the patterns.
+func matchEnv(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the variables of the environment list, in the form "key=value", that
+// can be passed into the container.
+func (hostEnv *HostEnv) Filter(environ []string) []string {
+
+	env := []string{}
+	for _, e := range environ {
+		name := strings.SplitN(e, "=", 2)[0]
+		if matchEnv(hostEnv.Deny, name) {
+			continue
+		}
+		if len(hostEnv.Allow) != 0 && !matchEnv(hostEnv.Allow, name) {
+			continue
+		}
+		env = append(env, e)
+	}
+	return env
+}
+
+// SelectRuntimeProfile replaces the runtime configuration with the configuration of the
+// named runtime profile. The DefaultRuntimeProfile is used if the name is empty, and the
+// runtime configuration is kept for the 'default' profile unless a profile with that name
+// exists. Empty fields of the profile are inherited from the runtime configuration.
+// Errors:
+//  - ErrNotFound if the profile doesn't exist
+func (conf *Config) SelectRuntimeProfile(name string) error {
+
+	if name == "" {
+		name = conf.DefaultRuntimeProfile
+	}
+
+	profile, ok := conf.RuntimeProfiles[name]
+	if !ok || profile == nil {
+		if name == "" || name == DefaultRuntimeProfileName {
+			return nil
+		}
+		return errdefs.NotFound("runtime profile", name)
+	}
+
+	run := *profile
+	if run.Name == "" {
+		run.Name = conf.Runtime.Name
+	}
+	if run.SocketName == "" {
+		run.SocketName = conf.Runtime.SocketName
+	}
+	if run.Namespace == "" {
+		run.Namespace = conf.Runtime.Namespace
+	}
+	if run.CreateNamespace == nil && conf.Runtime.CreateNamespace != nil {
+		run.CreateNamespace = newBool(*conf.Runtime.CreateNamespace)
+	}
+	if run.Plugin == "" {
+		run.Plugin = conf.Runtime.Plugin
+	}
+	if run.Proxy == (Proxy{}) {
+		run.Proxy = conf.Runtime.Proxy
+	}
+	if run.PullRetry == (Retry{}) {
+		run.PullRetry = conf.Runtime.PullRetry
+	}
+	conf.Runtime = run
+
+	return nil
+}
+
+// validateRuntime returns all problems of the runtime configuration.
+func validateRuntime(run *Runtime, runtimes []string) []error {
+
+	var errs []error
+
+	valid := false
+	for _, name := range runtimes {
+		if name == run.Name {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		names := append([]string{}, runtimes...)
+		sort.Strings(names)
+		errs = append(errs, errdefs.InvalidArgument(
+			"invalid runtime '%s', valid runtimes are: %s",
+			run.Name, strings.Join(names, ", ")))
+	}
+
+	if !filepath.IsAbs(run.SocketName) {
+		errs = append(errs, errdefs.InvalidArgument(
+			"runtime socket '%s' must be an absolute path", run.SocketName))
+	}
+
+	if run.Namespace == "" {
+		errs = append(errs, errdefs.InvalidArgument("runtime namespace must not be empty"))
+	}
+	if run.PullRetry.Attempts < 0 || run.PullRetry.Delay < 0 {
+		errs = append(errs, errdefs.InvalidArgument("pull retry settings must not be negative"))
+	}
+
+	return errs
+}
+
+// validateRegistry returns all problems of the registry configuration. The domain is a host
+// name with an optional port but without a scheme or path.
+func validateRegistry(name string, reg *Registry) []error {
+
+	if reg == nil {
+		return []error{errdefs.InvalidArgument("registry '%s' is empty", name)}
+	}
+
+	var errs []error
+	if reg.Domain == "" {
+		errs = append(errs, errdefs.InvalidArgument("registry '%s' has no domain", name))
+	} else if strings.Contains(reg.Domain, "://") {
+		errs = append(errs, errdefs.InvalidArgument(
+			"registry '%s' domain '%s' must not include a scheme", name, reg.Domain))
+	} else if u, err := url.Parse("//" + reg.Domain); err != nil || u.Host != reg.Domain {
+		errs = append(errs, errdefs.InvalidArgument(
+			"registry '%s' domain '%s' is not a valid host", name, reg.Domain))
+	}
+	if strings.HasPrefix(reg.RepoName, "/") || strings.HasSuffix(reg.RepoName, "/") {
+		errs = append(errs, errdefs.InvalidArgument(
+			"registry '%s' repository '%s' must not start or end with '/'",
+			name, reg.RepoName))
+	}
+	for _, mirror := range reg.Mirrors {
+		if u, err := url.Parse(mirror); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errdefs.InvalidArgument(
+				"registry '%s' mirror '%s' must be an http or https URL", name, mirror))
+		}
+	}
+	return errs
+}
+
+// Validate verifies the runtime configuration. The runtime name has to be one of the provided
+// runtimes, the socket an absolute path, and the namespace must not be empty.
+// Errors:
+//  - ErrInvalidArgument if the runtime configuration is invalid
+func (conf *Config) Validate(runtimes []string) error {
+
+	if errs := validateRuntime(&conf.Runtime, runtimes); len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// Problems returns all problems of the configuration. In addition to Validate, it verifies
+// that the runtime socket exists, the runtime profiles, and the registries.
+func (conf *Config) Problems(runtimes []string) []error {
+
+	errs := validateRuntime(&conf.Runtime, runtimes)
+	if filepath.IsAbs(conf.Runtime.SocketName) {
+		if _, err := os.Stat(conf.Runtime.SocketName); err != nil {
+			errs = append(errs, errdefs.NotFound("runtime socket", conf.Runtime.SocketName))
+		}
+	}
+
+	names := make([]string, 0, len(conf.RuntimeProfiles))
+	for name := range conf.RuntimeProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// runtimes of the sockets to detect profiles that use the same socket for different runtimes
+	sockets := map[string]string{conf.Runtime.SocketName: DefaultRuntimeProfileName}
+	for _, name := range names {
+		if conf.RuntimeProfiles[name] == nil {
+			errs = append(errs, errdefs.InvalidArgument("runtime profile '%s' is empty", name))
+			continue
+		}
+
+		profConf := *conf
+		profConf.SelectRuntimeProfile(name)
+		run := &profConf.Runtime
+		for _, err := range validateRuntime(run, runtimes) {
+			errs = append(errs, errdefs.InvalidArgument("runtime profile '%s': %v", name, err))
+		}
+
+		other, ok := sockets[run.SocketName]
+		if !ok {
+			sockets[run.SocketName] = name
+			continue
+		}
+		otherRun := conf.Runtime
+		if other != DefaultRuntimeProfileName {
+			otherConf := *conf
+			otherConf.SelectRuntimeProfile(other)
+			otherRun = otherConf.Runtime
+		}
+		if otherRun.Name != run.Name {
+			errs = append(errs, errdefs.InvalidArgument(
+				"runtime profiles '%s' and '%s' use socket '%s' for different runtimes",
+				other, name, run.SocketName))
+		}
+	}
+
+	defProfile := conf.DefaultRuntimeProfile
+	if defProfile != "" && defProfile != DefaultRuntimeProfileName {
+		if _, ok := conf.RuntimeProfiles[defProfile]; !ok {
+			errs = append(errs, errdefs.NotFound("default runtime profile", defProfile))
+		}
+	}
+
+	names = make([]string, 0, len(conf.Registry))
+	for name := range conf.Registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, validateRegistry(name, conf.Registry[name])...)
+	}
+
+	return errs
+}
+
+// isYAML returns true if the configuration file uses the YAML format, which is selected by
+// the .yaml or .yml extension. All other files use the TOML format.
+func isYAML(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// decodeFile reads the configuration file in the format of the file.
+func decodeFile(path string, conf *Config) error {
+
+	if !isYAML(path) {
+		_, err := toml.DecodeFile(path, conf)
+		return err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, conf)
+}
+
+// encode writes the configuration in the format of the configuration file.
+func (conf *Config) encode(w io.Writer, path string) error {
+
+	writer := bufio.NewWriter(w)
+	var err error
+	if isYAML(path) {
+		enc := yaml.NewEncoder(writer)
+		err = enc.Encode(conf)
+		if err == nil {
+			err = enc.Close()
+		}
+	} else {
+		err = toml.NewEncoder(writer).Encode(conf)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	return err
 }
 
 // update updates the configuration with the values from the specified configuration file
 func (conf *Config) update(path string) error {
-	_, err := toml.DecodeFile(path, conf)
+	err := decodeFile(path, conf)
+	if err != nil && os.IsNotExist(err) && path == userConfigFile {
+		return errdefs.NotFound("configuration file", path)
+	}
 	if err != nil && !os.IsNotExist(err) {
 		return errdefs.InvalidArgument("config file '%s' corrupt", path)
 	}
 	return nil
 }
 
+// userConfigFile replaces the user configuration file if set.
+var userConfigFile string
+
+// SetUserConfigFile replaces the configuration file in the home directory of the user with
+// the specified file. The file is read above the system configuration file and is used for
+// reading and writing the user configuration.
+// Errors:
+//  - ErrNotFound if the file doesn't exist
+func SetUserConfigFile(path string) error {
+
+	if path == "" {
+		userConfigFile = ""
+		return nil
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return errdefs.SystemError(err, "invalid configuration file '%s'", path)
+	}
+	if _, err = os.Stat(path); err != nil {
+		return errdefs.NotFound("configuration file", path)
+	}
+	userConfigFile = path
+	return nil
+}
+
+// userConfigPath returns the path of the user configuration file.
+func userConfigPath() (string, error) {
+
+	if userConfigFile != "" {
+		return userConfigFile, nil
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	// use a YAML configuration file if only the YAML file exists
+	path := usr.HomeDir + "/" + UserConfigFile
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		for _, ext := range []string{".yaml", ".yml"} {
+			if _, err := os.Stat(path + ext); err == nil {
+				return path + ext, nil
+			}
+		}
+	}
+	return path, nil
+}
+
+// newBool returns a pointer to a new bool with the value.
+func newBool(b bool) *bool {
+	return &b
+}
+
 // Load returns the default configuration amended by the configuration stored in the
-// system and user configuration file.
+// system and user configuration file, and by CNE_* environment variables. Values take
+// precedence in this order, from lowest to highest: defaults, system configuration file, user
+// configuration file, environment variables. Command line options override all of them.
 func Load() (*Config, error) {
 
 	conf := &Config{
 		Runtime: Runtime{
-			Name:       DefaultExecRuntimeName,
-			SocketName: DefaultExecRuntimeSocketName,
-			Namespace:  DefaultExecRuntimeNamespace,
+			Name:            DefaultExecRuntimeName,
+			SocketName:      DefaultExecRuntimeSocketName,
+			Namespace:       DefaultExecRuntimeNamespace,
+			CreateNamespace: newBool(true),
+			Plugin:          DefaultExecRuntimePlugin,
+			PullRetry: Retry{
+				Attempts: DefaultPullRetryAttempts,
+				Delay:    DefaultPullRetryDelay,
+			},
 		},
 		Registry: map[string]*Registry{
 			DefaultRegistryName: &Registry{
@@ -59,18 +463,120 @@ func Load() (*Config, error) {
 				RepoName: DefaultRegistryRepoName,
 			},
 		},
+		HostEnv: HostEnv{
+			Deny: DefaultHostEnvDeny,
+		},
+		ShortIDLength: DefaultShortIDLength,
 	}
 
 	conf.update(SystemConfigFile)
 
-	usr, err := user.Current()
+	path, err := userConfigPath()
 	if err == nil {
-		err = conf.update(usr.HomeDir + "/" + UserConfigFile)
+		err = conf.update(path)
+	}
+
+	if envErr := conf.applyEnv(os.LookupEnv); err == nil {
+		err = envErr
 	}
 
 	return conf, err
 }
 
+// envName returns the name for an environment variable derived from a field name or map key
+// by converting it to uppercase and replacing all other characters than letters and digits
+// with '_'.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, name)
+}
+
+// applyEnv overrides the configuration fields with the values of environment variables. The
+// name of the variable is the path of the field prefixed by CNE and separated by '_', such as
+// CNE_RUNTIME_SOCKETNAME. Elements of maps can only be overridden if the key already exists,
+// such as CNE_REGISTRY_DOCKER_IO_REPONAME for the 'docker.io' registry. String lists are
+// separated by ','. Variables with an empty value are ignored.
+func (conf *Config) applyEnv(lookup func(string) (string, bool)) error {
+	return applyEnvValue(reflect.ValueOf(conf).Elem(), EnvPrefix, "", lookup)
+}
+
+// applyEnvValue overrides the value and all nested values for the environment variable name.
+func applyEnvValue(elem reflect.Value, env, path string,
+	lookup func(string) (string, bool)) error {
+
+	switch elem.Kind() {
+	case reflect.Struct:
+		elemType := elem.Type()
+		for i := 0; i < elem.NumField(); i++ {
+			field := elemType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			err := applyEnvValue(elem.Field(i), env+"_"+envName(field.Name),
+				path+"/"+field.Name, lookup)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if elem.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		keys := elem.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			// only pointer elements can be updated in place
+			val := elem.MapIndex(key)
+			if val.Kind() != reflect.Ptr {
+				continue
+			}
+			err := applyEnvValue(val, env+"_"+envName(key.String()),
+				path+"/"+key.String(), lookup)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		if elem.Type().Elem().Kind() != reflect.Struct {
+			value, ok := lookup(env)
+			if !ok || value == "" {
+				return nil
+			}
+			if err := setValue(elem, value, strings.TrimPrefix(path, "/")); err != nil {
+				return errdefs.InvalidArgument("environment variable %s: %v", env, err)
+			}
+		} else if !elem.IsNil() {
+			return applyEnvValue(elem.Elem(), env, path, lookup)
+		}
+	case reflect.Slice:
+		value, ok := lookup(env)
+		if !ok || value == "" || elem.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		list := strings.Split(value, ",")
+		for i := range list {
+			list[i] = strings.TrimSpace(list[i])
+		}
+		elem.Set(reflect.ValueOf(list))
+	default:
+		value, ok := lookup(env)
+		if !ok || value == "" {
+			return nil
+		}
+		if err := setValue(elem, value, strings.TrimPrefix(path, "/")); err != nil {
+			return errdefs.InvalidArgument("environment variable %s: %v", env, err)
+		}
+	}
+	return nil
+}
+
 // LoadSystemConfig loads only the system configuration
 func LoadSystemConfig() (*Config, error) {
 
@@ -85,9 +591,9 @@ func LoadUserConfig() (*Config, error) {
 
 	conf := &Config{}
 
-	usr, err := user.Current()
+	path, err := userConfigPath()
 	if err == nil {
-		conf.update(usr.HomeDir + "/" + UserConfigFile)
+		err = conf.update(path)
 	}
 
 	return conf, err
@@ -137,7 +643,7 @@ func (conf *Config) getValue(filter string, makeMap bool) (string, reflect.Value
 		}
 		if i != len(path)-1 {
 			realPath = realPath + "/"
-		} else if elem.Kind() == reflect.String {
+		} else if curElem.Kind() == reflect.Struct {
 			field, _ := curElem.Type().FieldByName(fieldName)
 			tag = field.Tag.Get("cne")
 		}
@@ -146,33 +652,116 @@ func (conf *Config) getValue(filter string, makeMap bool) (string, reflect.Value
 	return realPath, elem, tag
 }
 
-// Set updates the value of the configuration field
-// Returns the actual case-corrected path and value of the field
-// Errors:
-//  - ErrNoSuchResource if the specified configuration field cannot be found
-//  - ErrInvalidArgument if the specified configuration field is a structure
-//  - ErrReadOnly if the specified configuration field cannot be written
+// setValue converts the value to the type of the field and sets the field.
+func setValue(field reflect.Value, value, name string) error {
+
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), value, name); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	var err error
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			field.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, field.Type().Bits()); err == nil {
+			field.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(value, 10, field.Type().Bits()); err == nil {
+			field.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(value, field.Type().Bits()); err == nil {
+			field.SetFloat(f)
+		}
+	default:
+		return errdefs.InvalidArgument("cannot set configuration '%s'", name)
+	}
+	if err != nil {
+		return errdefs.InvalidArgument("invalid %s value '%s' for configuration '%s'",
+			field.Kind(), value, name)
+	}
+	return nil
+}
 
+// SetByName updates the value of the configuration field. The value is converted to the
+// type of the field.
+// Returns the old value and the actual case-corrected path of the field
+// Errors:
+//  - ErrNotFound if the specified configuration field cannot be found
+//  - ErrInvalidArgument if the specified configuration field is a structure, the value
+//    doesn't match the type of the field, or the field is read-only
 func (conf *Config) SetByName(name string, value string) (string, string, error) {
 
 	path, field, tag := conf.getValue(name, true)
 	if !field.IsValid() {
 		return "", path, errdefs.NotFound("configuration", name)
 	}
-	if field.Kind() != reflect.String {
-		return "", "", errdefs.InvalidArgument("cannot set configuration '%s'", name)
-	}
-
 	if tag == "ReadOnly" {
 		return "", "", errdefs.InvalidArgument("configuration '%s' is read-only", name)
 	}
 
-	oldValue := field.String()
-	field.SetString(value)
+	oldValue := ""
+	if field.Kind() != reflect.Ptr {
+		oldValue = fmt.Sprint(field.Interface())
+	} else if !field.IsNil() {
+		oldValue = fmt.Sprint(field.Elem().Interface())
+	}
+
+	if err := setValue(field, value, name); err != nil {
+		return "", "", err
+	}
 
 	return oldValue, path, nil
 }
 
+// UnsetByName resets the configuration field to its zero value, or removes the entry if
+// the field is an element of a map, so the field isn't written to the configuration file.
+// Returns the actual case-corrected path of the field
+// Errors:
+//  - ErrNotFound if the specified configuration field cannot be found
+//  - ErrInvalidArgument if the specified configuration field is read-only
+func (conf *Config) UnsetByName(name string) (string, error) {
+
+	path, field, tag := conf.getValue(name, false)
+	if !field.IsValid() {
+		return "", errdefs.NotFound("configuration", name)
+	}
+	if tag == "ReadOnly" {
+		return "", errdefs.InvalidArgument("configuration '%s' is read-only", name)
+	}
+
+	// remove the map entry if the path references an element of a map
+	if idx := strings.LastIndex(path, "/"); idx > 0 {
+		_, parent, _ := conf.getValue(path[:idx], false)
+		if parent.Kind() == reflect.Map {
+			parent.SetMapIndex(reflect.ValueOf(path[idx+1:]), reflect.Value{})
+			return path, nil
+		}
+	}
+
+	if !field.CanSet() {
+		return "", errdefs.InvalidArgument("cannot unset configuration '%s'", name)
+	}
+	field.Set(reflect.Zero(field.Type()))
+
+	return path, nil
+}
+
 // Get returns the value of the configuration field specified by name
 // Errors:
 //  - ErrNotFound if the specified configuration field cannot be found
@@ -210,8 +799,7 @@ func (conf *Config) WriteSystemConfig() error {
 	defer file.Close()
 	defer file.Sync()
 
-	writer := bufio.NewWriter(file)
-	err = toml.NewEncoder(writer).Encode(conf)
+	err = conf.encode(file, SystemConfigFile)
 	if err != nil {
 		return errdefs.SystemError(err, "failed to write configuration file")
 	}
@@ -221,19 +809,23 @@ func (conf *Config) WriteSystemConfig() error {
 // WriteUserConfig writes the user configuration in the home directory of the current user.
 func (conf *Config) WriteUserConfig() error {
 
-	usr, err := user.Current()
+	path, err := userConfigPath()
 	if err != nil {
 		return err
 	}
 
-	path := usr.HomeDir + "/" + UserConfigFile
-	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, ConfigFilePerms)
+	// the user configuration can include registry credentials
+	file, err := os.OpenFile(path, os.O_TRUNC|os.O_RDWR|os.O_CREATE, UserConfigFilePerms)
 	if err != nil {
 		return errdefs.SystemError(err, "failed to write configuration file '%s'", path)
 	}
 	defer file.Close()
 	defer file.Sync()
 
+	if err = file.Chmod(UserConfigFilePerms); err != nil {
+		return errdefs.SystemError(err, "failed to update permissions for '%s'", path)
+	}
+
 	euid := os.Geteuid()
 	uid := os.Getuid()
 	if euid != uid {
@@ -244,8 +836,7 @@ func (conf *Config) WriteUserConfig() error {
 		}
 	}
 
-	writer := bufio.NewWriter(file)
-	err = toml.NewEncoder(writer).Encode(conf)
+	err = conf.encode(file, path)
 	if err != nil {
 		return errdefs.SystemError(err, "failed to write configuration file '%s'", path)
 	}
@@ -260,8 +851,10 @@ func (conf *Config) FullImageName(name string) string {
 		reg, foundReg = conf.Registry[name[:domEnd-1]]
 	}
 
-	if foundReg {
+	if foundReg && reg.RepoName != "" {
 		name = reg.Domain + "/" + reg.RepoName + "/" + name[domEnd:]
+	} else if foundReg {
+		name = reg.Domain + "/" + name[domEnd:]
 	}
 
 	v := strings.LastIndex(name, ":")
@@ -272,6 +865,66 @@ func (conf *Config) FullImageName(name string) string {
 	return name
 }
 
+// RegistryHost returns the host name for accessing the registry of the domain.
+func RegistryHost(domain string) string {
+	if domain == DefaultRegistryDomain {
+		return DefaultRegistryHost
+	}
+	return domain
+}
+
+// Credentials returns the username and secret configured for the registry host. The secret is
+// the password or, if no username is configured, the token. Both values are empty if no
+// credentials are configured for the host.
+func (conf *Config) Credentials(host string) (string, string, error) {
+
+	for _, reg := range conf.Registry {
+		if reg.Auth == nil || (host != reg.Domain && host != RegistryHost(reg.Domain)) {
+			continue
+		}
+		if reg.Auth.Username != "" {
+			return reg.Auth.Username, reg.Auth.Password, nil
+		}
+		return "", reg.Auth.Token, nil
+	}
+	return "", "", nil
+}
+
+// InsecureRegistry returns true if the registry for the host is configured to be accessed
+// over plain HTTP or without TLS verification.
+func (conf *Config) InsecureRegistry(host string) bool {
+
+	for _, reg := range conf.Registry {
+		if reg.Insecure && (host == reg.Domain || host == RegistryHost(reg.Domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistryMirrors returns the mirror endpoints configured for the registry host.
+func (conf *Config) RegistryMirrors(host string) []string {
+
+	for _, reg := range conf.Registry {
+		if len(reg.Mirrors) != 0 && (host == reg.Domain || host == RegistryHost(reg.Domain)) {
+			return reg.Mirrors
+		}
+	}
+	return nil
+}
+
+// RegistryCredentials returns the credentials for the registry host from the Docker
+// configuration of the user or, if the Docker configuration has no entry for the host, from
+// the registry configuration.
+func (conf *Config) RegistryCredentials(host string) (string, string, error) {
+
+	username, secret, err := DockerCredentials(host)
+	if err != nil || username != "" || secret != "" {
+		return username, secret, err
+	}
+	return conf.Credentials(host)
+}
+
 // GetUser returns the details and credentials of the current user
 func (conf *Config) User() (User, error) {
 	return CurrentUser()
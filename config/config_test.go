@@ -0,0 +1,556 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+func TestConfigHostEnvFilter(t *testing.T) {
+
+	environ := []string{
+		"TERM=xterm",
+		"HOME=/home/user",
+		"GITHUB_TOKEN=secret",
+		"TERM_TOKEN=secret",
+	}
+
+	// empty allowlist passes all variables but the denied
+	hostEnv := HostEnv{Deny: []string{"*_TOKEN"}}
+	env := hostEnv.Filter(environ)
+	expected := []string{"TERM=xterm", "HOME=/home/user"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+
+	// only allowlisted variables are passed and the denylist wins
+	hostEnv = HostEnv{Allow: []string{"TERM*"}, Deny: []string{"*_TOKEN"}}
+	env = hostEnv.Filter(environ)
+	expected = []string{"TERM=xterm"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %v, got %v", expected, env)
+	}
+
+	hostEnv = HostEnv{Allow: []string{"GITHUB_TOKEN"}, Deny: []string{"GITHUB_TOKEN"}}
+	env = hostEnv.Filter(environ)
+	if len(env) != 0 {
+		t.Errorf("Denylisted variable should not be passed: %v", env)
+	}
+}
+
+func TestConfigCredentials(t *testing.T) {
+
+	conf := &Config{Registry: map[string]*Registry{
+		DefaultRegistryName: &Registry{
+			Domain: DefaultRegistryDomain,
+			Auth:   &Auth{Username: "user", Password: "secret"},
+		},
+		"private": &Registry{
+			Domain: "registry.example.com",
+			Auth:   &Auth{Token: "token"},
+		},
+		"public": &Registry{Domain: "public.example.com"},
+	}}
+
+	tests := []struct {
+		host     string
+		username string
+		secret   string
+	}{
+		{DefaultRegistryHost, "user", "secret"},
+		{"registry.example.com", "", "token"},
+		{"public.example.com", "", ""},
+		{"unknown.example.com", "", ""},
+	}
+	for _, tc := range tests {
+		username, secret, err := conf.Credentials(tc.host)
+		if err != nil {
+			t.Fatalf("Failed to get credentials for %s: %v", tc.host, err)
+		}
+		if username != tc.username || secret != tc.secret {
+			t.Errorf("Unexpected credentials for %s: '%s' '%s'", tc.host, username, secret)
+		}
+	}
+}
+
+func TestConfigInsecureRegistry(t *testing.T) {
+
+	conf := &Config{Registry: map[string]*Registry{
+		DefaultRegistryName: &Registry{Domain: DefaultRegistryDomain},
+		"dev":               &Registry{Domain: "localhost:5000", Insecure: true},
+	}}
+
+	if !conf.InsecureRegistry("localhost:5000") {
+		t.Errorf("Registry configured as insecure should be insecure")
+	}
+	if conf.InsecureRegistry(DefaultRegistryDomain) || conf.InsecureRegistry(DefaultRegistryHost) {
+		t.Errorf("Default registry should not be insecure")
+	}
+}
+
+func TestConfigRegistryMirrors(t *testing.T) {
+
+	conf := Config{Registry: map[string]*Registry{
+		"docker": &Registry{
+			Domain:  DefaultRegistryDomain,
+			Mirrors: []string{"https://mirror.example.com"},
+		},
+	}}
+	for _, host := range []string{DefaultRegistryDomain, DefaultRegistryHost} {
+		mirrors := conf.RegistryMirrors(host)
+		if len(mirrors) != 1 || mirrors[0] != "https://mirror.example.com" {
+			t.Errorf("Unexpected mirrors for %s: %v", host, mirrors)
+		}
+	}
+	if mirrors := conf.RegistryMirrors("localhost:5000"); mirrors != nil {
+		t.Errorf("Unexpected mirrors for unconfigured registry: %v", mirrors)
+	}
+}
+
+func TestConfigSetByName(t *testing.T) {
+
+	conf := &Config{}
+
+	old, path, err := conf.SetByName("runtime/socketname", "/run/test.sock")
+	if err != nil || path != "Runtime/SocketName" || old != "" {
+		t.Errorf("Failed to set string: '%s' '%s' %v", old, path, err)
+	}
+	if conf.Runtime.SocketName != "/run/test.sock" {
+		t.Errorf("String not set: '%s'", conf.Runtime.SocketName)
+	}
+
+	if _, _, err = conf.SetByName("runtime/createnamespace", "false"); err != nil ||
+		conf.Runtime.CreateNamespaceEnabled() {
+		t.Errorf("Failed to set bool: %v", err)
+	}
+	if old, _, err = conf.SetByName("runtime/createnamespace", "true"); err != nil ||
+		!conf.Runtime.CreateNamespaceEnabled() || old != "false" {
+		t.Errorf("Failed to set bool: '%s' %v", old, err)
+	}
+	if _, _, err = conf.SetByName("shortidlength", "8"); err != nil ||
+		conf.ShortIDLength != 8 {
+		t.Errorf("Failed to set int: %v", err)
+	}
+
+	if _, _, err = conf.SetByName("shortidlength", "many"); err == nil {
+		t.Errorf("Invalid int value accepted")
+	}
+	if _, _, err = conf.SetByName("runtime/namespace", "ns"); err == nil {
+		t.Errorf("Read-only field was set")
+	}
+	if _, _, err = conf.SetByName("hostenv", "x"); err == nil {
+		t.Errorf("Structure was set")
+	}
+	if _, _, err = conf.SetByName("runtime/unknown", "x"); err == nil {
+		t.Errorf("Unknown field was set")
+	}
+}
+
+func TestConfigUnsetByName(t *testing.T) {
+
+	conf := &Config{
+		Runtime: Runtime{Name: "containerd"},
+		Registry: map[string]*Registry{
+			"private": &Registry{Domain: "registry.example.com"},
+		},
+	}
+
+	path, err := conf.UnsetByName("runtime/name")
+	if err != nil || path != "Runtime/Name" || conf.Runtime.Name != "" {
+		t.Errorf("Failed to unset field: '%s' %v", path, err)
+	}
+
+	if _, err = conf.UnsetByName("registry/private"); err != nil {
+		t.Errorf("Failed to unset map entry: %v", err)
+	}
+	if _, ok := conf.Registry["private"]; ok {
+		t.Errorf("Map entry not removed")
+	}
+
+	if _, err = conf.UnsetByName("runtime/namespace"); err == nil {
+		t.Errorf("Read-only field was unset")
+	}
+	if _, err = conf.UnsetByName("runtime/unknown"); err == nil {
+		t.Errorf("Unknown field was unset")
+	}
+}
+
+func TestConfigSelectRuntimeProfile(t *testing.T) {
+
+	newConfig := func() *Config {
+		return &Config{
+			Runtime: Runtime{
+				Name:       DefaultExecRuntimeName,
+				SocketName: DefaultExecRuntimeSocketName,
+				Namespace:  DefaultExecRuntimeNamespace,
+			},
+			RuntimeProfiles: map[string]*Runtime{
+				"rootless": &Runtime{SocketName: "/run/user/1000/containerd.sock"},
+			},
+		}
+	}
+
+	conf := newConfig()
+	if err := conf.SelectRuntimeProfile(""); err != nil ||
+		conf.Runtime.SocketName != DefaultExecRuntimeSocketName {
+		t.Errorf("Default profile not selected: %v", err)
+	}
+
+	conf = newConfig()
+	if err := conf.SelectRuntimeProfile("rootless"); err != nil {
+		t.Fatalf("Failed to select profile: %v", err)
+	}
+	if conf.Runtime.SocketName != "/run/user/1000/containerd.sock" ||
+		conf.Runtime.Name != DefaultExecRuntimeName ||
+		conf.Runtime.Namespace != DefaultExecRuntimeNamespace {
+		t.Errorf("Unexpected runtime configuration %v", conf.Runtime)
+	}
+
+	conf = newConfig()
+	conf.DefaultRuntimeProfile = "rootless"
+	if err := conf.SelectRuntimeProfile(""); err != nil ||
+		conf.Runtime.SocketName != "/run/user/1000/containerd.sock" {
+		t.Errorf("Default runtime profile not used: %v", err)
+	}
+
+	// CreateNamespace is inherited unless the profile sets it
+	conf = newConfig()
+	conf.Runtime.CreateNamespace = newBool(false)
+	conf.RuntimeProfiles["enabled"] = &Runtime{CreateNamespace: newBool(true)}
+	if err := conf.SelectRuntimeProfile("rootless"); err != nil ||
+		conf.Runtime.CreateNamespaceEnabled() {
+		t.Errorf("Disabled namespace creation not inherited: %v", err)
+	}
+	conf = newConfig()
+	conf.Runtime.CreateNamespace = newBool(false)
+	conf.RuntimeProfiles["enabled"] = &Runtime{CreateNamespace: newBool(true)}
+	if err := conf.SelectRuntimeProfile("enabled"); err != nil ||
+		!conf.Runtime.CreateNamespaceEnabled() {
+		t.Errorf("Namespace creation of the profile not used: %v", err)
+	}
+
+	conf = newConfig()
+	if err := conf.SelectRuntimeProfile("unknown"); err == nil {
+		t.Errorf("Unknown profile selected")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+
+	runtimes := []string{"mock", "containerd"}
+	valid := Runtime{
+		Name:       "containerd",
+		SocketName: "/run/containerd/containerd.sock",
+		Namespace:  "cne",
+	}
+
+	tests := []struct {
+		update func(run *Runtime)
+		valid  bool
+	}{
+		{func(run *Runtime) {}, true},
+		{func(run *Runtime) { run.Name = "dockerd" }, false},
+		{func(run *Runtime) { run.SocketName = "" }, false},
+		{func(run *Runtime) { run.SocketName = "containerd.sock" }, false},
+		{func(run *Runtime) { run.Namespace = "" }, false},
+	}
+
+	for i, test := range tests {
+		conf := &Config{Runtime: valid}
+		test.update(&conf.Runtime)
+		err := conf.Validate(runtimes)
+		if test.valid && err != nil {
+			t.Errorf("Test %d: unexpected error: %v", i, err)
+		}
+		if !test.valid && !errors.Is(err, errdefs.ErrInvalidArgument) {
+			t.Errorf("Test %d: expected invalid argument, got %v", i, err)
+		}
+	}
+
+	conf := &Config{Runtime: valid}
+	conf.Runtime.Name = "dockerd"
+	if err := conf.Validate(runtimes); !strings.Contains(err.Error(), "containerd, mock") {
+		t.Errorf("Expected error to list the valid runtimes: %v", err)
+	}
+}
+
+func TestConfigProblems(t *testing.T) {
+
+	socket, err := ioutil.TempFile("", "cne-socket-")
+	if err != nil {
+		t.Fatalf("Failed to create socket file: %v", err)
+	}
+	socket.Close()
+	defer os.Remove(socket.Name())
+
+	runtimes := []string{"mock", "containerd"}
+	conf := &Config{
+		Runtime: Runtime{Name: "containerd", SocketName: socket.Name(), Namespace: "cne"},
+		RuntimeProfiles: map[string]*Runtime{
+			"test": {Name: "mock", Namespace: "test", SocketName: "/run/mock.sock"},
+		},
+		Registry: map[string]*Registry{
+			"docker.io": {Domain: "docker.io", RepoName: "library"},
+			"local":     {Domain: "localhost:5000", Mirrors: []string{"http://localhost:5001"}},
+		},
+	}
+	if problems := conf.Problems(runtimes); len(problems) != 0 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+
+	conf.Runtime.Name = "dockerd"
+	conf.Runtime.SocketName = "/nonexistent/containerd.sock"
+	conf.RuntimeProfiles["other"] = &Runtime{Name: "containerd", SocketName: "/run/mock.sock"}
+	conf.DefaultRuntimeProfile = "missing"
+	conf.Registry["scheme"] = &Registry{Domain: "https://example.com"}
+	conf.Registry["path"] = &Registry{Domain: "example.com/path"}
+	conf.Registry["mirror"] = &Registry{Domain: "example.org", Mirrors: []string{"example.net"}}
+
+	problems := conf.Problems(runtimes)
+	expected := []string{
+		"invalid runtime 'dockerd'",
+		"runtime socket '/nonexistent/containerd.sock' not found",
+		"runtime profiles 'other' and 'test' use socket '/run/mock.sock'",
+		"default runtime profile 'missing' not found",
+		"registry 'mirror' mirror 'example.net' must be an http or https URL",
+		"registry 'path' domain 'example.com/path' is not a valid host",
+		"registry 'scheme' domain 'https://example.com' must not include a scheme",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, p := range problems {
+		if !strings.HasPrefix(p.Error(), expected[i]) {
+			t.Errorf("Expected problem '%s', got '%v'", expected[i], p)
+		}
+	}
+}
+
+func TestConfigApplyEnv(t *testing.T) {
+
+	conf := &Config{
+		Runtime: Runtime{Name: "containerd", SocketName: "/run/containerd.sock", Namespace: "cne"},
+		Registry: map[string]*Registry{
+			"docker.io": {Domain: "docker.io", RepoName: "library"},
+		},
+		ShortIDLength: 12,
+	}
+
+	environ := map[string]string{
+		"CNE_RUNTIME_SOCKETNAME":          "/run/test.sock",
+		"CNE_RUNTIME_NAMESPACE":           "test",
+		"CNE_RUNTIME_PLUGIN":              "",
+		"CNE_REGISTRY_DOCKER_IO_REPONAME": "mirror",
+		"CNE_REGISTRY_OTHER_DOMAIN":       "other.io",
+		"CNE_HOSTENV_DENY":                "*_TOKEN, *_KEY",
+		"CNE_SHORTIDLENGTH":               "8",
+	}
+	lookup := func(name string) (string, bool) {
+		val, ok := environ[name]
+		return val, ok
+	}
+
+	if err := conf.applyEnv(lookup); err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+	expected := Runtime{Name: "containerd", SocketName: "/run/test.sock", Namespace: "test"}
+	if conf.Runtime != expected {
+		t.Errorf("Expected runtime %v, got %v", expected, conf.Runtime)
+	}
+	if conf.Registry["docker.io"].RepoName != "mirror" || len(conf.Registry) != 1 {
+		t.Errorf("Unexpected registries: %v", conf.Registry)
+	}
+	if !reflect.DeepEqual(conf.HostEnv.Deny, []string{"*_TOKEN", "*_KEY"}) {
+		t.Errorf("Unexpected host environment deny list: %v", conf.HostEnv.Deny)
+	}
+	if conf.ShortIDLength != 8 {
+		t.Errorf("Expected short ID length 8, got %d", conf.ShortIDLength)
+	}
+
+	environ = map[string]string{"CNE_RUNTIME_CREATENAMESPACE": "false"}
+	if err := conf.applyEnv(lookup); err != nil || conf.Runtime.CreateNamespaceEnabled() {
+		t.Errorf("Failed to disable namespace creation: %v", err)
+	}
+
+	environ = map[string]string{"CNE_SHORTIDLENGTH": "short"}
+	if err := conf.applyEnv(lookup); !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Expected invalid argument for invalid value, got %v", err)
+	}
+}
+
+func TestConfigSetUserConfigFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cne-config-")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetUserConfigFile("")
+
+	path := dir + "/config"
+	err = SetUserConfigFile(path)
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error for missing file, got %v", err)
+	}
+
+	err = ioutil.WriteFile(path, []byte("[Runtime]\nNamespace = \"alt\"\n"), UserConfigFilePerms)
+	if err != nil {
+		t.Fatalf("Failed to write configuration file: %v", err)
+	}
+	if err = SetUserConfigFile(path); err != nil {
+		t.Fatalf("Failed to set configuration file: %v", err)
+	}
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if conf.Runtime.Namespace != "alt" || conf.Runtime.Name != DefaultExecRuntimeName {
+		t.Errorf("Configuration file not layered above the defaults: %v", conf.Runtime)
+	}
+
+	userConf, err := LoadUserConfig()
+	if err != nil || userConf.Runtime.Namespace != "alt" || userConf.Runtime.Name != "" {
+		t.Errorf("Unexpected user configuration: %v: %v", userConf.Runtime, err)
+	}
+
+	userConf.ShortIDLength = 8
+	if err = userConf.WriteUserConfig(); err != nil {
+		t.Fatalf("Failed to write user configuration: %v", err)
+	}
+	if conf, _ = Load(); conf.ShortIDLength != 8 {
+		t.Errorf("User configuration not written to the configuration file")
+	}
+
+	os.Remove(path)
+	if _, err = Load(); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error for removed file, got %v", err)
+	}
+}
+
+func TestConfigCreateNamespace(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cne-config-")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"config", "config.yaml"} {
+		path := dir + "/" + name
+
+		conf := &Config{Runtime: Runtime{Name: "containerd", CreateNamespace: newBool(false)}}
+		var buf bytes.Buffer
+		if err := conf.encode(&buf, path); err != nil {
+			t.Fatalf("Failed to encode configuration: %v", err)
+		}
+		ioutil.WriteFile(path, buf.Bytes(), UserConfigFilePerms)
+
+		loaded := &Config{Runtime: Runtime{CreateNamespace: newBool(true)}}
+		if err := loaded.update(path); err != nil {
+			t.Fatalf("Failed to read configuration %s: %v", name, err)
+		}
+		if loaded.Runtime.CreateNamespaceEnabled() {
+			t.Errorf("Disabled namespace creation not preserved in %s:\n%s", name, buf.String())
+		}
+	}
+
+	if !(&Runtime{}).CreateNamespaceEnabled() {
+		t.Errorf("Namespace creation should be enabled if not configured")
+	}
+}
+
+func TestConfigYAML(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cne-config-")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const tomlConfig = `DefaultRuntimeProfile = "test"
+ShortIDLength = 8
+
+[Runtime]
+  Name = "containerd"
+  SocketName = "/run/containerd/containerd.sock"
+  Namespace = "cne"
+
+[RuntimeProfiles]
+  [RuntimeProfiles.test]
+    Namespace = "test"
+
+[Registry]
+  [Registry."docker.io"]
+    Domain = "docker.io"
+    RepoName = "library"
+  [Registry.local]
+    Domain = "localhost:5000"
+    Insecure = true
+    [Registry.local.Auth]
+      Username = "user"
+      Password = "secret"
+`
+	const yamlConfig = `runtime:
+  name: containerd
+  socketname: /run/containerd/containerd.sock
+  namespace: cne
+runtimeprofiles:
+  test:
+    namespace: test
+defaultruntimeprofile: test
+registry:
+  docker.io:
+    domain: docker.io
+    reponame: library
+  local:
+    domain: localhost:5000
+    auth:
+      username: user
+      password: secret
+    insecure: true
+shortidlength: 8
+`
+	tomlPath := dir + "/config"
+	yamlPath := dir + "/config.yaml"
+	ioutil.WriteFile(tomlPath, []byte(tomlConfig), UserConfigFilePerms)
+	ioutil.WriteFile(yamlPath, []byte(yamlConfig), UserConfigFilePerms)
+
+	tomlConf := &Config{}
+	if err := tomlConf.update(tomlPath); err != nil {
+		t.Fatalf("Failed to read TOML configuration: %v", err)
+	}
+	yamlConf := &Config{}
+	if err := yamlConf.update(yamlPath); err != nil {
+		t.Fatalf("Failed to read YAML configuration: %v", err)
+	}
+	if !reflect.DeepEqual(tomlConf, yamlConf) {
+		t.Errorf("TOML and YAML configurations differ:\n%+v\n%+v", tomlConf, yamlConf)
+	}
+
+	for _, path := range []string{tomlPath, yamlPath} {
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("Failed to create configuration file: %v", err)
+		}
+		err = yamlConf.encode(file, path)
+		file.Close()
+		if err != nil {
+			t.Fatalf("Failed to write configuration '%s': %v", path, err)
+		}
+
+		conf := &Config{}
+		if err := conf.update(path); err != nil {
+			t.Fatalf("Failed to read configuration '%s': %v", path, err)
+		}
+		if !reflect.DeepEqual(conf, yamlConf) {
+			t.Errorf("Configuration '%s' doesn't round-trip:\n%+v", path, conf)
+		}
+	}
+}
@@ -1,17 +1,33 @@
 package config
 
 const (
-	UserConfigFile   = ".cneconfig"
-	SystemConfigFile = "/etc/cneconfig"
-	ConfigFilePerms  = 0644
+	UserConfigFile      = ".cneconfig"
+	SystemConfigFile    = "/etc/cneconfig"
+	ConfigFilePerms     = 0644
+	UserConfigFilePerms = 0600
+
+	// EnvPrefix is the prefix of the environment variables that override the configuration
+	EnvPrefix = "CNE"
 
 	DefaultPackageVersion = "latest"
 
 	DefaultExecRuntimeName       = "containerd"
 	DefaultExecRuntimeSocketName = "/run/containerd/containerd.sock"
 	DefaultExecRuntimeNamespace  = "cne"
+	DefaultExecRuntimePlugin     = "io.containerd.runc.v2"
+
+	DefaultPullRetryAttempts = 4
+	DefaultPullRetryDelay    = 500 // milliseconds
+
+	DefaultRuntimeProfileName = "default"
 
 	DefaultRegistryName     = "docker.io"
 	DefaultRegistryDomain   = "docker.io"
 	DefaultRegistryRepoName = "library"
+	DefaultRegistryHost     = "registry-1.docker.io"
+
+	DefaultShortIDLength = 12
 )
+
+// DefaultHostEnvDeny lists the host environment variables that are not passed into the container
+var DefaultHostEnvDeny = []string{"*_TOKEN", "*_SECRET", "*_PASSWORD"}
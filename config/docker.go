@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+const (
+	dockerConfigFile      = "config.json"
+	dockerConfigDir       = ".docker"
+	dockerHubIndexServer  = "https://index.docker.io/v1/"
+	dockerHelperPrefix    = "docker-credential-"
+	dockerHelperTokenUser = "<token>"
+)
+
+// dockerAuth is an entry of the auths section in the Docker configuration.
+type dockerAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// dockerConfig describes the credential related parts of the Docker configuration.
+type dockerConfig struct {
+	Auths       map[string]dockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore"`
+	CredHelpers map[string]string     `json:"credHelpers"`
+}
+
+// dockerConfigPath returns the path of the Docker configuration of the current user.
+func dockerConfigPath() (string, error) {
+
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, dockerConfigFile), nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(usr.HomeDir, dockerConfigDir, dockerConfigFile), nil
+}
+
+// loadDockerConfig loads the Docker configuration from the path. It returns an empty
+// configuration if the file doesn't exist.
+func loadDockerConfig(path string) (*dockerConfig, error) {
+
+	dockerConf := &dockerConfig{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && os.IsNotExist(err) {
+		return dockerConf, nil
+	}
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to read docker configuration '%s'", path)
+	}
+	err = json.Unmarshal(data, dockerConf)
+	if err != nil {
+		return nil, errdefs.InvalidArgument("docker configuration '%s' corrupt", path)
+	}
+	return dockerConf, nil
+}
+
+// dockerServerHost returns the registry host for a server entry of the Docker configuration,
+// which can be a host name or URL.
+func dockerServerHost(server string) string {
+
+	if server == dockerHubIndexServer || server == "index.docker.io" {
+		return DefaultRegistryHost
+	}
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	return RegistryHost(strings.SplitN(server, "/", 2)[0])
+}
+
+// helperCredentials runs the credential helper for the server and returns the credentials.
+func helperCredentials(helper, server string) (string, string, error) {
+
+	var out bytes.Buffer
+	cmd := exec.Command(dockerHelperPrefix+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if err != nil {
+		// helpers fail for servers without stored credentials
+		return "", "", nil
+	}
+
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	err = json.Unmarshal(out.Bytes(), &creds)
+	if err != nil {
+		return "", "", errdefs.InvalidArgument("invalid output of credential helper '%s'",
+			dockerHelperPrefix+helper)
+	}
+	if creds.Username == dockerHelperTokenUser {
+		return "", creds.Secret, nil
+	}
+	return creds.Username, creds.Secret, nil
+}
+
+// credentials returns the credentials for the registry host from the credential helpers or
+// the auths section of the Docker configuration. Credential helpers take precedence.
+func (dockerConf *dockerConfig) credentials(host string) (string, string, error) {
+
+	for server, helper := range dockerConf.CredHelpers {
+		if dockerServerHost(server) == host {
+			return helperCredentials(helper, server)
+		}
+	}
+
+	for server, auth := range dockerConf.Auths {
+		if dockerServerHost(server) != host {
+			continue
+		}
+		if dockerConf.CredsStore != "" {
+			return helperCredentials(dockerConf.CredsStore, server)
+		}
+		if auth.IdentityToken != "" {
+			return "", auth.IdentityToken, nil
+		}
+		if auth.Auth != "" {
+			data, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return "", "", errdefs.InvalidArgument("invalid auth for '%s'", server)
+			}
+			userPass := strings.SplitN(string(data), ":", 2)
+			if len(userPass) != 2 {
+				return "", "", errdefs.InvalidArgument("invalid auth for '%s'", server)
+			}
+			return userPass[0], userPass[1], nil
+		}
+		return auth.Username, auth.Password, nil
+	}
+	return "", "", nil
+}
+
+// DockerCredentials returns the username and secret for the registry host from the Docker
+// configuration of the current user. Both values are empty if no credentials are found.
+func DockerCredentials(host string) (string, string, error) {
+
+	path, err := dockerConfigPath()
+	if err != nil {
+		return "", "", nil
+	}
+	dockerConf, err := loadDockerConfig(path)
+	if err != nil {
+		return "", "", err
+	}
+	return dockerConf.credentials(host)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDockerConfigCredentials(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cne-docker-test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// credential helper that only knows the helper.example.com server
+	helper := `#!/bin/sh
+read server
+if [ "$server" = "helper.example.com" ]; then
+	echo '{"ServerURL":"helper.example.com","Username":"helper","Secret":"helpersecret"}'
+else
+	echo "credentials not found" >&2
+	exit 1
+fi
+`
+	err = ioutil.WriteFile(filepath.Join(dir, dockerHelperPrefix+"test"), []byte(helper), 0755)
+	if err != nil {
+		t.Fatalf("Failed to write credential helper: %v", err)
+	}
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+oldPath)
+	defer os.Setenv("PATH", oldPath)
+
+	conf := `{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "dXNlcjpzZWNyZXQ="},
+			"token.example.com": {"identitytoken": "token"}
+		},
+		"credHelpers": {
+			"helper.example.com": "test",
+			"missing.example.com": "test"
+		}
+	}`
+	path := filepath.Join(dir, dockerConfigFile)
+	err = ioutil.WriteFile(path, []byte(conf), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write docker configuration: %v", err)
+	}
+
+	dockerConf, err := loadDockerConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load docker configuration: %v", err)
+	}
+
+	tests := []struct {
+		host     string
+		username string
+		secret   string
+	}{
+		{DefaultRegistryHost, "user", "secret"},
+		{"token.example.com", "", "token"},
+		{"helper.example.com", "helper", "helpersecret"},
+		{"missing.example.com", "", ""},
+		{"unknown.example.com", "", ""},
+	}
+	for _, tc := range tests {
+		username, secret, err := dockerConf.credentials(tc.host)
+		if err != nil {
+			t.Fatalf("Failed to get credentials for %s: %v", tc.host, err)
+		}
+		if username != tc.username || secret != tc.secret {
+			t.Errorf("Unexpected credentials for %s: '%s' '%s'", tc.host, username, secret)
+		}
+	}
+
+	dockerConf, err = loadDockerConfig(filepath.Join(dir, "missing.json"))
+	if err != nil || len(dockerConf.Auths) != 0 {
+		t.Errorf("Missing docker configuration should be empty: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaURL is the JSON Schema version of the configuration schema.
+const SchemaURL = "http://json-schema.org/draft-07/schema#"
+
+// Schema describes a JSON Schema for the configuration.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+}
+
+// ConfigSchema returns the JSON Schema of the configuration file in the YAML or TOML format.
+// The name of the runtimes are the valid values for the runtime names. Read-only fields are
+// marked as readOnly and fields that are not shown, such as passwords, as writeOnly.
+func ConfigSchema(runtimes []string, yamlKeys bool) *Schema {
+
+	names := append([]string{}, runtimes...)
+	sort.Strings(names)
+
+	schema := typeSchema(reflect.TypeOf(Config{}), names, yamlKeys)
+	schema.Schema = SchemaURL
+	schema.Title = "cne configuration"
+	return schema
+}
+
+// schemaKey returns the key of the field in the configuration file.
+func schemaKey(field reflect.StructField, yamlKeys bool) string {
+
+	if !yamlKeys {
+		if name := strings.Split(field.Tag.Get("toml"), ",")[0]; name != "" {
+			return name
+		}
+		return field.Name
+	}
+	if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// typeSchema returns the schema for the type. Nested structures, maps, and slices are
+// described recursively.
+func typeSchema(typ reflect.Type, runtimes []string, yamlKeys bool) *Schema {
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return typeSchema(typ.Elem(), runtimes, yamlKeys)
+	case reflect.Struct:
+		schema := &Schema{
+			Type:                 "object",
+			Properties:           map[string]*Schema{},
+			AdditionalProperties: false,
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			prop := typeSchema(field.Type, runtimes, yamlKeys)
+			prop.ReadOnly = field.Tag.Get("cne") == "ReadOnly"
+			prop.WriteOnly = field.Tag.Get("output") == "-"
+			if typ == reflect.TypeOf(Runtime{}) && field.Name == "Name" {
+				prop.Enum = runtimes
+			}
+			schema.Properties[schemaKey(field, yamlKeys)] = prop
+		}
+		return schema
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: typeSchema(typ.Elem(), runtimes, yamlKeys),
+		}
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:  "array",
+			Items: typeSchema(typ.Elem(), runtimes, yamlKeys),
+		}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	}
+	return &Schema{}
+}
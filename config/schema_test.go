@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+
+	schema := ConfigSchema([]string{"mock", "containerd"}, true)
+	if schema.Schema != SchemaURL || schema.Type != "object" {
+		t.Errorf("Unexpected schema header: %v", schema)
+	}
+
+	run := schema.Properties["runtime"]
+	if run == nil || run.Type != "object" {
+		t.Fatalf("Missing runtime in schema: %v", schema.Properties)
+	}
+	if !reflect.DeepEqual(run.Properties["name"].Enum, []string{"containerd", "mock"}) {
+		t.Errorf("Expected runtime names as enum, got %v", run.Properties["name"].Enum)
+	}
+	if !run.Properties["namespace"].ReadOnly {
+		t.Errorf("Namespace should be read-only")
+	}
+	if run.Properties["createnamespace"].Type != "boolean" {
+		t.Errorf("Expected boolean for createnamespace")
+	}
+
+	profiles := schema.Properties["runtimeprofiles"]
+	if profiles.Type != "object" ||
+		!reflect.DeepEqual(profiles.AdditionalProperties, run) {
+		t.Errorf("Expected runtime profiles as map of runtimes: %v", profiles)
+	}
+
+	reg, ok := schema.Properties["registry"].AdditionalProperties.(*Schema)
+	if !ok || !reg.Properties["auth"].Properties["password"].WriteOnly {
+		t.Errorf("Registry password should be write-only")
+	}
+	if schema.Properties["hostenv"].Properties["deny"].Items.Type != "string" {
+		t.Errorf("Expected string list for hostenv/deny")
+	}
+	if schema.Properties["shortidlength"].Type != "integer" {
+		t.Errorf("Expected integer for shortidlength")
+	}
+
+	schema = ConfigSchema([]string{"containerd"}, false)
+	if _, ok := schema.Properties["Runtime"].Properties["SocketName"]; !ok {
+		t.Errorf("Expected TOML keys, got %v", schema.Properties)
+	}
+}
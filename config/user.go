@@ -59,7 +59,7 @@ func CurrentUser() (User, error) {
 
 	groupname := username
 	group, err := user.LookupGroupId(strconv.Itoa(gid))
-	if err != nil {
+	if err == nil {
 		groupname = group.Name
 	}
 
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"os/user"
+	"strconv"
+	"testing"
+)
+
+func TestCurrentUserGroupname(t *testing.T) {
+
+	usr, err := CurrentUser()
+	if err != nil {
+		t.Fatalf("Failed to get current user: %v", err)
+	}
+
+	group, err := user.LookupGroupId(strconv.Itoa(int(usr.GID)))
+	if err != nil {
+		t.Skipf("No group for gid %d: %v", usr.GID, err)
+	}
+	if usr.Groupname != group.Name {
+		t.Errorf("Groupname '%s' doesn't match group '%s'", usr.Groupname, group.Name)
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/containerd/console"
 	runspecs "github.com/opencontainers/runtime-spec/specs-go"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 
@@ -24,7 +26,7 @@ import (
 const MaxProgressOutputLength = 80
 
 type ContainerInterface interface {
-	Create() error
+	Create() ([]string, error)
 	Delete() error
 	Purge() error
 	Build(ws *project.Workspace, nextLayerIdx int,
@@ -32,8 +34,9 @@ type ContainerInterface interface {
 		progress chan []runtime.ProgressStatus, stream runtime.Stream) error
 	BuildExec(user *config.User, stream runtime.Stream,
 		args []string, env []string) (uint32, error)
+	PostBuild(ws *project.Workspace, user *config.User, stream runtime.Stream) error
 	Amend(ws *project.Workspace, bldLayerIdx int) error
-	Commit(ws *project.Workspace, user config.User, rootPath string) error
+	Commit(conf *config.Config, ws *project.Workspace, user config.User, rootPath string) error
 }
 
 type Container struct {
@@ -42,7 +45,9 @@ type Container struct {
 	Namespace    string
 	Name         string
 	Domain       [16]byte
+	DomainName   string
 	ID           [16]byte
+	RuntimeID    string
 	Generation   [16]byte
 	UID          uint32
 	CreatedAt    time.Time
@@ -67,22 +72,35 @@ func containerNameRunCtr(runCtr runtime.Container) string {
 	return containerName(dom, cid, gen)
 }
 
-// Containers returns all active containers in the project.
-func Containers(run runtime.Runtime, prj *project.Project, user *config.User) ([]Container, error) {
+// domainName is a helper function returning the name of the domain of a runtime Container
+// or the hex encoded domain if the runtime doesn't know the name.
+func domainName(runCtr runtime.Container) string {
+
+	if name := runCtr.DomainName(); name != "" {
+		return name
+	}
+	dom := runCtr.Domain()
+	return hex.EncodeToString(dom[:])
+}
+
+// Containers returns all active containers in the project and the warnings of the runtime for
+// containers that cannot be read.
+func Containers(run runtime.Runtime,
+	prj *project.Project, user *config.User) ([]Container, []string, error) {
 
 	var domain [16]byte
 	var err error
 	if prj != nil {
 		domain, err = uuid.Parse(prj.UUID)
 		if err != nil {
-			return nil, errdefs.InvalidArgument("invalid project UUID: '%v'", prj.UUID)
+			return nil, nil, errdefs.InvalidArgument("invalid project UUID: '%v'", prj.UUID)
 		}
 	}
 
 	var ctrs []Container
-	runCtrs, err := run.Containers()
+	runCtrs, warnings, err := run.Containers()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, c := range runCtrs {
@@ -100,6 +118,8 @@ func Containers(run runtime.Runtime, prj *project.Project, user *config.User) ([
 			runContainer: c,
 			Name:         containerNameRunCtr(c),
 			Domain:       dom,
+			DomainName:   domainName(c),
+			RuntimeID:    c.RuntimeID(),
 			ID:           cid,
 			Generation:   c.Generation(),
 			UID:          c.UID(),
@@ -107,11 +127,18 @@ func Containers(run runtime.Runtime, prj *project.Project, user *config.User) ([
 		})
 	}
 
-	return ctrs, nil
+	return ctrs, warnings, nil
 }
 
 // Get looks up the current active Container for the specified Workspace.
 func Get(run runtime.Runtime, ws *project.Workspace) (*Container, error) {
+	return GetGeneration(run, ws, ws.ConfigHash())
+}
+
+// GetGeneration returns the container of the workspace with the provided generation, which
+// can be a prior generation of the workspace container if it still exists.
+func GetGeneration(run runtime.Runtime,
+	ws *project.Workspace, gen [16]byte) (*Container, error) {
 
 	dom, err := uuid.Parse(ws.ProjectUUID)
 	if err != nil {
@@ -120,7 +147,6 @@ func Get(run runtime.Runtime, ws *project.Workspace) (*Container, error) {
 	}
 
 	cid := ws.ID()
-	gen := ws.ConfigHash()
 	runCtr, err := run.GetContainer(dom, cid, gen)
 	if err != nil {
 		return nil, err
@@ -133,6 +159,8 @@ func Get(run runtime.Runtime, ws *project.Workspace) (*Container, error) {
 		Namespace:    run.Namespace(),
 		Name:         name,
 		Domain:       runCtr.Domain(),
+		DomainName:   domainName(runCtr),
+		RuntimeID:    runCtr.RuntimeID(),
 		ID:           cid,
 		Generation:   gen,
 		UID:          runCtr.UID(),
@@ -140,9 +168,55 @@ func Get(run runtime.Runtime, ws *project.Workspace) (*Container, error) {
 	}, nil
 }
 
+// Find looks up the container of the Workspace regardless of its generation. If more than one
+// generation exists, it returns the most recently created one.
+func Find(run runtime.Runtime, ws *project.Workspace) (*Container, error) {
+
+	var dom [16]byte
+	dom, err := uuid.Parse(ws.ProjectUUID)
+	if err != nil {
+		return nil, errdefs.InvalidArgument(
+			"invalid project UUID in workspace: '%v'", ws.ProjectUUID)
+	}
+
+	// a container that cannot be read isn't found, so the warnings are ignored
+	runCtrs, _, err := run.Containers(dom)
+	if err != nil {
+		return nil, err
+	}
+
+	cid := ws.ID()
+	var runCtr runtime.Container
+	for _, c := range runCtrs {
+		if c.ID() != cid {
+			continue
+		}
+		if runCtr == nil || c.CreatedAt().After(runCtr.CreatedAt()) {
+			runCtr = c
+		}
+	}
+	if runCtr == nil {
+		return nil, errdefs.NotFound("container", ws.Name)
+	}
+
+	return &Container{
+		runRuntime:   run,
+		runContainer: runCtr,
+		Namespace:    run.Namespace(),
+		Name:         containerNameRunCtr(runCtr),
+		Domain:       dom,
+		DomainName:   domainName(runCtr),
+		RuntimeID:    runCtr.RuntimeID(),
+		ID:           cid,
+		Generation:   runCtr.Generation(),
+		UID:          runCtr.UID(),
+		CreatedAt:    runCtr.CreatedAt(),
+	}, nil
+}
+
 // NewContainer defines a new Container with a default generation value for the Workspace without
 // the Layer configuration. The generation value will be updated through Commit().
-func NewContainer(run runtime.Runtime, user *config.User,
+func NewContainer(run runtime.Runtime, conf *config.Config, user *config.User,
 	ws *project.Workspace, img runtime.Image) (*Container, error) {
 
 	dom, err := uuid.Parse(ws.ProjectUUID)
@@ -159,8 +233,14 @@ func NewContainer(run runtime.Runtime, user *config.User,
 	if err != nil {
 		return nil, err
 	}
+	spec.Mounts = append(spec.Mounts, mountSpecs(conf.DefaultMounts, ws.Mounts, user)...)
+	spec.Process.Env = append(spec.Process.Env, ws.EnvList()...)
+	err = applyResources(&spec, ws.Resources)
+	if err != nil {
+		return nil, err
+	}
 
-	runCtr, err := run.NewContainer(dom, cid, gen, user.UID, img, &spec)
+	runCtr, err := run.NewContainer(dom, cid, gen, user.UID, ws.ProjectName, img, &spec)
 	if err != nil {
 		return nil, err
 	}
@@ -171,13 +251,16 @@ func NewContainer(run runtime.Runtime, user *config.User,
 		Namespace:    run.Namespace(),
 		Name:         ctrName,
 		Domain:       dom,
+		DomainName:   ws.ProjectName,
+		RuntimeID:    runCtr.RuntimeID(),
 		ID:           cid,
 		Generation:   gen,
 	}, nil
 }
 
 // Create creates the container after it has been defined and before it can be built.
-func (ctr *Container) Create() error {
+// It returns a list of warnings for conditions that don't prevent the creation.
+func (ctr *Container) Create() ([]string, error) {
 
 	runCtr := ctr.runContainer
 	return runCtr.Create()
@@ -237,6 +320,13 @@ func (ctr *Container) Build(ws *project.Workspace, nextLayerIdx int,
 		return err
 	}
 
+	// record the use of the cached layer snapshots for pruning the cache
+	for i := 0; i < bldLayerIdx; i++ {
+		if digest := ws.Environment.Layers[i].Digest; digest != "" {
+			ctr.runRuntime.TouchSnapshot(digest) // ignore error
+		}
+	}
+
 	// prep the progress status updates
 	defer func() {
 		if progress != nil {
@@ -282,7 +372,11 @@ func (ctr *Container) Build(ws *project.Workspace, nextLayerIdx int,
 	for ; bldLayerIdx < nextLayerIdx; bldLayerIdx++ {
 
 		layer := &ws.Environment.Layers[bldLayerIdx]
-		for _, command := range layer.Commands {
+		commands := layer.Commands
+		if layer.Disabled {
+			commands = nil
+		}
+		for _, command := range commands {
 
 			args, err := expandLine(command.Args, vars)
 			if err != nil {
@@ -305,7 +399,8 @@ func (ctr *Container) Build(ws *project.Workspace, nextLayerIdx int,
 				progress <- stat
 			}
 
-			code, err := ctr.BuildExec(user, stream, args, command.Envs)
+			envs := append(ws.EnvList(), command.Envs...)
+			code, err := ctr.BuildExec(user, stream, args, envs)
 			if code != 0 {
 				err = errdefs.CommandFailed(args)
 			}
@@ -337,8 +432,11 @@ func (ctr *Container) Build(ws *project.Workspace, nextLayerIdx int,
 	return nil
 }
 
-// Commit commits a container that has been built and updates its configuration
-func (ctr *Container) Commit(ws *project.Workspace, user config.User, rootPath string) error {
+// UpdateSpec updates the container spec with the mounts, environment variables, and
+// resource limits of the workspace. The changes take effect when the main process of the
+// container is started the next time.
+func (ctr *Container) UpdateSpec(conf *config.Config,
+	ws *project.Workspace, user config.User) error {
 
 	spec, err := DefaultSpec(ctr.Namespace, ctr.Name)
 	if err != nil {
@@ -351,8 +449,21 @@ func (ctr *Container) Commit(ws *project.Workspace, user config.User, rootPath s
 		Source:      user.HomeDir,
 		Options:     []string{"rbind"},
 	})
+	spec.Mounts = append(spec.Mounts, mountSpecs(conf.DefaultMounts, ws.Mounts, &user)...)
+	spec.Process.Env = append(spec.Process.Env, ws.EnvList()...)
+	err = applyResources(&spec, ws.Resources)
+	if err != nil {
+		return err
+	}
 
-	err = ctr.runContainer.UpdateSpec(&spec)
+	return ctr.runContainer.UpdateSpec(&spec)
+}
+
+// Commit commits a container that has been built and updates its configuration
+func (ctr *Container) Commit(conf *config.Config,
+	ws *project.Workspace, user config.User, rootPath string) error {
+
+	err := ctr.UpdateSpec(conf, ws, user)
 	if err != nil {
 		return err
 	}
@@ -368,6 +479,133 @@ func (ctr *Container) Commit(ws *project.Workspace, user config.User, rootPath s
 	return nil
 }
 
+// Checkpoint commits the current filesystem of the container as a snapshot and records the
+// commit with the provided message in the workspace. The container is updated to the new
+// generation of the workspace.
+func (ctr *Container) Checkpoint(ws *project.Workspace, message string) error {
+
+	runCtr := ctr.runContainer
+	snap, err := runCtr.Snapshot()
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return errdefs.NotFound("snapshot", ctr.Name)
+	}
+
+	commits := ws.Environment.Commits
+	ws.Environment.Commits = append(commits, project.Commit{
+		Message:   message,
+		Snapshot:  snap.Name(),
+		CreatedAt: time.Now(),
+	})
+
+	gen := ws.ConfigHash()
+	err = runCtr.Commit(gen)
+	if err != nil {
+		ws.Environment.Commits = commits
+		return err
+	}
+
+	ctr.Generation = gen
+	return nil
+}
+
+// CreateSnapshot commits the current filesystem of the container as a snapshot and records it
+// with the provided name and the current generation of the container in the workspace.
+func (ctr *Container) CreateSnapshot(ws *project.Workspace, name string) error {
+
+	if _, err := ws.FindSnapshot(name); err == nil {
+		return errdefs.AlreadyExists("snapshot", name)
+	}
+
+	snap, err := ctr.runContainer.Snapshot()
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return errdefs.NotFound("snapshot", ctr.Name)
+	}
+
+	return ws.AddSnapshot(name, ctr.Generation, snap.Name())
+}
+
+// RestoreSnapshot restores the filesystem and the generation of the container, and the
+// environment of the workspace from the named snapshot.
+func (ctr *Container) RestoreSnapshot(ws *project.Workspace, name string) error {
+
+	wsSnap, err := ws.FindSnapshot(name)
+	if err != nil {
+		return err
+	}
+
+	var gen [16]byte
+	b, err := hex.DecodeString(wsSnap.Generation)
+	if err != nil || len(b) != len(gen) {
+		return errdefs.InvalidArgument("invalid generation in snapshot '%s'", name)
+	}
+	copy(gen[:], b)
+
+	snap, err := ctr.runRuntime.GetSnapshot(wsSnap.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	err = ctr.runContainer.Restore(snap, gen)
+	if err != nil {
+		return err
+	}
+
+	ctr.Generation = gen
+	return ws.RestoreSnapshot(name)
+}
+
+// Rollback reverts the container to the generation that was committed before the current
+// generation. Commits that were recorded in the workspace after that generation are removed,
+// so the configuration of the workspace matches the container again.
+func (ctr *Container) Rollback(ws *project.Workspace) error {
+
+	gen, err := ctr.runContainer.Rollback()
+	if err != nil {
+		return err
+	}
+	ctr.Generation = gen
+
+	commits := ws.Environment.Commits
+	for i := len(commits); i >= 0; i-- {
+		ws.Environment.Commits = commits[:i]
+		if ws.ConfigHash() == gen {
+			return nil
+		}
+	}
+
+	// the workspace was changed otherwise and the container needs to be rebuilt
+	ws.Environment.Commits = commits
+	return nil
+}
+
+// PostBuild executes the post-build command of the workspace after the container was built.
+// The command runs with the same user and environment variables as the build commands. A
+// non-zero exit code fails with a command failed error unless the workspace is configured to
+// ignore post-build errors.
+func (ctr *Container) PostBuild(ws *project.Workspace, user *config.User,
+	stream runtime.Stream) error {
+
+	args := ws.PostBuild.Args
+	if len(args) == 0 {
+		return nil
+	}
+
+	code, err := ctr.BuildExec(user, stream, args, ws.EnvList())
+	if err != nil {
+		return err
+	}
+	if code != 0 && !ws.PostBuild.IgnoreErrors {
+		return errdefs.CommandFailed(args)
+	}
+	return nil
+}
+
 // Amend updates the current snapshot
 func (ctr *Container) Amend(ws *project.Workspace, bldLayerIdx int) error {
 
@@ -384,17 +622,26 @@ func (ctr *Container) Amend(ws *project.Workspace, bldLayerIdx int) error {
 
 // Exec excutes the provided command, using the default proces runtime spec.
 // The user defines the current working directory and UID and GID.
-// It uses the default environment from the calling process.
+// The environment consists of the default environment, the provided envs, and the variables
+// required by the workspace.
 // I/O is defined by the provided stream.
+// The optional resources override the resource limits of the container for this process.
 // The container must be started before calling this function
-func (ctr *Container) Exec(user *config.User, stream runtime.Stream, args []string) (uint32, error) {
+func (ctr *Container) Exec(ws *project.Workspace, user *config.User, stream runtime.Stream,
+	args []string, envs []string, res *ProcessResources) (uint32, error) {
 
 	procSpec := DefaultProcessSpec()
 	procSpec.Cwd = user.Pwd
 	procSpec.User.UID = user.UID
 	procSpec.User.GID = user.GID
 	procSpec.Args = args
-	procSpec.Env = os.Environ()
+	procSpec.Env = append(procSpec.Env, envs...)
+	procSpec.Env = append(procSpec.Env, mountEnv(ws.Mounts)...)
+
+	err := res.Validate()
+	if err != nil {
+		return 0, err
+	}
 
 	// TODO: have a mechanism to permit or disallow sudo, i.e. 'sudo cne'
 	allowSudo := true
@@ -433,19 +680,57 @@ func commonExec(ctr *Container, procSpec *specs.Process, stream runtime.Stream)
 		return 0, err
 	}
 
+	return waitProcess(proc, stream.Terminal)
+}
+
+// forwardSignals are the signals that are forwarded to a process with a terminal while
+// waiting for it to exit.
+var forwardSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// resizeProcess resizes the terminal of the process to the size of the terminal of stdin.
+func resizeProcess(proc runtime.Process) error {
+
+	con, err := console.ConsoleFromFile(os.Stdin)
+	if err != nil {
+		return err
+	}
+	size, err := con.Size()
+	if err != nil {
+		return err
+	}
+	return proc.Resize(uint32(size.Width), uint32(size.Height))
+}
+
+// waitProcess waits for the process to exit. If the process has a terminal, the signals for
+// terminating the process are forwarded to it and its size follows the size of the host
+// terminal. The default signal handlers are restored when the process exits.
+func waitProcess(proc runtime.Process, terminal bool) (uint32, error) {
+
 	ch, err := proc.Wait()
 	if err != nil {
 		return 0, err
 	}
 
+	if !terminal {
+		exitStat := <-ch
+		return exitStat.Code, exitStat.Error
+	}
+
+	resizeProcess(proc) // ignore errors, e.g. if stdin is not a terminal
+	signals := append([]os.Signal{syscall.SIGWINCH}, forwardSignals...)
+
 	sigc := make(chan os.Signal, 1)
-	signal.Notify(sigc)
+	signal.Notify(sigc, signals...)
 	go func() {
 		for {
 			s, more := <-sigc
 			if !more {
 				return
 			}
+			if s == syscall.SIGWINCH {
+				resizeProcess(proc)
+				continue
+			}
 			proc.Signal(s)
 		}
 	}()
@@ -456,6 +741,65 @@ func commonExec(ctr *Container, procSpec *specs.Process, stream runtime.Stream)
 	return exitStat.Code, exitStat.Error
 }
 
+// Start starts the main process of the container, as defined by the image, and returns
+// without waiting for the process to exit.
+func (ctr *Container) Start() error {
+	return ctr.runContainer.Start()
+}
+
+// Stop stops the main process and all other processes running in the container.
+func (ctr *Container) Stop() error {
+	return ctr.runContainer.Stop()
+}
+
+// Running returns true if the main process of the container is running.
+func (ctr *Container) Running() (bool, error) {
+	return ctr.runContainer.Running()
+}
+
+// Stats returns the current resource usage of the container.
+func (ctr *Container) Stats() (*runtime.Stats, error) {
+	return ctr.runContainer.Stats()
+}
+
+// Attach attaches the stream to the main process of the container and waits for the process
+// to exit.
+func (ctr *Container) Attach(stream runtime.Stream) (uint32, error) {
+
+	proc, err := ctr.runContainer.Attach(stream)
+	if err != nil {
+		return 0, err
+	}
+	return waitProcess(proc, stream.Terminal)
+}
+
+// Logs attaches the stream to the output of the main process of the container. If follow is
+// set, it waits for the process to exit.
+func (ctr *Container) Logs(stream runtime.Stream, follow bool) error {
+	return ctr.runContainer.Logs(stream, follow)
+}
+
+// Diff calls the function for each file that was changed in the container since the last
+// committed snapshot.
+func (ctr *Container) Diff(changes func(runtime.Change) error) error {
+	return ctr.runContainer.Diff(changes)
+}
+
+// Ephemeral returns a temporary container with a copy of the filesystem of the container.
+// Changes in the temporary container are discarded when the container is deleted.
+func (ctr *Container) Ephemeral() (*Container, error) {
+
+	runCtr, err := ctr.runContainer.Ephemeral()
+	if err != nil {
+		return nil, err
+	}
+
+	ephCtr := *ctr
+	ephCtr.runContainer = runCtr
+	ephCtr.RuntimeID = runCtr.RuntimeID()
+	return &ephCtr, nil
+}
+
 // Delete deletes the container if not already deleted but not any associated Snapshots.
 func (ctr *Container) Delete() error {
 	return ctr.runContainer.Delete()
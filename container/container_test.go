@@ -0,0 +1,891 @@
+package container
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/containerd/console"
+	"github.com/google/uuid"
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+	"github.com/czankel/cne/runtime/mock"
+)
+
+// testRuntime provides the runtime functions used by the container package.
+type testRuntime struct {
+	runtime.Runtime
+	snaps   []runtime.Snapshot
+	ctrs    []*testRunContainer
+	touched []string
+}
+
+func (run *testRuntime) Namespace() string {
+	return "test"
+}
+
+func (run *testRuntime) Snapshots() ([]runtime.Snapshot, error) {
+	return run.snaps, nil
+}
+
+func (run *testRuntime) TouchSnapshot(name string) error {
+	run.touched = append(run.touched, name)
+	return nil
+}
+
+func (run *testRuntime) GetContainer(domain, id, generation [16]byte) (runtime.Container, error) {
+	for _, c := range run.ctrs {
+		if c.Domain() == domain && c.ID() == id && c.Generation() == generation {
+			return c, nil
+		}
+	}
+	return nil, errdefs.NotFound("container", "test")
+}
+
+func (run *testRuntime) Containers(filters ...interface{}) ([]runtime.Container, []string, error) {
+	var ctrs []runtime.Container
+	for _, c := range run.ctrs {
+		ctrs = append(ctrs, c)
+	}
+	return ctrs, []string{}, nil
+}
+
+func (run *testRuntime) NewContainer(domain, id, generation [16]byte, uid uint32,
+	domainName string, img runtime.Image, spec *runspecs.Spec) (runtime.Container, error) {
+
+	runCtr := &testRunContainer{
+		domain:     domain,
+		id:         id,
+		gen:        generation,
+		domainName: domainName,
+	}
+	run.ctrs = append(run.ctrs, runCtr)
+	return runCtr, nil
+}
+
+// testRunContainer records all executed commands. The exit code for a command can be
+// injected through the codes map using the command line joined by spaces. Files created
+// with 'touch' are recorded in the files map.
+type testRunContainer struct {
+	runtime.Container
+	codes      map[string]uint32
+	files      map[string]bool
+	deleted    bool
+	cmdlines   [][]string
+	procSpec   *runspecs.Process
+	running    bool
+	warnings   []string
+	snap       runtime.Snapshot
+	domain     [16]byte
+	domainName string
+	id         [16]byte
+	gen        [16]byte
+}
+
+func (runCtr *testRunContainer) Domain() [16]byte {
+	return runCtr.domain
+}
+
+func (runCtr *testRunContainer) DomainName() string {
+	return runCtr.domainName
+}
+
+func (runCtr *testRunContainer) ID() [16]byte {
+	return runCtr.id
+}
+
+func (runCtr *testRunContainer) RuntimeID() string {
+	return hex.EncodeToString(runCtr.domain[:]) + "-" + hex.EncodeToString(runCtr.id[:])
+}
+
+func (runCtr *testRunContainer) Generation() [16]byte {
+	return runCtr.gen
+}
+
+func (runCtr *testRunContainer) UID() uint32 {
+	return 0
+}
+
+func (runCtr *testRunContainer) CreatedAt() time.Time {
+	return time.Time{}
+}
+
+func (runCtr *testRunContainer) Create() ([]string, error) {
+	return runCtr.warnings, nil
+}
+
+func (runCtr *testRunContainer) SetRootFs(snap runtime.Snapshot) error {
+	return nil
+}
+
+func (runCtr *testRunContainer) Snapshot() (runtime.Snapshot, error) {
+	if runCtr.snap == nil {
+		return nil, errdefs.NotImplemented()
+	}
+	return runCtr.snap, nil
+}
+
+func (runCtr *testRunContainer) Commit(gen [16]byte) error {
+	runCtr.gen = gen
+	return nil
+}
+
+func (runCtr *testRunContainer) Delete() error {
+	runCtr.deleted = true
+	return nil
+}
+
+func (runCtr *testRunContainer) Ephemeral() (runtime.Container, error) {
+
+	files := map[string]bool{}
+	for f := range runCtr.files {
+		files[f] = true
+	}
+	ephCtr := *runCtr
+	ephCtr.files = files
+	ephCtr.cmdlines = nil
+	return &ephCtr, nil
+}
+
+func (runCtr *testRunContainer) Exec(stream runtime.Stream,
+	procSpec *runspecs.Process) (runtime.Process, error) {
+
+	runCtr.cmdlines = append(runCtr.cmdlines, procSpec.Args)
+	runCtr.procSpec = procSpec
+	if len(procSpec.Args) == 2 && procSpec.Args[0] == "touch" {
+		runCtr.files[procSpec.Args[1]] = true
+	}
+	return &testProcess{code: runCtr.codes[strings.Join(procSpec.Args, " ")]}, nil
+}
+
+func (runCtr *testRunContainer) Start() error {
+	if runCtr.running {
+		return errdefs.AlreadyExists("task", "test")
+	}
+	runCtr.running = true
+	return nil
+}
+
+func (runCtr *testRunContainer) Stop() error {
+	if !runCtr.running {
+		return errdefs.NotFound("task", "test")
+	}
+	runCtr.running = false
+	return nil
+}
+
+func (runCtr *testRunContainer) Running() (bool, error) {
+	return runCtr.running, nil
+}
+
+func (runCtr *testRunContainer) Attach(stream runtime.Stream) (runtime.Process, error) {
+	if !runCtr.running {
+		return nil, errdefs.NotFound("task", "test")
+	}
+	return &testProcess{}, nil
+}
+
+type testSnapshot struct {
+	runtime.Snapshot
+	name string
+}
+
+func (snap *testSnapshot) Name() string {
+	return snap.name
+}
+
+type testProcess struct {
+	code   uint32
+	mutex  sync.Mutex
+	width  uint32
+	height uint32
+}
+
+func (proc *testProcess) Signal(sig os.Signal) error {
+	return nil
+}
+
+func (proc *testProcess) Wait() (<-chan runtime.ExitStatus, error) {
+	ch := make(chan runtime.ExitStatus, 1)
+	ch <- runtime.ExitStatus{ExitTime: time.Now(), Code: proc.code}
+	return ch, nil
+}
+
+func (proc *testProcess) Resize(width, height uint32) error {
+
+	proc.mutex.Lock()
+	defer proc.mutex.Unlock()
+
+	proc.width = width
+	proc.height = height
+	return nil
+}
+
+func (proc *testProcess) size() (uint32, uint32) {
+
+	proc.mutex.Lock()
+	defer proc.mutex.Unlock()
+
+	return proc.width, proc.height
+}
+
+// testSignalProcess is a process that runs until it exits with the exit status sent to the
+// exit channel and reports the signals it receives.
+type testSignalProcess struct {
+	testProcess
+	exit    chan runtime.ExitStatus
+	signals chan os.Signal
+}
+
+func (proc *testSignalProcess) Signal(sig os.Signal) error {
+	proc.signals <- sig
+	return nil
+}
+
+func (proc *testSignalProcess) Wait() (<-chan runtime.ExitStatus, error) {
+	return proc.exit, nil
+}
+
+func setupContainer(t *testing.T) (*Container, *testRunContainer, *project.Workspace) {
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	runCtr := &testRunContainer{codes: map[string]uint32{}}
+	ctr := &Container{
+		runRuntime:   &testRuntime{},
+		runContainer: runCtr,
+	}
+	return ctr, runCtr, ws
+}
+
+func TestContainerPostBuild(t *testing.T) {
+
+	user, err := config.CurrentUser()
+	if err != nil {
+		t.Fatalf("Failed to get current user")
+	}
+	params := config.Parameters{}
+
+	ctr, runCtr, ws := setupContainer(t)
+
+	for _, name := range []string{"layer1", "layer2"} {
+		layer, err := ws.CreateLayer(false, name, -1)
+		if err != nil {
+			t.Fatalf("Failed to create layer %s", name)
+		}
+		layer.Commands = []project.Command{{Args: []string{"cmd-" + name}}}
+	}
+	ws.PostBuild.Args = []string{"post-build", "arg"}
+	ws.Environment.Env = map[string]string{"FOO": "bar"}
+
+	err = ctr.Build(ws, -1, &user, &params, nil, runtime.Stream{})
+	if err != nil {
+		t.Fatalf("Failed to build container: %v", err)
+	}
+	err = ctr.PostBuild(ws, &user, runtime.Stream{})
+	if err != nil {
+		t.Fatalf("Post-build failed: %v", err)
+	}
+
+	expected := []string{"cmd-layer1", "cmd-layer2", "post-build arg"}
+	if len(runCtr.cmdlines) != len(expected) {
+		t.Fatalf("Expected %d commands, got %d", len(expected), len(runCtr.cmdlines))
+	}
+	for i, c := range runCtr.cmdlines {
+		if strings.Join(c, " ") != expected[i] {
+			t.Errorf("Command %d should be '%s', got '%v'", i, expected[i], c)
+		}
+	}
+	found := false
+	for _, e := range runCtr.procSpec.Env {
+		found = found || e == "FOO=bar"
+	}
+	if !found {
+		t.Errorf("Post-build command should get the workspace environment: %v",
+			runCtr.procSpec.Env)
+	}
+
+	// failing post-build command
+	runCtr.codes["post-build arg"] = 1
+	err = ctr.PostBuild(ws, &user, runtime.Stream{})
+	if !errors.Is(err, errdefs.ErrCommandFailed) {
+		t.Errorf("Failing post-build command should fail the build: %v", err)
+	}
+
+	ws.PostBuild.IgnoreErrors = true
+	err = ctr.PostBuild(ws, &user, runtime.Stream{})
+	if err != nil {
+		t.Errorf("Failing post-build command should be ignored: %v", err)
+	}
+}
+
+func TestContainerBuildNoCache(t *testing.T) {
+
+	user, err := config.CurrentUser()
+	if err != nil {
+		t.Fatalf("Failed to get current user")
+	}
+	params := config.Parameters{}
+
+	ctr, runCtr, ws := setupContainer(t)
+	run := &testRuntime{snaps: []runtime.Snapshot{
+		&testSnapshot{name: "snap-layer1"},
+		&testSnapshot{name: "snap-layer2"},
+	}}
+	ctr.runRuntime = run
+
+	for _, name := range []string{"layer1", "layer2"} {
+		layer, err := ws.CreateLayer(false, name, -1)
+		if err != nil {
+			t.Fatalf("Failed to create layer %s", name)
+		}
+		layer.Commands = []project.Command{{Args: []string{"cmd-" + name}}}
+		layer.Digest = "snap-" + name
+	}
+
+	// cached layers are not rebuilt
+	err = ctr.Build(ws, -1, &user, &params, nil, runtime.Stream{})
+	if err != nil {
+		t.Fatalf("Failed to build container: %v", err)
+	}
+	if len(runCtr.cmdlines) != 0 {
+		t.Errorf("Cached layers should not be rebuilt: %v", runCtr.cmdlines)
+	}
+	if len(run.touched) != 2 || run.touched[0] != "snap-layer1" ||
+		run.touched[1] != "snap-layer2" {
+		t.Errorf("Use of the cached layers should be recorded: %v", run.touched)
+	}
+
+	ws.ClearLayerCache()
+	err = ctr.Build(ws, -1, &user, &params, nil, runtime.Stream{})
+	if err != nil {
+		t.Fatalf("Failed to build container: %v", err)
+	}
+	if len(runCtr.cmdlines) != 2 ||
+		runCtr.cmdlines[0][0] != "cmd-layer1" || runCtr.cmdlines[1][0] != "cmd-layer2" {
+		t.Errorf("All layer commands should be executed: %v", runCtr.cmdlines)
+	}
+}
+
+func TestContainerExecEnv(t *testing.T) {
+
+	user, err := config.CurrentUser()
+	if err != nil {
+		t.Fatalf("Failed to get current user")
+	}
+
+	ctr, runCtr, ws := setupContainer(t)
+
+	_, err = ctr.Exec(ws, &user, runtime.Stream{}, []string{"cmd"}, []string{"FOO=bar"}, nil)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	for _, env := range append(DefaultProcessSpec().Env, "FOO=bar") {
+		found := false
+		for _, e := range runCtr.procSpec.Env {
+			found = found || e == env
+		}
+		if !found {
+			t.Errorf("Variable '%s' missing in the environment: %v", env, runCtr.procSpec.Env)
+		}
+	}
+}
+
+func TestContainerExecResources(t *testing.T) {
+
+	user, err := config.CurrentUser()
+	if err != nil {
+		t.Fatalf("Failed to get current user")
+	}
+
+	ctr, runCtr, ws := setupContainer(t)
+
+	_, err = ctr.Exec(ws, &user, runtime.Stream{}, []string{"cmd"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	for _, r := range runCtr.procSpec.Rlimits {
+		if r.Type == "RLIMIT_AS" {
+			t.Errorf("Exec should not limit the address space: %v", runCtr.procSpec.Rlimits)
+		}
+	}
+
+	// processes share the cgroup of the container, so per-process limits are not supported
+	res := &ProcessResources{Memory: 512000000}
+	_, err = ctr.Exec(ws, &user, runtime.Stream{}, []string{"cmd"}, nil, res)
+	if !errors.Is(err, errdefs.ErrNotImplemented) {
+		t.Errorf("Memory limit for a single process should not be supported: %v", err)
+	}
+
+	res = &ProcessResources{CPUs: 2}
+	_, err = ctr.Exec(ws, &user, runtime.Stream{}, []string{"cmd"}, nil, res)
+	if !errors.Is(err, errdefs.ErrNotImplemented) {
+		t.Errorf("CPU limit for a single process should not be supported: %v", err)
+	}
+
+	res = &ProcessResources{Memory: -1}
+	_, err = ctr.Exec(ws, &user, runtime.Stream{}, []string{"cmd"}, nil, res)
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Negative memory limit should be invalid: %v", err)
+	}
+}
+
+func TestContainerStartStop(t *testing.T) {
+
+	ctr, _, _ := setupContainer(t)
+
+	err := ctr.Start()
+	if err != nil {
+		t.Fatalf("Failed to start container: %v", err)
+	}
+	running, err := ctr.Running()
+	if err != nil || !running {
+		t.Errorf("Container should be running after start: %v", err)
+	}
+	err = ctr.Start()
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Starting a running container should fail: %v", err)
+	}
+
+	code, err := ctr.Attach(runtime.Stream{})
+	if err != nil || code != 0 {
+		t.Errorf("Failed to attach to container: %d %v", code, err)
+	}
+
+	err = ctr.Stop()
+	if err != nil {
+		t.Fatalf("Failed to stop container: %v", err)
+	}
+	running, err = ctr.Running()
+	if err != nil || running {
+		t.Errorf("Container should not be running after stop: %v", err)
+	}
+	_, err = ctr.Attach(runtime.Stream{})
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Attaching to a stopped container should fail: %v", err)
+	}
+}
+
+func TestContainerCreateWarnings(t *testing.T) {
+
+	ctr, runCtr, _ := setupContainer(t)
+	runCtr.warnings = []string{"runtime plugin is deprecated"}
+
+	warnings, err := ctr.Create()
+	if err != nil {
+		t.Fatalf("Container should be created despite warnings: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0] != runCtr.warnings[0] {
+		t.Errorf("Expected warnings %v, got %v", runCtr.warnings, warnings)
+	}
+}
+
+func TestContainerGetAfterRename(t *testing.T) {
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	dom, err := uuid.Parse(prj.UUID)
+	if err != nil {
+		t.Fatalf("Invalid project UUID")
+	}
+	runCtr := &testRunContainer{domain: dom, id: ws.ID(), gen: ws.ConfigHash()}
+	run := &testRuntime{ctrs: []*testRunContainer{runCtr}}
+
+	_, err = Get(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get container: %v", err)
+	}
+
+	err = prj.RenameWorkspace("ws", "renamed")
+	if err != nil {
+		t.Fatalf("Failed to rename workspace: %v", err)
+	}
+	ws, err = prj.Workspace("renamed")
+	if err != nil {
+		t.Fatalf("Renamed workspace not found")
+	}
+	ctr, err := Get(run, ws)
+	if err != nil {
+		t.Fatalf("Container should resolve after renaming the workspace: %v", err)
+	}
+	if ctr.ID != runCtr.id {
+		t.Errorf("Container ID changed after renaming the workspace")
+	}
+}
+
+func TestContainerDomainName(t *testing.T) {
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	run := &testRuntime{}
+	conf := &config.Config{}
+	user := &config.User{UID: 0}
+	_, err = NewContainer(run, conf, user, ws, nil)
+	if err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	// a container of an older version has no domain name
+	dom := [16]byte{0xde, 0xad, 0xbe, 0xef}
+	run.ctrs = append(run.ctrs, &testRunContainer{domain: dom})
+
+	ctrs, _, err := Containers(run, nil, user)
+	if err != nil {
+		t.Fatalf("Failed to get containers: %v", err)
+	}
+	if len(ctrs) != 2 {
+		t.Fatalf("Expected 2 containers, got %d", len(ctrs))
+	}
+	if ctrs[0].DomainName != "project" {
+		t.Errorf("Expected domain name 'project', got '%s'", ctrs[0].DomainName)
+	}
+	if ctrs[1].DomainName != hex.EncodeToString(dom[:]) {
+		t.Errorf("Expected hex domain for unnamed domain, got '%s'", ctrs[1].DomainName)
+	}
+}
+
+func TestContainerCheckpoint(t *testing.T) {
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	gen := ws.ConfigHash()
+	runCtr := &testRunContainer{gen: gen}
+	ctr := &Container{runContainer: runCtr, Name: "test", Generation: gen}
+
+	err = ctr.Checkpoint(ws, "first")
+	if err == nil {
+		t.Errorf("Checkpoint should fail if the runtime doesn't support snapshots")
+	}
+	if len(ws.Environment.Commits) != 0 {
+		t.Errorf("Failed checkpoint should not be recorded")
+	}
+
+	runCtr.snap = &testSnapshot{name: "snap1"}
+	err = ctr.Checkpoint(ws, "first")
+	if err != nil {
+		t.Fatalf("Failed to checkpoint container: %v", err)
+	}
+	if len(ws.Environment.Commits) != 1 ||
+		ws.Environment.Commits[0].Message != "first" ||
+		ws.Environment.Commits[0].Snapshot != "snap1" {
+		t.Errorf("Commit not recorded: %v", ws.Environment.Commits)
+	}
+	if ctr.Generation == gen || ctr.Generation != ws.ConfigHash() ||
+		runCtr.gen != ctr.Generation {
+		t.Errorf("Container not committed with a new generation")
+	}
+}
+
+func TestContainerGetGeneration(t *testing.T) {
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	dom, err := uuid.Parse(prj.UUID)
+	if err != nil {
+		t.Fatalf("Invalid project UUID")
+	}
+	oldGen := [16]byte{1}
+	run := &testRuntime{ctrs: []*testRunContainer{
+		{domain: dom, id: ws.ID(), gen: oldGen},
+		{domain: dom, id: ws.ID(), gen: ws.ConfigHash()},
+	}}
+
+	ctr, err := Get(run, ws)
+	if err != nil || ctr.Generation != ws.ConfigHash() {
+		t.Errorf("Failed to get the current generation: %v", err)
+	}
+
+	ctr, err = GetGeneration(run, ws, oldGen)
+	if err != nil || ctr.Generation != oldGen {
+		t.Errorf("Failed to get the prior generation: %v", err)
+	}
+
+	_, err = GetGeneration(run, ws, [16]byte{2})
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error for unknown generation, got %v", err)
+	}
+}
+
+func TestContainerEphemeral(t *testing.T) {
+
+	ctr, runCtr, ws := setupContainer(t)
+	runCtr.files = map[string]bool{"/etc/passwd": true}
+
+	ephCtr, err := ctr.Ephemeral()
+	if err != nil {
+		t.Fatalf("Failed to create ephemeral container: %v", err)
+	}
+	_, err = ephCtr.Exec(ws, &config.User{}, runtime.Stream{},
+		[]string{"touch", "/tmp/scratch"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+	err = ephCtr.Delete()
+	if err != nil {
+		t.Fatalf("Failed to delete ephemeral container: %v", err)
+	}
+
+	ephRunCtr := ephCtr.runContainer.(*testRunContainer)
+	if !ephRunCtr.files["/tmp/scratch"] || !ephRunCtr.deleted {
+		t.Errorf("Command not executed in the ephemeral container")
+	}
+	if runCtr.files["/tmp/scratch"] || len(runCtr.cmdlines) != 0 || runCtr.deleted {
+		t.Errorf("Ephemeral exec modified the workspace container")
+	}
+}
+
+func TestContainerSnapshots(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	img := run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("", img.Name(), "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	dom, _ := uuid.Parse(ws.ProjectUUID)
+	runCtr, err := run.NewContainer(dom, ws.ID(), ws.ConfigHash(), 0, "test", img, nil)
+	if err == nil {
+		_, err = runCtr.Create()
+	}
+	if err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	ctr, err := Get(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get container: %v", err)
+	}
+	gen := ctr.Generation
+
+	if err = ctr.CreateSnapshot(ws, "base"); err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	if err = ctr.CreateSnapshot(ws, "base"); !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Creating a snapshot with an existing name should fail: %v", err)
+	}
+
+	// advance the container to a new generation
+	if _, err = ws.CreateLayer(false, "tools", -1); err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	if err = ctr.Checkpoint(ws, "tools"); err != nil {
+		t.Fatalf("Failed to commit container: %v", err)
+	}
+	if ctr.Generation == gen {
+		t.Fatalf("Generation should have changed")
+	}
+
+	if err = ctr.RestoreSnapshot(ws, "missing"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Restoring a missing snapshot should fail: %v", err)
+	}
+	if err = ctr.RestoreSnapshot(ws, "base"); err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+	if ctr.Generation != gen || ws.ConfigHash() != gen || len(ws.Environment.Layers) != 0 {
+		t.Errorf("Snapshot not restored: %v", ws.Environment)
+	}
+	if _, err = Get(run, ws); err != nil {
+		t.Errorf("Failed to get restored container: %v", err)
+	}
+}
+
+func TestContainerRollback(t *testing.T) {
+
+	defer mock.Reset()
+	run := mock.Namespace("test")
+	img := run.AddImage("docker.io/library/busybox:latest", 1000)
+
+	prj := project.NewProject("project", "/some/path")
+	ws, err := prj.CreateWorkspace("", img.Name(), "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace")
+	}
+
+	dom, _ := uuid.Parse(ws.ProjectUUID)
+	gen := ws.ConfigHash()
+	runCtr, err := run.NewContainer(dom, ws.ID(), gen, 0, "test", img, nil)
+	if err == nil {
+		_, err = runCtr.Create()
+	}
+	if err == nil {
+		err = runCtr.Commit(gen)
+	}
+	if err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	ctr, err := Get(run, ws)
+	if err != nil {
+		t.Fatalf("Failed to get container: %v", err)
+	}
+	if err = ctr.Rollback(ws); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Rollback without a previous generation should fail: %v", err)
+	}
+
+	if err = ctr.Checkpoint(ws, "first"); err != nil {
+		t.Fatalf("Failed to commit container: %v", err)
+	}
+	if err = ctr.Checkpoint(ws, "second"); err != nil {
+		t.Fatalf("Failed to commit container: %v", err)
+	}
+	prevGen := ctr.Generation
+
+	if err = ctr.Rollback(ws); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if len(ws.Environment.Commits) != 1 || ctr.Generation == prevGen ||
+		ws.ConfigHash() != ctr.Generation {
+		t.Errorf("Unexpected state after rollback: %v", ws.Environment.Commits)
+	}
+
+	if err = ctr.Rollback(ws); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if len(ws.Environment.Commits) != 0 || ctr.Generation != gen {
+		t.Errorf("Unexpected state after second rollback: %v", ws.Environment.Commits)
+	}
+	if _, err = Get(run, ws); err != nil {
+		t.Errorf("Failed to get container after rollback: %v", err)
+	}
+}
+
+func TestWaitProcessSignals(t *testing.T) {
+
+	// catch the signal, so it doesn't terminate the test if it isn't forwarded
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT)
+	defer signal.Stop(sigc)
+
+	for _, terminal := range []bool{false, true} {
+
+		proc := &testSignalProcess{
+			exit:    make(chan runtime.ExitStatus, 1),
+			signals: make(chan os.Signal, 10),
+		}
+		done := make(chan struct{})
+		go func() {
+			waitProcess(proc, terminal)
+			close(done)
+		}()
+
+		// the process waits asynchronously, so repeat the signal until it is forwarded
+		forwarded := false
+		timeout := time.After(500 * time.Millisecond)
+		for loop := true; loop; {
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+			select {
+			case <-proc.signals:
+				forwarded = true
+				loop = false
+			case <-timeout:
+				loop = false
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		proc.exit <- runtime.ExitStatus{}
+		<-done
+
+		if forwarded != terminal {
+			t.Errorf("Terminal %t: expected forwarded signal %t", terminal, terminal)
+		}
+	}
+}
+
+func TestResizeProcess(t *testing.T) {
+
+	pty, slave, err := console.NewPty()
+	if err != nil {
+		t.Skipf("Failed to create a pseudo terminal: %v", err)
+	}
+	defer pty.Close()
+
+	tty, err := os.OpenFile(slave, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to open the pseudo terminal: %v", err)
+	}
+	defer tty.Close()
+
+	stdin := os.Stdin
+	os.Stdin = tty
+	defer func() { os.Stdin = stdin }()
+
+	err = pty.Resize(console.WinSize{Width: 120, Height: 40})
+	if err != nil {
+		t.Fatalf("Failed to resize the pseudo terminal: %v", err)
+	}
+
+	proc := &testSignalProcess{
+		exit:    make(chan runtime.ExitStatus, 1),
+		signals: make(chan os.Signal, 10),
+	}
+	done := make(chan struct{})
+	go func() {
+		waitProcess(proc, true)
+		close(done)
+	}()
+
+	// the process terminal gets the size of the terminal and follows its changes
+	sizes := []console.WinSize{{Width: 120, Height: 40}, {Width: 80, Height: 24}}
+	for i, size := range sizes {
+		if i > 0 {
+			err = pty.Resize(size)
+			if err != nil {
+				t.Fatalf("Failed to resize the pseudo terminal: %v", err)
+			}
+		}
+		timeout := time.After(time.Second)
+		for loop := true; loop; {
+			width, height := proc.size()
+			if width == uint32(size.Width) && height == uint32(size.Height) {
+				break
+			}
+			if i > 0 {
+				syscall.Kill(os.Getpid(), syscall.SIGWINCH)
+			}
+			select {
+			case <-timeout:
+				t.Errorf("Expected size %dx%d, got %dx%d",
+					size.Width, size.Height, width, height)
+				loop = false
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	proc.exit <- runtime.ExitStatus{}
+	<-done
+}
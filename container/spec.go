@@ -3,6 +3,10 @@ package container
 import (
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"path/filepath"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
 )
 
 const (
@@ -11,6 +15,9 @@ const (
 	defaultRootfsPath = "rootfs"
 )
 
+// RuntimeSocketPath is the path of the runtime socket inside the container.
+const RuntimeSocketPath = "/run/cne/runtime.sock"
+
 var (
 	defaultEnv = []string{
 		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
@@ -90,6 +97,156 @@ func DefaultProcessSpec() specs.Process {
 	}
 }
 
+// ProcessResources describes resource limits that override the container defaults for a
+// single process.
+type ProcessResources struct {
+	CPUs   float64 // Number of CPUs, 0 for no override
+	Memory int64   // Memory limit in bytes, 0 for no override
+}
+
+// cpuPeriod is the CFS period in microseconds used for CPU limits.
+const cpuPeriod = 100000
+
+// applyResources applies the resource limits of the workspace to the cgroup of the container,
+// which includes all processes executed in the container.
+func applyResources(spec *specs.Spec, res project.Resources) error {
+
+	if res.CPUs < 0 {
+		return errdefs.InvalidArgument("invalid number of CPUs: %v", res.CPUs)
+	}
+	if res.Memory < 0 {
+		return errdefs.InvalidArgument("invalid memory limit: %d", res.Memory)
+	}
+
+	if spec.Linux.Resources == nil {
+		spec.Linux.Resources = &specs.LinuxResources{}
+	}
+	linuxRes := spec.Linux.Resources
+
+	if res.CPUs != 0 || res.CPUShares != 0 {
+		linuxRes.CPU = &specs.LinuxCPU{}
+		if res.CPUs != 0 {
+			quota := int64(res.CPUs * cpuPeriod)
+			period := uint64(cpuPeriod)
+			linuxRes.CPU.Quota = &quota
+			linuxRes.CPU.Period = &period
+		}
+		if res.CPUShares != 0 {
+			shares := res.CPUShares
+			linuxRes.CPU.Shares = &shares
+		}
+	}
+	if res.Memory != 0 {
+		limit := res.Memory
+		linuxRes.Memory = &specs.LinuxMemory{Limit: &limit}
+	}
+	return nil
+}
+
+// Validate checks the resource overrides of the process.
+// Processes executed in a container share the cgroup of the container and the runtime does not
+// support cgroup limits for a single process, so any limit is rejected as not implemented.
+func (res *ProcessResources) Validate() error {
+
+	if res == nil {
+		return nil
+	}
+
+	if res.CPUs < 0 {
+		return errdefs.InvalidArgument("invalid CPU limit: %f", res.CPUs)
+	}
+	if res.Memory < 0 {
+		return errdefs.InvalidArgument("invalid memory limit: %d", res.Memory)
+	}
+	if res.CPUs != 0 {
+		return errdefs.New(errdefs.ErrNotImplemented, "resources",
+			"CPU limits are not supported for a single process")
+	}
+	if res.Memory != 0 {
+		return errdefs.New(errdefs.ErrNotImplemented, "resources",
+			"memory limits are not supported for a single process")
+	}
+
+	return nil
+}
+
+// bindMount returns a bind mount for the spec.
+func bindMount(source, destination string, readOnly bool) specs.Mount {
+
+	opt := "rw"
+	if readOnly {
+		opt = "ro"
+	}
+	return specs.Mount{
+		Destination: destination,
+		Type:        "bind",
+		Source:      source,
+		Options:     []string{"rbind", opt},
+	}
+}
+
+// mountSpecs returns the mounts for the default mounts from the configuration and the mounts
+// of the workspace. Relative paths of default mounts are resolved against the home directory
+// of the user. Workspace mounts take precedence over default mounts with the same destination
+// and a workspace mount without a source removes the default mount.
+func mountSpecs(defMounts []config.Mount, wsMounts []project.Mount,
+	user *config.User) []specs.Mount {
+
+	mounts := []specs.Mount{}
+
+	resolve := func(path string) string {
+		if path != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(user.HomeDir, path)
+		}
+		return path
+	}
+
+	for _, dm := range defMounts {
+		dest := resolve(dm.Destination)
+		overridden := false
+		for _, wm := range wsMounts {
+			if filepath.Clean(wm.Destination) == filepath.Clean(dest) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			mounts = append(mounts, bindMount(resolve(dm.Source), dest, dm.ReadOnly))
+		}
+	}
+
+	for _, wm := range wsMounts {
+		if wm.Source != "" {
+			mounts = append(mounts, bindMount(wm.Source, wm.Destination, wm.ReadOnly))
+		}
+	}
+
+	return mounts
+}
+
+// RuntimeSocketMount returns a workspace mount for the socket of the configured runtime.
+// Note that access to the socket gives the container full control over the runtime and,
+// therefore, the host, even if mounted read-only.
+func RuntimeSocketMount(conf *config.Config, readOnly bool) project.Mount {
+	return project.Mount{
+		Source:      conf.Runtime.SocketName,
+		Destination: RuntimeSocketPath,
+		ReadOnly:    readOnly,
+	}
+}
+
+// mountEnv returns the environment variables required for the workspace mounts.
+func mountEnv(wsMounts []project.Mount) []string {
+
+	env := []string{}
+	for _, wm := range wsMounts {
+		if wm.Source != "" && filepath.Clean(wm.Destination) == RuntimeSocketPath {
+			env = append(env, "CONTAINERD_ADDRESS="+RuntimeSocketPath)
+		}
+	}
+	return env
+}
+
 func DefaultSpec(namespace string, ctrID string) (specs.Spec, error) {
 
 	caps := []string{}
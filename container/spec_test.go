@@ -0,0 +1,126 @@
+package container
+
+import (
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/project"
+)
+
+// findMount returns the mount for the destination or nil if it doesn't exist.
+func findMount(mounts []specs.Mount, dest string) *specs.Mount {
+	for i, m := range mounts {
+		if m.Destination == dest {
+			return &mounts[i]
+		}
+	}
+	return nil
+}
+
+func TestSpecDefaultMounts(t *testing.T) {
+
+	user := &config.User{HomeDir: "/home/user"}
+	defMounts := []config.Mount{
+		{Source: "/var/cache/pkg", Destination: "/var/cache/pkg"},
+		{Source: ".cache", Destination: ".cache", ReadOnly: true},
+		{Source: "/opt/tools", Destination: "/opt/tools"},
+	}
+
+	// default mounts in a workspace without mounts
+	mounts := mountSpecs(defMounts, nil, user)
+	if len(mounts) != 3 {
+		t.Fatalf("Expected 3 mounts, got %d", len(mounts))
+	}
+	m := findMount(mounts, "/var/cache/pkg")
+	if m == nil || m.Source != "/var/cache/pkg" || m.Type != "bind" {
+		t.Errorf("Default mount missing: %v", mounts)
+	}
+	m = findMount(mounts, "/home/user/.cache")
+	if m == nil || m.Source != "/home/user/.cache" {
+		t.Fatalf("Relative default mount not resolved against home: %v", mounts)
+	}
+	if m.Options[len(m.Options)-1] != "ro" {
+		t.Errorf("Read-only default mount should be read-only: %v", m.Options)
+	}
+
+	// workspace mounts take precedence and can remove default mounts
+	wsMounts := []project.Mount{
+		{Source: "/srv/pkg", Destination: "/var/cache/pkg", ReadOnly: true},
+		{Source: "", Destination: "/opt/tools"},
+	}
+	mounts = mountSpecs(defMounts, wsMounts, user)
+	if len(mounts) != 2 {
+		t.Fatalf("Expected 2 mounts, got %d: %v", len(mounts), mounts)
+	}
+	m = findMount(mounts, "/var/cache/pkg")
+	if m == nil || m.Source != "/srv/pkg" {
+		t.Errorf("Workspace mount should override default mount: %v", mounts)
+	}
+	if findMount(mounts, "/opt/tools") != nil {
+		t.Errorf("Workspace mount without source should remove the default mount")
+	}
+}
+
+func TestSpecRuntimeSocketMount(t *testing.T) {
+
+	user := &config.User{HomeDir: "/home/user"}
+	conf := &config.Config{Runtime: config.Runtime{SocketName: "/run/test/test.sock"}}
+
+	wsMounts := []project.Mount{RuntimeSocketMount(conf, true)}
+	mounts := mountSpecs(nil, wsMounts, user)
+	m := findMount(mounts, RuntimeSocketPath)
+	if m == nil || m.Source != "/run/test/test.sock" {
+		t.Fatalf("Runtime socket not mounted: %v", mounts)
+	}
+	if m.Options[len(m.Options)-1] != "ro" {
+		t.Errorf("Runtime socket should be mounted read-only: %v", m.Options)
+	}
+
+	env := mountEnv(wsMounts)
+	if len(env) != 1 || env[0] != "CONTAINERD_ADDRESS="+RuntimeSocketPath {
+		t.Errorf("Runtime socket address not set in the environment: %v", env)
+	}
+	if len(mountEnv(nil)) != 0 {
+		t.Errorf("Environment should be empty without runtime socket")
+	}
+}
+
+func TestSpecApplyResources(t *testing.T) {
+
+	spec, err := DefaultSpec("test", "ctr")
+	if err != nil {
+		t.Fatalf("Failed to create spec: %v", err)
+	}
+
+	err = applyResources(&spec, project.Resources{})
+	if err != nil {
+		t.Fatalf("Failed to apply resources: %v", err)
+	}
+	if spec.Linux.Resources.CPU != nil || spec.Linux.Resources.Memory != nil {
+		t.Errorf("Empty resources should not set limits")
+	}
+
+	err = applyResources(&spec, project.Resources{CPUs: 1.5, CPUShares: 512, Memory: 1 << 30})
+	if err != nil {
+		t.Fatalf("Failed to apply resources: %v", err)
+	}
+	cpu := spec.Linux.Resources.CPU
+	if cpu == nil || *cpu.Quota != 150000 || *cpu.Period != 100000 || *cpu.Shares != 512 {
+		t.Errorf("Unexpected CPU limits: %v", cpu)
+	}
+	mem := spec.Linux.Resources.Memory
+	if mem == nil || *mem.Limit != 1<<30 {
+		t.Errorf("Unexpected memory limit: %v", mem)
+	}
+	if len(spec.Linux.Resources.Devices) == 0 {
+		t.Errorf("Device rules should be preserved")
+	}
+
+	for _, res := range []project.Resources{{CPUs: -1}, {Memory: -1}} {
+		if err := applyResources(&spec, res); err == nil {
+			t.Errorf("Invalid resources %v should fail", res)
+		}
+	}
+}
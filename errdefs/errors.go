@@ -28,6 +28,13 @@ var (
 	ErrInternalError = errors.New("internal error")
 	// error: internal error: <description>
 	ErrInUse = errors.New("in use")
+	// error: <resource> '<name>' is in use
+	ErrCanceled = errors.New("canceled")
+	// error: <operation> '<name>' canceled
+	ErrPermissionDenied = errors.New("permission denied")
+	// error: permission denied for <resource> '<name>'
+	ErrUnavailable = errors.New("unavailable")
+	// error: <resource> '<name>' is unavailable: <error>
 
 	// pass-through errors
 	ErrCommandFailed   = errors.New("cmd failed")
@@ -128,6 +135,30 @@ func InUse(resource, name string) error {
 	}
 }
 
+func Canceled(operation, name string) error {
+	return &cneError{
+		cause:    ErrCanceled,
+		resource: operation,
+		msg:      fmt.Sprintf("%s '%s' canceled", operation, name),
+	}
+}
+
+func PermissionDenied(resource, name string) error {
+	return &cneError{
+		cause:    ErrPermissionDenied,
+		resource: resource,
+		msg:      fmt.Sprintf("permission denied for %s '%s'", resource, name),
+	}
+}
+
+func Unavailable(resource, name string, err error) error {
+	return &cneError{
+		cause:    ErrUnavailable,
+		resource: resource,
+		msg:      fmt.Sprintf("%s '%s' is unavailable: %v", resource, name, err),
+	}
+}
+
 func InternalError(format string, args ...interface{}) error {
 	return &cneError{
 		cause: ErrInternalError,
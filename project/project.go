@@ -3,6 +3,7 @@ package project
 
 import (
 	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -66,10 +67,50 @@ type Project struct {
 // pipeline by propagating results to the following workspace.
 // Note that Image cannot be changed and requires to create a new workspace
 type Workspace struct {
-	Name        string // Name of the workspace (must be unique)
-	ProjectUUID string `yaml:"-" output:"-"`
-	Path        string `yaml:"-"`
-	Environment Environment
+	Name          string // Name of the workspace (must be unique)
+	ContainerName string `yaml:",omitempty" output:"-"` // Container name if not the name
+	ProjectUUID   string `yaml:"-" output:"-"`
+	ProjectName   string `yaml:"-" output:"-"`
+	Path          string `yaml:"-"`
+	Environment   Environment
+	PostBuild     PostBuild  `yaml:",omitempty"`
+	Mounts        []Mount    `yaml:",omitempty"`
+	Resources     Resources  `yaml:",omitempty"`
+	Shell         string     `yaml:",omitempty"` // Shell for commands; overrides the user shell
+	Snapshots     []Snapshot `yaml:",omitempty"`
+}
+
+// Snapshot describes a named save-point of the workspace container. Restoring the snapshot
+// restores the filesystem of the container and the environment of the workspace.
+type Snapshot struct {
+	Name        string
+	Generation  string      // Generation of the container
+	Snapshot    string      `output:"-"` // Committed snapshot of the container filesystem
+	CreatedAt   time.Time   // Time the snapshot was created
+	Environment Environment `output:"-"` // Environment of the workspace
+}
+
+// Resources describes the resource limits of the workspace container. Zero values don't
+// limit the resource.
+type Resources struct {
+	CPUs      float64 `yaml:",omitempty"` // Number of CPUs (CPU quota)
+	CPUShares uint64  `yaml:",omitempty"` // Relative CPU weight
+	Memory    int64   `yaml:",omitempty"` // Memory limit in bytes
+}
+
+// Mount describes a bind mount of a host directory into the container.
+// A mount without a source removes a default mount for the same destination.
+type Mount struct {
+	Source      string
+	Destination string
+	ReadOnly    bool `yaml:",omitempty"`
+}
+
+// PostBuild describes a command that is executed inside the container after a successful build.
+// A failing command fails the build unless IgnoreErrors is set.
+type PostBuild struct {
+	Args         []string `output:"flat" yaml:",flow"`
+	IgnoreErrors bool     `yaml:",omitempty"`
 }
 
 // Environment describes the container-native environment
@@ -79,9 +120,18 @@ type Workspace struct {
 //  * "auto"   -  packages will be updated whenever the package layer(s) are rebuild
 // Note that the image needs to be pulled manually to cause an update (using 'pull')
 type Environment struct {
-	Origin string // Name or link of the base image
-	Update string // Update package strategy: One of "never", "manual", "auto"
-	Layers []Layer
+	Origin  string            // Name or link of the base image
+	Update  string            // Update package strategy: One of "never", "manual", "auto"
+	Env     map[string]string `yaml:",omitempty"` // Environment variables of all processes
+	Layers  []Layer
+	Commits []Commit `yaml:",omitempty"` // Changes committed on top of the layers
+}
+
+// Commit describes a snapshot of the container filesystem that was committed manually.
+type Commit struct {
+	Message   string
+	Snapshot  string    `output:"-"`
+	CreatedAt time.Time `hash:"-"`
 }
 
 // Layer describes an 'overlay' layer. This can be virtual or explicit using an overlay FS
@@ -89,7 +139,8 @@ type Environment struct {
 type Layer struct {
 	Name     string // Unique name for the layer in the workspace; must not contain '/'
 	Type     string // one of the system layer types or custom layer
-	Digest   string `output:"-"` // Images/Snaps for faster rebuilds
+	Digest   string `output:"-"`                         // Images/Snaps for faster rebuilds
+	Disabled bool   `yaml:",omitempty" hash:"omitempty"` // Skip the commands when building
 	Commands []Command
 }
 
@@ -217,6 +268,7 @@ func Load(path string) (*Project, error) {
 	// Fixup workspaces
 	for i := 0; i < len(prj.Workspaces); i++ {
 		prj.Workspaces[i].ProjectUUID = prj.UUID
+		prj.Workspaces[i].ProjectName = prj.Name
 		prj.Workspaces[i].Path = prj.path
 	}
 
@@ -248,7 +300,19 @@ func (prj *Project) Write() error {
 
 // Delete removes the CNE project file
 func (prj *Project) Delete() error {
-	return os.Remove(prj.path)
+	return os.Remove(prj.path + "/" + projectFileName)
+}
+
+// Chown changes the owner of the project file, for example, to the user that invoked the
+// command through sudo.
+func (prj *Project) Chown(uid, gid int) error {
+
+	path := prj.path + "/" + projectFileName
+	if err := os.Chown(path, uid, gid); err != nil {
+		return errdefs.SystemError(err,
+			"failed to change the owner of the project file in '%s'", prj.path)
+	}
+	return nil
 }
 
 // CurrentWorkspace retuns a pointer to the current workspace or nil if unset or no workspaces.
@@ -310,6 +374,7 @@ func (prj *Project) CreateWorkspace(name string, origin string, before string) (
 	workspace := Workspace{
 		Name:        name,
 		ProjectUUID: prj.UUID,
+		ProjectName: prj.Name,
 		Environment: Environment{Origin: origin, Layers: []Layer{}},
 		Path:        "",
 	}
@@ -326,6 +391,7 @@ func (prj *Project) CreateWorkspace(name string, origin string, before string) (
 	if before != "" && idx == len(prj.Workspaces) {
 		return nil, errdefs.NotFound("workspace", workspace.Name)
 	}
+	workspace.ContainerName = prj.containerName(workspace.Name)
 
 	prj.Workspaces = append(prj.Workspaces[:idx],
 		append([]Workspace{workspace}, prj.Workspaces[idx:]...)...)
@@ -333,6 +399,89 @@ func (prj *Project) CreateWorkspace(name string, origin string, before string) (
 	return &prj.Workspaces[idx], nil
 }
 
+// containerName returns the name for identifying the container of a new workspace or an empty
+// string if the workspace name can be used. A renamed workspace keeps its original name for
+// the container, so a new workspace with that name gets a unique container name.
+func (prj *Project) containerName(name string) string {
+
+	used := func(n string) bool {
+		for _, ws := range prj.Workspaces {
+			if ws.Name == n || ws.ContainerName == n {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !used(name) {
+		return ""
+	}
+	for i := 1; ; i++ {
+		n := name + "-" + strconv.Itoa(i)
+		if !used(n) {
+			return n
+		}
+	}
+}
+
+// RenameWorkspace renames the specified workspace and updates the current workspace if it
+// referenced the workspace. It returns ErrAlreadyExists if the new name is already used.
+func (prj *Project) RenameWorkspace(oldName, newName string) error {
+
+	if newName == "" {
+		return errdefs.InvalidArgument("workspace name cannot be empty")
+	}
+
+	for _, ws := range prj.Workspaces {
+		if ws.Name == newName {
+			return errdefs.AlreadyExists("workspace", newName)
+		}
+	}
+
+	for i, ws := range prj.Workspaces {
+		if ws.Name == oldName {
+			prj.Workspaces[i].Rename(newName)
+			if prj.CurrentWorkspaceName == oldName {
+				prj.CurrentWorkspaceName = newName
+			}
+			return nil
+		}
+	}
+
+	return errdefs.NotFound("workspace", oldName)
+}
+
+// CloneWorkspace creates a copy of the workspace src with the name dst and inserts it after
+// the source workspace. If dst is empty, the name of the source workspace with a '-clone'
+// suffix is used. The clone gets its own container, which has to be built separately.
+func (prj *Project) CloneWorkspace(src, dst string) (*Workspace, error) {
+
+	if dst == "" {
+		dst = src + "-clone"
+	}
+
+	idx := -1
+	for i, ws := range prj.Workspaces {
+		if ws.Name == src {
+			idx = i
+		}
+		// a renamed workspace still uses the original name for the container
+		if ws.Name == dst || ws.ContainerName == dst {
+			return nil, errdefs.AlreadyExists("workspace", dst)
+		}
+	}
+	if idx == -1 {
+		return nil, errdefs.NotFound("workspace", src)
+	}
+
+	clone := prj.Workspaces[idx].Clone(dst)
+	idx++
+	prj.Workspaces = append(prj.Workspaces[:idx],
+		append([]Workspace{*clone}, prj.Workspaces[idx:]...)...)
+
+	return &prj.Workspaces[idx], nil
+}
+
 // DeleteWorkspace removes the specified workspace.
 // If it was the current workspace, the current workspace will become unset
 func (prj *Project) DeleteWorkspace(name string) error {
@@ -368,6 +517,9 @@ func hashValueElem(w io.Writer, prefix string, elem reflect.Value, deep bool) {
 		for i := 0; i < elem.NumField(); i++ {
 			field := elemType.Field(i)
 			tag := field.Tag.Get("hash")
+			if tag == "omitempty" && elem.Field(i).IsZero() {
+				continue
+			}
 			if tag != "-" {
 				hashValueElem(w, prefix+field.Name, elem.Field(i), true)
 			}
@@ -397,9 +549,78 @@ func hashValueElem(w io.Writer, prefix string, elem reflect.Value, deep bool) {
 
 // ID returns an identification for the workspace
 func (ws *Workspace) ID() [16]byte {
+	if ws.ContainerName != "" {
+		return md5.Sum([]byte(ws.ContainerName))
+	}
 	return md5.Sum([]byte(ws.Name))
 }
 
+// Rename renames the workspace. The workspace keeps the original name for identifying the
+// container, so the container doesn't have to be rebuilt.
+func (ws *Workspace) Rename(name string) {
+	if ws.ContainerName == "" {
+		ws.ContainerName = ws.Name
+	}
+	ws.Name = name
+}
+
+// Clone returns a deep copy of the workspace with the provided name. The clone doesn't keep the
+// original container name of a renamed workspace, so it is identified by its new name, and it
+// doesn't include the commits and snapshots of the workspace container.
+func (ws *Workspace) Clone(name string) *Workspace {
+
+	clone := *ws
+	clone.Name = name
+	clone.ContainerName = ""
+	clone.Environment = cloneEnvironment(&ws.Environment)
+	clone.Environment.Commits = nil
+	clone.PostBuild.Args = append([]string(nil), ws.PostBuild.Args...)
+	clone.Mounts = append([]Mount(nil), ws.Mounts...)
+	clone.Snapshots = nil
+
+	return &clone
+}
+
+// cloneEnvironment is a helper function returning a deep copy of the environment.
+func cloneEnvironment(env *Environment) Environment {
+
+	clone := *env
+	if env.Env != nil {
+		clone.Env = make(map[string]string, len(env.Env))
+		for k, v := range env.Env {
+			clone.Env[k] = v
+		}
+	}
+	if env.Layers != nil {
+		clone.Layers = make([]Layer, len(env.Layers))
+		for i, l := range env.Layers {
+			clone.Layers[i] = l
+			clone.Layers[i].Commands = cloneCommands(l.Commands)
+		}
+	}
+	clone.Commits = append([]Commit(nil), env.Commits...)
+
+	return clone
+}
+
+// cloneCommands is a helper function returning a deep copy of the commands.
+func cloneCommands(commands []Command) []Command {
+
+	if commands == nil {
+		return nil
+	}
+
+	clone := make([]Command, len(commands))
+	for i, c := range commands {
+		clone[i] = Command{
+			Name: c.Name,
+			Envs: append([]string(nil), c.Envs...),
+			Args: append([]string(nil), c.Args...),
+		}
+	}
+	return clone
+}
+
 // BaseHash returns a unique hash value for a build container
 func (ws *Workspace) BaseHash() [16]byte {
 
@@ -484,6 +705,181 @@ func (ws *Workspace) DeleteLayer(name string) error {
 	return nil
 }
 
+// AddMount adds the mount to the workspace. It returns ErrAlreadyExists if the workspace
+// already has a mount for the destination.
+func (ws *Workspace) AddMount(mount Mount) error {
+
+	for _, m := range ws.Mounts {
+		if filepath.Clean(m.Destination) == filepath.Clean(mount.Destination) {
+			return errdefs.AlreadyExists("mount", mount.Destination)
+		}
+	}
+	ws.Mounts = append(ws.Mounts, mount)
+	return nil
+}
+
+// DeleteMount removes the mount for the destination from the workspace.
+func (ws *Workspace) DeleteMount(dest string) error {
+
+	for i, m := range ws.Mounts {
+		if filepath.Clean(m.Destination) == filepath.Clean(dest) {
+			ws.Mounts = append(ws.Mounts[:i], ws.Mounts[i+1:]...)
+			return nil
+		}
+	}
+	return errdefs.NotFound("mount", dest)
+}
+
+// SetEnv sets the environment variable for the workspace.
+func (ws *Workspace) SetEnv(key, value string) error {
+
+	if key == "" || strings.Contains(key, "=") {
+		return errdefs.InvalidArgument("invalid environment variable name: '%s'", key)
+	}
+	if ws.Environment.Env == nil {
+		ws.Environment.Env = map[string]string{}
+	}
+	ws.Environment.Env[key] = value
+	return nil
+}
+
+// UnsetEnv removes the environment variable from the workspace.
+func (ws *Workspace) UnsetEnv(key string) error {
+
+	if _, ok := ws.Environment.Env[key]; !ok {
+		return errdefs.NotFound("environment variable", key)
+	}
+	delete(ws.Environment.Env, key)
+	if len(ws.Environment.Env) == 0 {
+		ws.Environment.Env = nil
+	}
+	return nil
+}
+
+// AddSnapshot records a named snapshot of the workspace container with the current
+// environment of the workspace. It returns ErrAlreadyExists if the name is already used.
+func (ws *Workspace) AddSnapshot(name string, generation [16]byte, snapName string) error {
+
+	if name == "" {
+		return errdefs.InvalidArgument("snapshot name cannot be empty")
+	}
+	if _, err := ws.FindSnapshot(name); err == nil {
+		return errdefs.AlreadyExists("snapshot", name)
+	}
+
+	ws.Snapshots = append(ws.Snapshots, Snapshot{
+		Name:        name,
+		Generation:  hex.EncodeToString(generation[:]),
+		Snapshot:    snapName,
+		CreatedAt:   time.Now(),
+		Environment: cloneEnvironment(&ws.Environment),
+	})
+	return nil
+}
+
+// FindSnapshot returns the named snapshot of the workspace.
+func (ws *Workspace) FindSnapshot(name string) (*Snapshot, error) {
+
+	for i := range ws.Snapshots {
+		if ws.Snapshots[i].Name == name {
+			return &ws.Snapshots[i], nil
+		}
+	}
+	return nil, errdefs.NotFound("snapshot", name)
+}
+
+// RestoreSnapshot restores the environment of the workspace from the named snapshot.
+func (ws *Workspace) RestoreSnapshot(name string) error {
+
+	snap, err := ws.FindSnapshot(name)
+	if err != nil {
+		return err
+	}
+	ws.Environment = cloneEnvironment(&snap.Environment)
+	return nil
+}
+
+// EnvList returns the environment variables of the workspace as KEY=VALUE entries sorted
+// by the key.
+func (ws *Workspace) EnvList() []string {
+
+	keys := make([]string, 0, len(ws.Environment.Env))
+	for k := range ws.Environment.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, len(keys))
+	for i, k := range keys {
+		env[i] = k + "=" + ws.Environment.Env[k]
+	}
+	return env
+}
+
+// ClearLayerCache invalidates the snapshots of all layers, so the next build rebuilds all
+// layers from the base image.
+func (ws *Workspace) ClearLayerCache() {
+	for i := range ws.Environment.Layers {
+		ws.Environment.Layers[i].Digest = ""
+	}
+}
+
+// EnableLayer enables or disables the layer and invalidates the snapshots of all layers
+// starting with the layer.
+func (ws *Workspace) EnableLayer(name string, enable bool) error {
+
+	_, layer := ws.FindLayer(name)
+	if layer == nil {
+		return errdefs.NotFound("layer", name)
+	}
+	if layer.Disabled != enable {
+		return nil
+	}
+	layer.Disabled = !enable
+	ws.UpdateLayer(layer)
+	return nil
+}
+
+// MoveLayer moves the layer before or, if after is set, after the other layer. The snapshots
+// of all layers starting with the first changed position are invalidated.
+func (ws *Workspace) MoveLayer(name, other string, after bool) error {
+
+	if name == other {
+		return errdefs.InvalidArgument("cannot move layer '%s' relative to itself", name)
+	}
+	from, _ := ws.FindLayer(name)
+	if from == -1 {
+		return errdefs.NotFound("layer", name)
+	}
+	if idx, _ := ws.FindLayer(other); idx == -1 {
+		return errdefs.NotFound("layer", other)
+	}
+
+	layer := ws.Environment.Layers[from]
+	ws.Environment.Layers = append(ws.Environment.Layers[:from],
+		ws.Environment.Layers[from+1:]...)
+
+	to, _ := ws.FindLayer(other)
+	if after {
+		to++
+	}
+	layers := append(ws.Environment.Layers[:to],
+		append([]Layer{layer}, ws.Environment.Layers[to:]...)...)
+	ws.Environment.Layers = layers
+
+	if from == to {
+		return nil
+	}
+	first := from
+	if to < first {
+		first = to
+	}
+	for i := first; i < len(layers); i++ {
+		layers[i].Digest = ""
+	}
+	return nil
+}
+
 // TopLayer returns the pointer to the top layer.
 func (ws *Workspace) TopLayer() *Layer {
 	cnt := len(ws.Environment.Layers)
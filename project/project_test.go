@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -76,6 +77,35 @@ func TestProjectCreateExistingProject(t *testing.T) {
 	}
 }
 
+func TestProjectDelete(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	err = prj.Chown(os.Getuid(), os.Getgid())
+	if err != nil {
+		t.Errorf("Failed to change the owner of the project: %v", err)
+	}
+
+	err = prj.Delete()
+	if err != nil {
+		t.Fatalf("Failed to delete project: %v", err)
+	}
+
+	_, err = Load(dir)
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Project should have been deleted: %v", err)
+	}
+}
+
 // create project in dir test1
 // copy project to test2
 // update project
@@ -507,3 +537,335 @@ func TestProjectLayers(t *testing.T) {
 		t.Fatalf("Number of layers should be 0")
 	}
 }
+
+func TestProjectRenameWorkspace(t *testing.T) {
+
+	prj := NewProject("test", "/some/path")
+	for _, name := range []string{"ws0", "ws1"} {
+		_, err := prj.CreateWorkspace(name, "image", "")
+		if err != nil {
+			t.Fatalf("Failed to create workspace %s", name)
+		}
+	}
+	prj.CurrentWorkspaceName = "ws0"
+
+	ws, _ := prj.Workspace("ws0")
+	id := ws.ID()
+
+	err := prj.RenameWorkspace("ws0", "ws1")
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Renaming to an existing workspace should fail: %v", err)
+	}
+	err = prj.RenameWorkspace("ws2", "ws3")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Renaming a non-existing workspace should fail: %v", err)
+	}
+
+	err = prj.RenameWorkspace("ws0", "renamed")
+	if err != nil {
+		t.Fatalf("Failed to rename workspace: %v", err)
+	}
+	if prj.CurrentWorkspaceName != "renamed" {
+		t.Errorf("Current workspace not updated: %s", prj.CurrentWorkspaceName)
+	}
+	ws, err = prj.Workspace("renamed")
+	if err != nil {
+		t.Fatalf("Renamed workspace not found")
+	}
+	if ws.ID() != id {
+		t.Errorf("Workspace ID changed after rename")
+	}
+
+	err = prj.RenameWorkspace("renamed", "again")
+	if err != nil || ws.ID() != id {
+		t.Errorf("Workspace ID changed after second rename: %v", err)
+	}
+
+	// a new workspace with the original name must not use the container of the renamed one
+	for _, name := range []string{"ws0", "ws0-1"} {
+		newWs, err := prj.CreateWorkspace(name, "image", "")
+		if err != nil {
+			t.Fatalf("Failed to create workspace %s after rename: %v", name, err)
+		}
+		for _, w := range prj.Workspaces {
+			if w.Name != name && w.ID() == newWs.ID() {
+				t.Errorf("Workspace %s uses the ID of workspace %s", name, w.Name)
+			}
+		}
+	}
+}
+
+func TestProjectCloneWorkspace(t *testing.T) {
+
+	prj := NewProject("test", "/some/path")
+	src, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	src.SetEnv("DEBUG", "1")
+	src.AddMount(Mount{Source: "/src", Destination: "/work"})
+	layer, err := src.CreateLayer(false, "tools", -1)
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Commands = []Command{{Args: []string{"apt", "install", "vim"}}}
+
+	_, err = prj.CloneWorkspace("missing", "")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Cloning a non-existing workspace should fail: %v", err)
+	}
+	_, err = prj.CloneWorkspace("ws", "ws")
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Cloning to an existing workspace should fail: %v", err)
+	}
+
+	clone, err := prj.CloneWorkspace("ws", "")
+	if err != nil {
+		t.Fatalf("Failed to clone workspace: %v", err)
+	}
+	src, _ = prj.Workspace("ws")
+	hash := src.ConfigHash()
+
+	if clone.Name != "ws-clone" || clone.ID() == src.ID() {
+		t.Errorf("Clone should have a new name and id: %s", clone.Name)
+	}
+	if clone.ConfigHash() != hash {
+		t.Errorf("Clone should have the same configuration")
+	}
+
+	clone.Environment.Layers[0].Commands[0].Args[2] = "emacs"
+	clone.Environment.Layers[0].Name = "editors"
+	clone.SetEnv("DEBUG", "0")
+	clone.Mounts[0].ReadOnly = true
+	if _, err = clone.CreateLayer(false, "more", -1); err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+
+	src, _ = prj.Workspace("ws")
+	if src.ConfigHash() != hash || len(src.Environment.Layers) != 1 ||
+		src.Environment.Layers[0].Commands[0].Args[2] != "vim" ||
+		src.Environment.Env["DEBUG"] != "1" || src.Mounts[0].ReadOnly {
+		t.Errorf("Changing the clone modified the source workspace")
+	}
+
+	// commits refer to snapshots of the source container
+	src.Environment.Commits = []Commit{{Message: "first", Snapshot: "snap1"}}
+	clone, err = prj.CloneWorkspace("ws", "ws-commits")
+	if err != nil {
+		t.Fatalf("Failed to clone workspace: %v", err)
+	}
+	src, _ = prj.Workspace("ws")
+	if len(clone.Environment.Commits) != 0 || len(src.Environment.Commits) != 1 {
+		t.Errorf("Clone should not include the commits: %v", clone.Environment.Commits)
+	}
+}
+
+func TestProjectWorkspaceEnv(t *testing.T) {
+
+	prj := NewProject("test", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	hash := ws.ConfigHash()
+
+	if err := ws.SetEnv("DEBUG", "1"); err != nil {
+		t.Fatalf("Failed to set variable: %v", err)
+	}
+	if err := ws.SetEnv("A", "b=c"); err != nil {
+		t.Fatalf("Failed to set variable: %v", err)
+	}
+	for _, key := range []string{"", "A=B"} {
+		if err := ws.SetEnv(key, "1"); err == nil {
+			t.Errorf("Setting variable '%s' should fail", key)
+		}
+	}
+
+	env := ws.EnvList()
+	if len(env) != 2 || env[0] != "A=b=c" || env[1] != "DEBUG=1" {
+		t.Errorf("Unexpected environment: %v", env)
+	}
+	if ws.ConfigHash() == hash {
+		t.Errorf("Environment variables should change the configuration hash")
+	}
+
+	if err := ws.UnsetEnv("MISSING"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Unsetting a missing variable should fail: %v", err)
+	}
+	ws.UnsetEnv("A")
+	ws.UnsetEnv("DEBUG")
+	if ws.Environment.Env != nil || ws.ConfigHash() != hash {
+		t.Errorf("Removing all variables should restore the configuration")
+	}
+}
+
+func TestProjectWorkspaceMounts(t *testing.T) {
+
+	prj := NewProject("test", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	err = ws.AddMount(Mount{Source: "/src", Destination: "/work"})
+	if err != nil {
+		t.Fatalf("Failed to add mount: %v", err)
+	}
+	err = ws.AddMount(Mount{Source: "/other", Destination: "/work/"})
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Adding a mount for the same destination should fail: %v", err)
+	}
+
+	if err := ws.DeleteMount("/missing"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Deleting a missing mount should fail: %v", err)
+	}
+	if err := ws.DeleteMount("/work/"); err != nil || len(ws.Mounts) != 0 {
+		t.Errorf("Failed to delete mount: %v %v", err, ws.Mounts)
+	}
+}
+
+func TestProjectLayerTemplates(t *testing.T) {
+
+	for _, name := range TemplateNames() {
+		cmds, err := TemplateCommands(name, []string{"pkg1", "pkg2"})
+		if err != nil || len(cmds) == 0 {
+			t.Fatalf("Template %s failed: %v", name, err)
+		}
+		args := cmds[len(cmds)-1].Args
+		if len(args) < 2 || args[len(args)-2] != "pkg1" || args[len(args)-1] != "pkg2" {
+			t.Errorf("Template %s doesn't install the packages: %v", name, args)
+		}
+	}
+
+	cmds, _ := TemplateCommands("apt", []string{"vim"})
+	if len(cmds) != 2 || strings.Join(cmds[0].Args, " ") != "apt-get update" ||
+		strings.Join(cmds[1].Args, " ") != "apt-get install -y vim" {
+		t.Errorf("Unexpected apt commands: %v", cmds)
+	}
+
+	if _, err := TemplateCommands("none", []string{"vim"}); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Unknown template should fail: %v", err)
+	}
+	if _, err := TemplateCommands("apt", nil); err == nil {
+		t.Errorf("Template without packages should fail")
+	}
+}
+
+func TestProjectMoveLayer(t *testing.T) {
+
+	prj := NewProject("test", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	reset := func() {
+		ws.Environment.Layers = []Layer{
+			{Name: "a", Digest: "da"},
+			{Name: "b", Digest: "db"},
+			{Name: "c", Digest: "dc"},
+			{Name: "d", Digest: "dd"},
+		}
+	}
+	check := func(names, digests string) {
+		t.Helper()
+		n, d := "", ""
+		for _, l := range ws.Environment.Layers {
+			n += l.Name
+			if l.Digest != "" {
+				d += l.Name
+			}
+		}
+		if n != names || d != digests {
+			t.Errorf("Expected layers %s with digests %s, got %s and %s", names, digests, n, d)
+		}
+	}
+
+	// first layer to the end
+	reset()
+	if err := ws.MoveLayer("a", "d", true); err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	check("bcda", "")
+
+	// last layer to the beginning
+	reset()
+	if err := ws.MoveLayer("d", "a", false); err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	check("dabc", "")
+
+	// only layers from the first changed position are invalidated
+	reset()
+	if err := ws.MoveLayer("d", "c", false); err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	check("abdc", "ab")
+
+	// moving to the same position keeps the snapshots
+	reset()
+	if err := ws.MoveLayer("b", "c", false); err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	check("abcd", "abcd")
+
+	reset()
+	for _, tc := range [][2]string{{"x", "a"}, {"a", "x"}, {"a", "a"}} {
+		if err := ws.MoveLayer(tc[0], tc[1], false); err == nil {
+			t.Errorf("Moving %s before %s should fail", tc[0], tc[1])
+		}
+	}
+	check("abcd", "abcd")
+}
+
+func TestProjectEnableLayer(t *testing.T) {
+
+	prj := NewProject("test", "/some/path")
+	ws, err := prj.CreateWorkspace("ws", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.Environment.Layers = []Layer{
+		{Name: "a", Digest: "da"},
+		{Name: "b", Digest: "db"},
+		{Name: "c", Digest: "dc"},
+	}
+	hash := ws.ConfigHash()
+
+	// enabling an enabled layer keeps the snapshots
+	if err := ws.EnableLayer("b", true); err != nil || ws.Environment.Layers[1].Digest != "db" {
+		t.Errorf("Enabling an enabled layer should not change it: %v", err)
+	}
+
+	if err := ws.EnableLayer("b", false); err != nil {
+		t.Fatalf("Failed to disable layer: %v", err)
+	}
+	layers := ws.Environment.Layers
+	if !layers[1].Disabled || layers[0].Digest != "da" ||
+		layers[1].Digest != "" || layers[2].Digest != "" {
+		t.Errorf("Unexpected layers after disabling: %v", layers)
+	}
+	if ws.ConfigHash() == hash {
+		t.Errorf("Disabling a layer should change the configuration hash")
+	}
+
+	ws.Environment.Layers[1].Digest = "db"
+	ws.Environment.Layers[2].Digest = "dc"
+	if err := ws.EnableLayer("b", true); err != nil || layers[1].Disabled {
+		t.Fatalf("Failed to enable layer: %v", err)
+	}
+	if layers[1].Digest != "" || ws.ConfigHash() == hash {
+		t.Errorf("Enabling a layer should invalidate the snapshots")
+	}
+
+	// enabled layers don't contribute to the hash
+	ws.Environment.Layers[1].Digest = "db"
+	ws.Environment.Layers[2].Digest = "dc"
+	if ws.ConfigHash() != hash {
+		t.Errorf("Enabled layers should not change the configuration hash")
+	}
+
+	if err := ws.EnableLayer("x", false); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Disabling a missing layer should fail: %v", err)
+	}
+}
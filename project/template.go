@@ -0,0 +1,60 @@
+package project
+
+import (
+	"sort"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// LayerTemplate returns the commands of a layer that installs the provided packages.
+type LayerTemplate func(packages []string) []Command
+
+// LayerTemplates are the built-in templates for creating layers, keyed by the template name.
+var LayerTemplates = map[string]LayerTemplate{
+	"apt": func(packages []string) []Command {
+		env := []string{"DEBIAN_FRONTEND=noninteractive"}
+		return []Command{
+			{Envs: env, Args: []string{"apt-get", "update"}},
+			{Envs: env, Args: append([]string{"apt-get", "install", "-y"}, packages...)},
+		}
+	},
+	"apk": func(packages []string) []Command {
+		return []Command{
+			{Envs: []string{}, Args: append([]string{"apk", "add", "--no-cache"}, packages...)},
+		}
+	},
+	"pip": func(packages []string) []Command {
+		return []Command{
+			{Envs: []string{}, Args: append([]string{"pip", "install"}, packages...)},
+		}
+	},
+	"npm": func(packages []string) []Command {
+		return []Command{
+			{Envs: []string{}, Args: append([]string{"npm", "install", "-g"}, packages...)},
+		}
+	},
+}
+
+// TemplateNames returns the sorted names of the layer templates.
+func TemplateNames() []string {
+
+	names := make([]string, 0, len(LayerTemplates))
+	for n := range LayerTemplates {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TemplateCommands returns the commands generated by the template for the packages.
+func TemplateCommands(template string, packages []string) ([]Command, error) {
+
+	tmpl, ok := LayerTemplates[template]
+	if !ok {
+		return nil, errdefs.NotFound("layer template", template)
+	}
+	if len(packages) == 0 {
+		return nil, errdefs.InvalidArgument("no packages for layer template '%s'", template)
+	}
+	return tmpl(packages), nil
+}
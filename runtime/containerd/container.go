@@ -5,16 +5,27 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	cgroupsv1 "github.com/containerd/cgroups/stats/v1"
+	cgroupsv2 "github.com/containerd/cgroups/v2/stats"
 	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/cio"
 	"github.com/containerd/containerd/containers"
 	ctrderr "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
 	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/runtime/v2/runc/options"
+	"github.com/containerd/continuity/fs"
 	"github.com/containerd/typeurl"
 
 	runspecs "github.com/opencontainers/runtime-spec/specs-go"
@@ -27,11 +38,13 @@ import (
 
 type container struct {
 	domain        [16]byte
+	domainName    string
 	id            [16]byte
 	generation    [16]byte
 	uid           uint32
 	spec          runspecs.Spec
 	image         *image
+	ephemeral     bool
 	ctrdRuntime   *containerdRuntime
 	ctrdContainer containerd.Container
 }
@@ -99,6 +112,28 @@ func getUID(ctrdRun *containerdRuntime, ctrdCtr containerd.Container) (uint32, e
 	return uint32(uid), nil
 }
 
+// getDomainName returns the name of the domain of a containerD Container or an empty
+// string if the name is unknown.
+func getDomainName(ctrdRun *containerdRuntime, ctrdCtr containerd.Container) string {
+
+	labels, err := ctrdCtr.Labels(ctrdRun.context)
+	if err != nil {
+		return ""
+	}
+	return labels[containerdDomainNameLabel]
+}
+
+// isEphemeral returns true if the containerD Container is a temporary container.
+func isEphemeral(ctrdRun *containerdRuntime, ctrdCtr containerd.Container) bool {
+
+	labels, err := ctrdCtr.Labels(ctrdRun.context)
+	if err != nil {
+		return false
+	}
+	_, ok := labels[containerdEphemeralLabel]
+	return ok
+}
+
 // getGenerationString returns the generation of a containerD Container as a string.
 func getGenerationString(ctrdRun *containerdRuntime, ctrdCtr containerd.Container) string {
 
@@ -110,41 +145,60 @@ func getGenerationString(ctrdRun *containerdRuntime, ctrdCtr containerd.Containe
 	return labels[containerdGenerationLabel]
 }
 
-// getContainers returns all containers in the specified domain
-func getContainers(ctrdRun *containerdRuntime, filters ...interface{}) ([]runtime.Container, error) {
-
-	var runCtrs []runtime.Container
+// getContainers returns all containers in the specified domain and warnings for the
+// containers that were skipped.
+func getContainers(ctrdRun *containerdRuntime,
+	filters ...interface{}) ([]runtime.Container, []string, error) {
 
 	hasDomain := false
 	var domain [16]byte
 
 	if len(filters) > 1 {
-		return nil, errdefs.InvalidArgument("too many arguments to get containers")
+		return nil, nil, errdefs.InvalidArgument("too many arguments to get containers")
 	}
 	if len(filters) == 1 {
 		domain, hasDomain = filters[0].([16]byte)
 		if !hasDomain {
-			return nil, errdefs.InvalidArgument("invalid arguments for getting containers")
+			return nil, nil, errdefs.InvalidArgument("invalid arguments for getting containers")
 		}
 	}
 
 	ctrdCtrs, err := ctrdRun.client.Containers(ctrdRun.context)
 	if err != nil {
-		return nil, runtime.Errorf("failed to get containers: %v", err)
+		return nil, nil, runtime.Errorf("failed to get containers: %v", err)
 	}
 
+	return readContainers(ctrdRun, ctrdCtrs, hasDomain, domain)
+}
+
+// readContainers returns the runtime containers for the containerD Containers. If hasDomain is
+// set, it only returns the containers in the provided domain. Containers whose image or spec
+// cannot be read are skipped and reported in the returned warnings.
+func readContainers(ctrdRun *containerdRuntime, ctrdCtrs []containerd.Container,
+	hasDomain bool, domain [16]byte) ([]runtime.Container, []string, error) {
+
+	var runCtrs []runtime.Container
+	warnings := []string{}
+
+	// share the images between the containers to read their configuration only once
+	imgs := map[string]*image{}
+
 	// skip containers where we cannot read certain variables
 	for _, c := range ctrdCtrs {
 
 		dom, id, err := splitCtrdID(c.ID())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if hasDomain && dom != domain {
 			continue
 		}
 
+		if isEphemeral(ctrdRun, c) {
+			continue
+		}
+
 		gen, err := getGeneration(ctrdRun, c)
 		if err != nil {
 			continue
@@ -157,22 +211,30 @@ func getContainers(ctrdRun *containerdRuntime, filters ...interface{}) ([]runtim
 
 		img, err := c.Image(ctrdRun.context)
 		if err != nil {
-			return nil, runtime.Errorf("failed to get image: %v", err)
+			warnings = append(warnings, fmt.Sprintf(
+				"skipping container %s: failed to get image: %v", c.ID(), err))
+			continue
 		}
 
 		spec, err := c.Spec(ctrdRun.context)
 		if err != nil {
-			return nil, runtime.Errorf("failed to get image spec: %v", err)
+			warnings = append(warnings, fmt.Sprintf(
+				"skipping container %s: failed to get image spec: %v", c.ID(), err))
+			continue
 		}
 
-		ctr := newContainer(ctrdRun, c, dom, id, gen, uid, &image{ctrdRun, img}, spec)
-		if err != nil {
-			return nil, err
+		runImg, ok := imgs[img.Name()]
+		if !ok {
+			runImg = newImage(ctrdRun, img)
+			imgs[img.Name()] = runImg
 		}
 
+		ctr := newContainer(ctrdRun, c, dom, id, gen, uid, runImg, spec)
+		ctr.domainName = getDomainName(ctrdRun, c)
+
 		runCtrs = append(runCtrs, ctr)
 	}
-	return runCtrs, nil
+	return runCtrs, warnings, nil
 }
 
 // newContainer defines a new container without creating it.
@@ -240,13 +302,15 @@ func getContainer(ctrdRun *containerdRuntime, domain, id, generation [16]byte) (
 		return nil, runtime.Errorf("failed to get image spec: %v", err)
 	}
 
-	ctr := newContainer(ctrdRun, ctrdCtr, domain, id, generation, uid, &image{ctrdRun, img}, spec)
+	ctr := newContainer(ctrdRun, ctrdCtr, domain, id, generation, uid,
+		newImage(ctrdRun, img), spec)
+	ctr.domainName = getDomainName(ctrdRun, ctrdCtr)
 
 	return ctr, nil
 }
 
 // createTask creates a new task for the active snapshot
-func createTask(ctr *container) (containerd.Task, error) {
+func createTask(ctr *container, ioCreator cio.Creator) (containerd.Task, error) {
 
 	ctrdRun := ctr.ctrdRuntime
 	ctrdCtx := ctrdRun.context
@@ -256,7 +320,7 @@ func createTask(ctr *container) (containerd.Task, error) {
 		return nil, err
 	}
 
-	ctrdTask, err := ctr.ctrdContainer.NewTask(ctrdCtx, cio.NewCreator(),
+	ctrdTask, err := ctr.ctrdContainer.NewTask(ctrdCtx, ioCreator,
 		containerd.WithRootFS(mounts))
 	if err != nil {
 		deleteCtrdContainer(ctrdRun, ctr.ctrdContainer, ctr.domain, ctr.id, false /*purge*/)
@@ -303,6 +367,18 @@ func (ctr *container) Domain() [16]byte {
 	return ctr.domain
 }
 
+func (ctr *container) DomainName() string {
+	return ctr.domainName
+}
+
+func (ctr *container) RuntimeID() string {
+	return composeCtrdID(ctr.domain, ctr.id)
+}
+
+func (ctr *container) Image() runtime.Image {
+	return ctr.image
+}
+
 func (ctr *container) ID() [16]byte {
 	return ctr.id
 }
@@ -315,21 +391,50 @@ func (ctr *container) UID() uint32 {
 	return ctr.uid
 }
 
+// info returns the containerd metadata of the container. It returns an empty record if the
+// container hasn't been created or the metadata cannot be read.
+func (ctr *container) info() containers.Container {
+
+	if ctr.ctrdContainer == nil {
+		return containers.Container{}
+	}
+	info, err := ctr.ctrdContainer.Info(ctr.ctrdRuntime.context)
+	if err != nil {
+		return containers.Container{}
+	}
+	return info
+}
+
 func (ctr *container) CreatedAt() time.Time {
-	// TODO: Container.CreatedAt not yet supported by containerd?
-	return time.Now()
+	return ctr.info().CreatedAt
 }
 
 func (ctr *container) UpdatedAt() time.Time {
-	// TODO: Container.updatedAt not yet supported by containerd?
-	return time.Now()
+	return ctr.info().UpdatedAt
 }
 
 func (ctr *container) SetRootFs(snap runtime.Snapshot) error {
 	return createActiveSnapshot(ctr.ctrdRuntime, ctr.image, ctr.domain, ctr.id, snap)
 }
 
-func (ctr *container) Create() error {
+// deprecatedPlugins maps deprecated runtime plugins to their replacement.
+var deprecatedPlugins = map[string]string{
+	"io.containerd.runtime.v1.linux": "io.containerd.runc.v2",
+	"io.containerd.runc.v1":          "io.containerd.runc.v2",
+}
+
+// pluginWarnings returns warnings for the configured runtime plugin.
+func pluginWarnings(plugin string) []string {
+
+	warnings := []string{}
+	if repl, ok := deprecatedPlugins[plugin]; ok {
+		warnings = append(warnings, fmt.Sprintf(
+			"runtime plugin '%s' is deprecated, use '%s' instead", plugin, repl))
+	}
+	return warnings
+}
+
+func (ctr *container) Create() ([]string, error) {
 
 	ctrdRun := ctr.ctrdRuntime
 	ctrdCtx := ctrdRun.context
@@ -339,21 +444,21 @@ func (ctr *container) Create() error {
 	// if a container with a different generation exists, delete that container
 	ctrdCtr, err := ctrdRun.client.LoadContainer(ctrdRun.context, ctrdID)
 	if err != nil && !ctrderr.IsNotFound(err) {
-		return err
+		return nil, err
 	}
 	if err == nil {
 		ctr.ctrdContainer = ctrdCtr
 		labels, err := ctrdCtr.Labels(ctrdCtx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		ctrdGen := labels[containerdGenerationLabel]
 		if ctrdGen == gen {
-			return errdefs.AlreadyExists("container", ctrdID)
+			return nil, errdefs.AlreadyExists("container", ctrdID)
 		}
 		err = deleteCtrdContainer(ctrdRun, ctrdCtr, ctr.domain, ctr.id, false /*purge*/)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -365,10 +470,10 @@ func (ctr *container) Create() error {
 
 	config, err := ctr.image.Config()
 	if err != nil {
-		return runtime.Errorf("failed to get image OCI spec: %v", err)
+		return nil, runtime.Errorf("failed to get image OCI spec: %v", err)
 	}
 	if spec.Linux != nil {
-		spec.Process.Args = append(config.Entrypoint, config.Cmd...)
+		spec.Process.Args = append(append([]string{}, config.Entrypoint...), config.Cmd...)
 		cwd := config.WorkingDir
 		if cwd == "" {
 			cwd = "/"
@@ -381,18 +486,24 @@ func (ctr *container) Create() error {
 	labels := map[string]string{}
 	labels[containerdGenerationLabel] = gen
 	labels[containerdUIDLabel] = strconv.FormatUint(uint64(ctr.uid), 10)
+	if ctr.domainName != "" {
+		labels[containerdDomainNameLabel] = ctr.domainName
+	}
+	if ctr.ephemeral {
+		labels[containerdEphemeralLabel] = composeCtrdID(ctr.domain, ctr.id)
+	}
 
 	ctrdCtr, err = ctrdRun.client.NewContainer(ctrdRun.context, uuidName,
 		containerd.WithImage(ctr.image.ctrdImage),
 		containerd.WithSpec(&spec),
-		containerd.WithRuntime("io.containerd.runtime.v1.linux", nil),
+		containerd.WithRuntime(ctrdRun.plugin, nil),
 		containerd.WithContainerLabels(labels))
 	if err != nil {
-		return runtime.Errorf("failed to create container: %v", err)
+		return nil, runtime.Errorf("failed to create container: %v", err)
 	}
 
 	ctr.ctrdContainer = ctrdCtr
-	return nil
+	return pluginWarnings(ctrdRun.plugin), nil
 }
 
 func (ctr *container) UpdateSpec(newSpec *runspecs.Spec) error {
@@ -412,7 +523,7 @@ func (ctr *container) UpdateSpec(newSpec *runspecs.Spec) error {
 		return runtime.Errorf("failed to get image OCI spec: %v", err)
 	}
 	if spec.Linux != nil {
-		spec.Process.Args = append(config.Entrypoint, config.Cmd...)
+		spec.Process.Args = append(append([]string{}, config.Entrypoint...), config.Cmd...)
 		cwd := config.WorkingDir
 		if cwd == "" {
 			cwd = "/"
@@ -454,9 +565,56 @@ func (ctr *container) Commit(gen [16]byte) error {
 		return err
 	}
 
+	// keep the generation with the snapshot for rolling back to it
+	return labelSnapshotGeneration(ctr.ctrdRuntime, ctr.domain, ctr.id, gen)
+}
+
+func (ctr *container) Restore(snap runtime.Snapshot, gen [16]byte) error {
+
+	if snap.Kind() != "committed" {
+		return errdefs.InvalidArgument("snapshot '%s' is not committed", snap.Name())
+	}
+
+	err := ctr.resetActiveSnapshot(snap)
+	if err != nil {
+		return err
+	}
+
+	err = ctr.Commit(gen)
+	if err != nil {
+		return err
+	}
+
+	ctr.generation = gen
 	return nil
 }
 
+func (ctr *container) Rollback() ([16]byte, error) {
+
+	snap, gen, err := previousGeneration(ctr.ctrdRuntime, ctr.domain, ctr.id, ctr.generation)
+	if err != nil {
+		return gen, err
+	}
+
+	err = ctr.Restore(snap, gen)
+	return gen, err
+}
+
+// resetActiveSnapshot replaces the active snapshot of the container with a new active snapshot
+// based on the provided snapshot.
+func (ctr *container) resetActiveSnapshot(snap runtime.Snapshot) error {
+
+	ctrdRun := ctr.ctrdRuntime
+
+	// need to delete the task to release the active snapshot
+	err := deleteCtrdTask(ctrdRun, ctr.ctrdContainer)
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+
+	return replaceActiveSnapshot(ctrdRun, ctr.domain, ctr.id, snap.Name())
+}
+
 func (ctr *container) Snapshot() (runtime.Snapshot, error) {
 
 	// need to delete the task to pick up the new mount point
@@ -467,11 +625,133 @@ func (ctr *container) Snapshot() (runtime.Snapshot, error) {
 	return updateSnapshot(ctr.ctrdRuntime, ctr.domain, ctr.id, false /* amend */)
 }
 
+// Ephemeral creates a temporary container in the same domain with a copy of the active
+// snapshot of the container.
+func (ctr *container) Ephemeral() (runtime.Container, error) {
+
+	ctrdRun := ctr.ctrdRuntime
+
+	err := deleteStaleEphemeral(ctrdRun)
+	if err != nil {
+		return nil, err
+	}
+
+	id := [16]byte(uuid.New())
+	ephCtr := newContainer(ctrdRun, nil,
+		ctr.domain, id, ctr.generation, ctr.uid, ctr.image, &ctr.spec)
+	ephCtr.domainName = ctr.domainName
+	ephCtr.ephemeral = true
+
+	err = copyActiveSnapshot(ctrdRun, ctr.domain, ctr.id, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ephCtr.Create()
+	if err != nil {
+		deleteActiveSnapshot(ctrdRun, ctr.domain, id)
+		return nil, err
+	}
+	return ephCtr, nil
+}
+
+// staleEphemeral returns true if the temporary container doesn't have a task or the task has
+// stopped. A task that was created but not started yet belongs to a container in use.
+func staleEphemeral(ctrdRun *containerdRuntime, ctrdCtr containerd.Container) bool {
+
+	ctrdTask, err := ctrdCtr.Task(ctrdRun.context, nil)
+	if err != nil {
+		return ctrderr.IsNotFound(err)
+	}
+	stat, err := ctrdTask.Status(ctrdRun.context)
+	if err != nil {
+		return false
+	}
+	return stat.Status == containerd.Stopped
+}
+
+// deleteStaleEphemeral deletes temporary containers and their snapshots that were left behind.
+func deleteStaleEphemeral(ctrdRun *containerdRuntime) error {
+
+	ctrdCtrs, err := ctrdRun.client.Containers(ctrdRun.context,
+		"labels.\""+containerdEphemeralLabel+"\"")
+	if err != nil {
+		return runtime.Errorf("failed to get containers: %v", err)
+	}
+
+	for _, c := range ctrdCtrs {
+		if !staleEphemeral(ctrdRun, c) {
+			continue
+		}
+		dom, id, err := splitCtrdID(c.ID())
+		if err != nil {
+			continue
+		}
+		err = deleteCtrdContainer(ctrdRun, c, dom, id, false /*purge*/)
+		if err != nil {
+			return err
+		}
+		err = deleteActiveSnapshot(ctrdRun, dom, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (ctr *container) Amend() (runtime.Snapshot, error) {
 
 	return updateSnapshot(ctr.ctrdRuntime, ctr.domain, ctr.id, true /* amend */)
 }
 
+// Diff compares the mounted active snapshot of the container with a view of its parent.
+func (ctr *container) Diff(changes func(runtime.Change) error) error {
+
+	ctrdRun := ctr.ctrdRuntime
+	ctrdCtx := ctrdRun.context
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+
+	activeName := activeSnapshotName(ctr.domain, ctr.id)
+	info, err := snapSvc.Stat(ctrdCtx, activeName)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return errdefs.NotFound("snapshot", activeName)
+	} else if err != nil {
+		return runtime.Errorf("failed to get snapshot: %v", err)
+	}
+
+	upper, err := snapSvc.Mounts(ctrdCtx, activeName)
+	if err != nil {
+		return runtime.Errorf("failed to mount snapshot: %v", err)
+	}
+
+	// use a unique view, so concurrent diffs of the container don't share the view
+	var lower []mount.Mount
+	if info.Parent != "" {
+		viewName := activeName + "-diff-" + uuid.New().String()
+		lower, err = snapSvc.View(ctrdCtx, viewName, info.Parent)
+		if err != nil {
+			return runtime.Errorf("failed to create view of snapshot '%s': %v",
+				info.Parent, err)
+		}
+		defer snapSvc.Remove(ctrdCtx, viewName)
+	}
+
+	return mount.WithTempMount(ctrdCtx, lower, func(lowerRoot string) error {
+		return mount.WithTempMount(ctrdCtx, upper, func(upperRoot string) error {
+			return fs.Changes(ctrdCtx, lowerRoot, upperRoot,
+				func(kind fs.ChangeKind, path string, _ os.FileInfo, err error) error {
+					if err != nil {
+						return err
+					}
+					if kind == fs.ChangeKindUnmodified {
+						return nil
+					}
+					return changes(runtime.Change{Kind: kind.String(), Path: path})
+				})
+		})
+	})
+}
+
 // Exec executes the provided command.
 func (ctr *container) Exec(stream runtime.Stream,
 	procSpec *runspecs.Process) (runtime.Process, error) {
@@ -482,7 +762,7 @@ func (ctr *container) Exec(stream runtime.Stream,
 
 	ctrdTask, err := ctrdCtr.Task(ctrdCtx, nil)
 	if err != nil && ctrderr.IsNotFound(err) {
-		ctrdTask, err = createTask(ctr)
+		ctrdTask, err = createTask(ctr, cio.NewCreator())
 	}
 	if err != nil {
 		return nil, runtime.Errorf("failed to get task: %v", err)
@@ -514,8 +794,442 @@ func (ctr *container) Exec(stream runtime.Stream,
 	}, nil
 }
 
+// taskLogPath returns the path of the file that keeps the output of the main process of a task
+// started by Start. The file is written by the shim, so the process doesn't block if nobody
+// reads its output.
+func taskLogPath(ctr *container) string {
+	return filepath.Join(containerdTaskLogDir, ctr.ctrdRuntime.namespace,
+		composeCtrdID(ctr.domain, ctr.id)+".log")
+}
+
+// createTaskLogDir creates the directories of the path below the root directory, which must
+// exist. Existing directories must be owned by root and must not be accessible by other users,
+// and symbolic links are not followed.
+func createTaskLogDir(root, path string) error {
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return runtime.Errorf("log directory '%s' is not in '%s'", path, root)
+	}
+
+	dir := root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if name == "." {
+			continue
+		}
+		dir = filepath.Join(dir, name)
+		err = os.Mkdir(dir, 0700)
+		if err != nil && !os.IsExist(err) {
+			return runtime.Errorf("failed to create log directory '%s': %v", dir, err)
+		}
+
+		fileInfo, err := os.Lstat(dir)
+		if err != nil {
+			return runtime.Errorf("failed to get status of '%s': %v", dir, err)
+		}
+		stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+		if !fileInfo.IsDir() || !ok || stat.Uid != 0 || fileInfo.Mode().Perm()&0077 != 0 {
+			return runtime.Errorf("log directory '%s' must be a directory "+
+				"only accessible by root", dir)
+		}
+	}
+	return nil
+}
+
+// runningTaskLog returns the task of the container and the path of the file that keeps the
+// output of its main process, or an empty path if the output isn't written to a file.
+func runningTaskLog(ctr *container) (containerd.Task, string, error) {
+
+	var stdout string
+	ctrdTask, err := ctr.ctrdContainer.Task(ctr.ctrdRuntime.context,
+		func(fifos *cio.FIFOSet) (cio.IO, error) {
+			stdout = fifos.Stdout
+			return cio.NullIO("")
+		})
+	if err != nil && ctrderr.IsNotFound(err) {
+		return nil, "", errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return nil, "", runtime.Errorf("failed to get task: %v", err)
+	}
+
+	u, err := url.Parse(stdout)
+	if err != nil || u.Scheme != "file" {
+		return ctrdTask, "", nil
+	}
+	return ctrdTask, u.Path, nil
+}
+
+// taskDone returns a channel that is closed when the main process of the task exits.
+func taskDone(ctr *container, ctrdTask containerd.Task) (<-chan struct{}, error) {
+
+	exitC, err := ctrdTask.Wait(ctr.ctrdRuntime.context)
+	if err != nil {
+		return nil, runtime.Errorf("failed to wait for task: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		<-exitC
+		close(done)
+	}()
+	return done, nil
+}
+
+// logPollInterval is the interval for checking the log file of a task for new output.
+const logPollInterval = 100 * time.Millisecond
+
+// copyLog copies the content of the log file to the writer. If done isn't nil, it continues
+// copying new output until done is closed.
+func copyLog(path string, w io.Writer, done <-chan struct{}) error {
+
+	f, err := os.Open(path)
+	if err != nil && os.IsNotExist(err) {
+		return errdefs.NotFound("log", path)
+	} else if err != nil {
+		return runtime.Errorf("failed to open log: %v", err)
+	}
+	defer f.Close()
+
+	for {
+		_, err = io.Copy(w, f)
+		if err != nil {
+			return runtime.Errorf("failed to copy log: %v", err)
+		}
+		if done == nil {
+			return nil
+		}
+		select {
+		case <-done:
+			done = nil
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// Start creates the task, if it doesn't exist, and starts the main process without attaching
+// to it. The output of the process is written to a log file for Logs and Attach.
+func (ctr *container) Start() error {
+
+	ctrdRun := ctr.ctrdRuntime
+	ctrdCtx := ctrdRun.context
+	ctrdID := composeCtrdID(ctr.domain, ctr.id)
+
+	ctrdTask, err := ctr.ctrdContainer.Task(ctrdCtx, nil)
+	if err != nil && !ctrderr.IsNotFound(err) {
+		return runtime.Errorf("failed to get task: %v", err)
+	}
+	if err == nil {
+		stat, err := ctrdTask.Status(ctrdCtx)
+		if err != nil {
+			return runtime.Errorf("failed to get status for task: %v", err)
+		}
+		if stat.Status == containerd.Running || stat.Status == containerd.Paused {
+			return errdefs.AlreadyExists("task", ctrdID)
+		}
+		err = deleteCtrdTask(ctrdRun, ctr.ctrdContainer)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the shim appends to the log file
+	logPath := taskLogPath(ctr)
+	err = createTaskLogDir(filepath.Dir(containerdTaskLogDir), filepath.Dir(logPath))
+	if err != nil {
+		return err
+	}
+	err = os.Remove(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return runtime.Errorf("failed to remove log: %v", err)
+	}
+
+	ctrdTask, err = createTask(ctr, cio.LogFile(logPath))
+	if err != nil {
+		return err
+	}
+
+	err = ctrdTask.Start(ctrdCtx)
+	if err != nil {
+		ctrdTask.Delete(ctrdCtx)
+		return runtime.Errorf("failed to start task: %v", err)
+	}
+	return nil
+}
+
+// Stop kills and deletes the task including all processes.
+func (ctr *container) Stop() error {
+
+	ctrdRun := ctr.ctrdRuntime
+
+	_, err := ctr.ctrdContainer.Task(ctrdRun.context, nil)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return runtime.Errorf("failed to get task: %v", err)
+	}
+
+	err = deleteCtrdTask(ctrdRun, ctr.ctrdContainer)
+	if err != nil {
+		return err
+	}
+	os.Remove(taskLogPath(ctr)) // the log might be owned by the shim
+	return nil
+}
+
+// Running returns true if the main process of the task is running.
+func (ctr *container) Running() (bool, error) {
+
+	ctrdCtx := ctr.ctrdRuntime.context
+
+	ctrdTask, err := ctr.ctrdContainer.Task(ctrdCtx, nil)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, runtime.Errorf("failed to get task: %v", err)
+	}
+
+	stat, err := ctrdTask.Status(ctrdCtx)
+	if err != nil {
+		return false, runtime.Errorf("failed to get status for task: %v", err)
+	}
+	return stat.Status == containerd.Running, nil
+}
+
+// Attach attaches the stream to the output of the running task. The output of a task started
+// by Start is read from its log file, and stdin isn't attached.
+func (ctr *container) Attach(stream runtime.Stream) (runtime.Process, error) {
+
+	ctrdTask, logPath, err := runningTaskLog(ctr)
+	if err != nil {
+		return nil, err
+	}
+	if logPath != "" {
+		done, err := taskDone(ctr, ctrdTask)
+		if err != nil {
+			return nil, err
+		}
+		output := make(chan struct{})
+		go func() {
+			defer close(output)
+			copyLog(logPath, stream.Stdout, done)
+		}()
+		return &process{
+			container: ctr,
+			ctrdProc:  ctrdTask,
+			output:    output,
+		}, nil
+	}
+
+	cioOpts := []cio.Opt{cio.WithStreams(stream.Stdin, stream.Stdout, stream.Stderr)}
+	if stream.Terminal {
+		cioOpts = append(cioOpts, cio.WithTerminal)
+	}
+
+	ctrdTask, err = ctr.ctrdContainer.Task(ctr.ctrdRuntime.context, cio.NewAttach(cioOpts...))
+	if err != nil && ctrderr.IsNotFound(err) {
+		return nil, errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return nil, runtime.Errorf("failed to attach to task: %v", err)
+	}
+
+	return &process{
+		container: ctr,
+		ctrdProc:  ctrdTask,
+	}, nil
+}
+
+// logsIdleTimeout is the time without output after which the pending output of the task is
+// considered copied.
+const logsIdleTimeout = 200 * time.Millisecond
+
+// activityWriter notifies the activity channel for every write.
+type activityWriter struct {
+	w        io.Writer
+	activity chan<- struct{}
+}
+
+func (aw *activityWriter) Write(p []byte) (int, error) {
+	select {
+	case aw.activity <- struct{}{}:
+	default:
+	}
+	return aw.w.Write(p)
+}
+
+// waitIdle waits until there was no activity for the timeout.
+func waitIdle(activity <-chan struct{}, timeout time.Duration) {
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-activity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(timeout)
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// Logs attaches to the output of the task of the container.
+func (ctr *container) Logs(stream runtime.Stream, follow bool) error {
+
+	ctrdCtx := ctr.ctrdRuntime.context
+
+	ctrdTask, logPath, err := runningTaskLog(ctr)
+	if err != nil {
+		return err
+	}
+	if logPath != "" {
+		if !follow {
+			return copyLog(logPath, stream.Stdout, nil)
+		}
+		done, err := taskDone(ctr, ctrdTask)
+		if err != nil {
+			return err
+		}
+		return copyLog(logPath, stream.Stdout, done)
+	}
+
+	stdout, stderr := stream.Stdout, stream.Stderr
+	activity := make(chan struct{}, 1)
+	if !follow {
+		stdout = &activityWriter{w: stdout, activity: activity}
+		stderr = &activityWriter{w: stderr, activity: activity}
+	}
+
+	ctrdTask, err = ctr.ctrdContainer.Task(ctrdCtx,
+		cio.NewAttach(cio.WithStreams(nil, stdout, stderr)))
+	if err != nil && ctrderr.IsNotFound(err) {
+		return errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return runtime.Errorf("failed to attach to task: %v", err)
+	}
+	ctrdIO := ctrdTask.IO()
+	defer ctrdIO.Close()
+
+	if !follow {
+		waitIdle(activity, logsIdleTimeout)
+		ctrdIO.Cancel()
+		return nil
+	}
+
+	exitC, err := ctrdTask.Wait(ctrdCtx)
+	if err != nil {
+		return runtime.Errorf("failed to wait for task: %v", err)
+	}
+	<-exitC
+	ctrdIO.Wait()
+
+	return nil
+}
+
+// Processes returns the processes that were started with Exec in the running task.
 func (ctr *container) Processes() ([]runtime.Process, error) {
-	return nil, errdefs.NotImplemented()
+
+	ctrdCtx := ctr.ctrdRuntime.context
+
+	ctrdTask, err := ctr.ctrdContainer.Task(ctrdCtx, nil)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return nil, errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return nil, runtime.Errorf("failed to get task: %v", err)
+	}
+
+	pids, err := ctrdTask.Pids(ctrdCtx)
+	if err != nil {
+		return nil, runtime.Errorf("failed to get processes of task: %v", err)
+	}
+
+	procs := []runtime.Process{}
+	for _, p := range pids {
+		if p.Pid == ctrdTask.Pid() || p.Info == nil {
+			continue
+		}
+		info, err := typeurl.UnmarshalAny(p.Info)
+		if err != nil {
+			return nil, runtime.Errorf("failed to decode process %d: %v", p.Pid, err)
+		}
+		details, ok := info.(*options.ProcessDetails)
+		if !ok || details.ExecID == "" {
+			continue
+		}
+		ctrdProc, err := ctrdTask.LoadProcess(ctrdCtx, details.ExecID, nil)
+		if err != nil && ctrderr.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, runtime.Errorf("failed to load process %d: %v", p.Pid, err)
+		}
+		procs = append(procs, &process{
+			container: ctr,
+			ctrdProc:  ctrdProc,
+		})
+	}
+	return procs, nil
+}
+
+// Stats returns the resource usage from the metrics of the task.
+func (ctr *container) Stats() (*runtime.Stats, error) {
+
+	ctrdCtx := ctr.ctrdRuntime.context
+
+	ctrdTask, err := ctr.ctrdContainer.Task(ctrdCtx, nil)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return nil, errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return nil, runtime.Errorf("failed to get task: %v", err)
+	}
+
+	metric, err := ctrdTask.Metrics(ctrdCtx)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return nil, errdefs.NotFound("task", composeCtrdID(ctr.domain, ctr.id))
+	} else if err != nil {
+		return nil, runtime.Errorf("failed to get metrics of task: %v", err)
+	}
+	return metricStats(metric)
+}
+
+// metricStats decodes the cgroup v1 or v2 metrics reported for a task.
+func metricStats(metric *types.Metric) (*runtime.Stats, error) {
+
+	if metric == nil || metric.Data == nil {
+		return &runtime.Stats{}, nil
+	}
+	data, err := typeurl.UnmarshalAny(metric.Data)
+	if err != nil {
+		return nil, runtime.Errorf("failed to decode metrics: %v", err)
+	}
+
+	stats := &runtime.Stats{}
+	switch m := data.(type) {
+	case *cgroupsv1.Metrics:
+		if m.CPU != nil && m.CPU.Usage != nil {
+			stats.CPU = m.CPU.Usage.Total
+		}
+		if m.Memory != nil && m.Memory.Usage != nil {
+			stats.MemoryUsage = m.Memory.Usage.Usage
+			stats.MemoryLimit = m.Memory.Usage.Limit
+		}
+		if m.Pids != nil {
+			stats.Pids = m.Pids.Current
+		}
+	case *cgroupsv2.Metrics:
+		if m.CPU != nil {
+			stats.CPU = m.CPU.UsageUsec * 1000
+		}
+		if m.Memory != nil {
+			stats.MemoryUsage = m.Memory.Usage
+			stats.MemoryLimit = m.Memory.UsageLimit
+		}
+		if m.Pids != nil {
+			stats.Pids = m.Pids.Current
+		}
+	default:
+		return nil, runtime.Errorf("unsupported metrics type: %T", data)
+	}
+	return stats, nil
 }
 
 // deleteContainer deletes the container, task, and active snapshot.
@@ -556,8 +1270,18 @@ func deleteCtrdContainer(ctrdRun *containerdRuntime,
 }
 
 func (ctr *container) Delete() error {
-	return deleteCtrdContainer(ctr.ctrdRuntime,
+
+	err := deleteCtrdContainer(ctr.ctrdRuntime,
 		ctr.ctrdContainer, ctr.domain, ctr.id, false /*purge*/)
+	if err != nil {
+		return err
+	}
+
+	// temporary containers don't keep their filesystem
+	if ctr.ephemeral {
+		return deleteActiveSnapshot(ctr.ctrdRuntime, ctr.domain, ctr.id)
+	}
+	return nil
 }
 
 func (ctr *container) Purge() error {
@@ -0,0 +1,177 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/containers"
+
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+func TestContainerPluginWarnings(t *testing.T) {
+
+	warnings := pluginWarnings("io.containerd.runtime.v1.linux")
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "deprecated") {
+		t.Errorf("Deprecated plugin should produce a warning: %v", warnings)
+	}
+
+	warnings = pluginWarnings("io.containerd.runc.v2")
+	if len(warnings) != 0 {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestContainerRuntimeID(t *testing.T) {
+
+	dom := [16]byte{0xde, 0xad, 0xbe, 0xef}
+	id := [16]byte{1, 2, 3, 4}
+	ctr := &container{domain: dom, id: id}
+
+	splitDom, splitID, err := splitCtrdID(ctr.RuntimeID())
+	if err != nil {
+		t.Fatalf("Failed to split containerd ID '%s': %v", ctr.RuntimeID(), err)
+	}
+	if splitDom != dom || splitID != id {
+		t.Errorf("Containerd ID '%s' doesn't round-trip", ctr.RuntimeID())
+	}
+}
+
+func TestReadContainers(t *testing.T) {
+
+	ctrdRun := &containerdRuntime{context: context.Background()}
+	dom := [16]byte{0xde, 0xad, 0xbe, 0xef}
+	img := newTestCtrdImage(t)
+	img.name = "docker.io/library/test:latest"
+
+	newCtrdContainer := func(id byte, img containerd.Image,
+		spec *runspecs.Spec) *testCtrdContainer {
+
+		ctr := &container{domain: dom, id: [16]byte{id}}
+		return &testCtrdContainer{
+			info: containers.Container{
+				ID: ctr.RuntimeID(),
+				Labels: map[string]string{
+					containerdGenerationLabel: hex.EncodeToString([]byte{id}),
+					containerdUIDLabel:        "1000",
+				},
+			},
+			image: img,
+			spec:  spec,
+		}
+	}
+
+	ctrdCtrs := []containerd.Container{
+		newCtrdContainer(1, img, &runspecs.Spec{}),
+		newCtrdContainer(2, nil, &runspecs.Spec{}),
+		newCtrdContainer(3, img, nil),
+		newCtrdContainer(4, img, &runspecs.Spec{}),
+	}
+
+	runCtrs, warnings, err := readContainers(ctrdRun, ctrdCtrs, true, dom)
+	if err != nil {
+		t.Fatalf("Failed to read containers: %v", err)
+	}
+	if len(runCtrs) != 2 {
+		t.Fatalf("Expected 2 containers, got %d", len(runCtrs))
+	}
+	if runCtrs[0].ID() != [16]byte{1} || runCtrs[1].ID() != [16]byte{4} {
+		t.Errorf("Unexpected containers: %v %v", runCtrs[0].ID(), runCtrs[1].ID())
+	}
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "skipping container") ||
+		!strings.HasPrefix(warnings[1], "skipping container") {
+		t.Errorf("Expected a warning for each skipped container, got: %v", warnings)
+	}
+}
+
+func TestCreateTaskLogDir(t *testing.T) {
+
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root")
+	}
+
+	root, err := ioutil.TempDir("", "cnetest")
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "cne", "tasks", "test")
+	if err = createTaskLogDir(root, path); err != nil {
+		t.Fatalf("Failed to create log directory: %v", err)
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil || fileInfo.Mode().Perm() != 0700 {
+		t.Errorf("Log directory should only be accessible by root: %v %v", fileInfo, err)
+	}
+	if err = createTaskLogDir(root, path); err != nil {
+		t.Errorf("Existing log directory should be accepted: %v", err)
+	}
+
+	if err = os.Chmod(filepath.Join(root, "cne"), 0777); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	if err = createTaskLogDir(root, path); err == nil {
+		t.Errorf("Writable log directory should be rejected")
+	}
+
+	other := filepath.Join(root, "other")
+	if err = os.Mkdir(other, 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err = os.Symlink(other, filepath.Join(root, "link")); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+	if err = createTaskLogDir(root, filepath.Join(root, "link", "test")); err == nil {
+		t.Errorf("Symbolic link should be rejected")
+	}
+}
+
+func TestCopyLog(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cne-log-")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "task.log")
+	var out bytes.Buffer
+	if err := copyLog(path, &out, nil); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error for a missing log, got %v", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	defer f.Close()
+	f.WriteString("first\n")
+
+	if err := copyLog(path, &out, nil); err != nil || out.String() != "first\n" {
+		t.Errorf("Unexpected log '%s': %v", out.String(), err)
+	}
+
+	// output written while following the log is copied until the task is done
+	out.Reset()
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(2 * logPollInterval)
+		f.WriteString("second\n")
+		close(done)
+	}()
+	if err := copyLog(path, &out, done); err != nil || out.String() != "first\nsecond\n" {
+		t.Errorf("Unexpected log '%s': %v", out.String(), err)
+	}
+}
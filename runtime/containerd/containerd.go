@@ -4,15 +4,24 @@ package containerd
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/containerd/containerd"
 	ctrderr "github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/images/archive"
 	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/platforms"
 	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/containerd/containerd/snapshots"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	runspecs "github.com/opencontainers/runtime-spec/specs-go"
@@ -24,13 +33,29 @@ import (
 
 const containerdGenerationLabel = "CNE-GEN"
 const containerdUIDLabel = "CNE-UID"
+const containerdDomainNameLabel = "cne.domain.name"
+const containerdEphemeralLabel = "cne.ephemeral"
+const containerdSnapshotGenerationLabel = "cne.generation."
+const containerdPlatformLabel = "cne.platform"
+const containerdSnapshotLastUsedLabel = "cne.last-used"
+
+// containerdTaskLogDir is the directory for the output of tasks started by Start. The shim
+// runs as root and appends to the log files, so the directory must only be writable by root.
+const containerdTaskLogDir = "/run/cne/tasks"
 
 // containerdRuntime provides the runtime implementation for the containerd daemon
 // For more information about containerd, see: https://github.com/containerd/containerd
 type containerdRuntime struct {
-	client    *containerd.Client
-	context   context.Context
-	namespace string
+	client      *containerd.Client
+	context     context.Context
+	namespace   string
+	socket      string
+	plugin      string
+	credentials runtime.Credentials
+	insecure    runtime.InsecureRegistry
+	mirrors     runtime.RegistryMirrors
+	proxy       proxyFunc
+	pullRetry   retryPolicy
 }
 
 type containerdRuntimeType struct {
@@ -49,25 +74,83 @@ func (r *containerdRuntimeType) Open(confRun config.Runtime) (runtime.Runtime, e
 	// Validate the provided port
 	_, err := os.Stat(confRun.SocketName)
 	if err != nil {
-		return nil, runtime.Errorf("failed to open runtime socket '%s': %v",
-			confRun.SocketName, err)
+		return nil, socketError(confRun.SocketName, err)
 	}
 
 	client, err := containerd.New(confRun.SocketName)
 	if err != nil {
-		return nil, runtime.Errorf("failed to open runtime socket '%s': %v",
-			confRun.SocketName, err)
+		return nil, socketError(confRun.SocketName, err)
 	}
 
-	ctrdCtx := namespaces.WithNamespace(context.Background(), confRun.Namespace)
+	ctrdCtx := runtimeContext(confRun)
+
+	err = ensureNamespace(ctrdCtx, client.NamespaceService(),
+		confRun.Namespace, confRun.CreateNamespaceEnabled())
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	plugin := confRun.Plugin
+	if plugin == "" {
+		plugin = config.DefaultExecRuntimePlugin
+	}
 
 	return &containerdRuntime{
 		client:    client,
 		context:   ctrdCtx,
 		namespace: confRun.Namespace,
+		socket:    confRun.SocketName,
+		plugin:    plugin,
+		proxy:     newProxyFunc(confRun.Proxy),
+		pullRetry: newRetryPolicy(confRun.PullRetry),
 	}, nil
 }
 
+// socketError maps the error for accessing the runtime socket to a missing socket, missing
+// permissions, or an unavailable daemon.
+func socketError(socket string, err error) error {
+
+	// the dial error of the client doesn't always wrap the system error
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return errdefs.NotFound(runtime.SocketResource, socket)
+	case errors.Is(err, os.ErrPermission) || strings.Contains(err.Error(), "permission denied"):
+		return errdefs.PermissionDenied(runtime.SocketResource, socket)
+	default:
+		return errdefs.Unavailable(runtime.SocketResource, socket, err)
+	}
+}
+
+// runtimeContext returns the context for the namespace of the runtime configuration.
+func runtimeContext(confRun config.Runtime) context.Context {
+	return namespaces.WithNamespace(context.Background(), confRun.Namespace)
+}
+
+// ensureNamespace creates the namespace, if create is set, or verifies that it exists.
+func ensureNamespace(ctx context.Context,
+	nsSvc namespaces.Store, namespace string, create bool) error {
+
+	if create {
+		err := nsSvc.Create(ctx, namespace, nil)
+		if err != nil && !ctrderr.IsAlreadyExists(err) {
+			return runtime.Errorf("failed to create namespace '%s': %v", namespace, err)
+		}
+		return nil
+	}
+
+	nsList, err := nsSvc.List(ctx)
+	if err != nil {
+		return runtime.Errorf("failed to get namespaces: %v", err)
+	}
+	for _, ns := range nsList {
+		if ns == namespace {
+			return nil
+		}
+	}
+	return errdefs.NotFound("namespace", namespace)
+}
+
 func (ctrdRun *containerdRuntime) Namespace() string {
 	return ctrdRun.namespace
 }
@@ -76,6 +159,20 @@ func (ctrdRun *containerdRuntime) Close() {
 	ctrdRun.client.Close()
 }
 
+func (ctrdRun *containerdRuntime) Version() (runtime.VersionInfo, error) {
+
+	info := runtime.VersionInfo{Name: "containerd", Socket: ctrdRun.socket}
+
+	version, err := ctrdRun.client.Version(ctrdRun.context)
+	if err != nil {
+		return info, errdefs.Unavailable(runtime.SocketResource, ctrdRun.socket, err)
+	}
+	info.Version = version.Version
+	info.Revision = version.Revision
+
+	return info, nil
+}
+
 func (ctrdRun *containerdRuntime) Images() ([]runtime.Image, error) {
 
 	ctrdImgs, err := ctrdRun.client.ListImages(ctrdRun.context)
@@ -85,10 +182,7 @@ func (ctrdRun *containerdRuntime) Images() ([]runtime.Image, error) {
 
 	runImgs := make([]runtime.Image, len(ctrdImgs))
 	for i, ctrdImg := range ctrdImgs {
-		runImgs[i] = &image{
-			ctrdRuntime: ctrdRun,
-			ctrdImage:   ctrdImg,
-		}
+		runImgs[i] = newImage(ctrdRun, ctrdImg)
 	}
 
 	return runImgs, nil
@@ -103,18 +197,65 @@ func (ctrdRun *containerdRuntime) GetImage(name string) (runtime.Image, error) {
 		return nil, err
 	}
 
-	return &image{
-		ctrdRuntime: ctrdRun,
-		ctrdImage:   ctrdImg,
-	}, nil
+	return newImage(ctrdRun, ctrdImg), nil
+}
+
+// cleanupPull removes the snapshots that were left in the extracting stage by an interrupted
+// pull that started at the provided time and the image record, if the image didn't exist
+// before the pull.
+func cleanupPull(ctrdRun *containerdRuntime, name string, startedAt time.Time, existed bool) {
+
+	ctrdCtx := ctrdRun.context
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+
+	var extracting []string
+	snapSvc.Walk(ctrdCtx, func(ctx context.Context, info snapshots.Info) error {
+		if isExtractSnapshot(info, startedAt) {
+			extracting = append(extracting, info.Name)
+		}
+		return nil
+	})
+	for _, snapName := range extracting {
+		snapSvc.Remove(ctrdCtx, snapName)
+	}
+
+	if !existed {
+		ctrdRun.client.ImageService().Delete(ctrdCtx, name)
+	}
+}
+
+// isExtractSnapshot returns true if the snapshot is an active snapshot for extracting an
+// image layer that was created after the provided time.
+func isExtractSnapshot(info snapshots.Info, since time.Time) bool {
+	return info.Kind == snapshots.KindActive &&
+		strings.HasPrefix(info.Name, "extract-") && !info.Created.Before(since)
 }
 
-// TODO: ContainerD is not really stable when interrupting an image pull (e.g. using CTRL-C)
-// TODO: Snapshots can stay in extracting stage and never complete.
+// isPlatformMismatch returns true if the error reports that the image has no manifest for
+// the requested platform.
+func isPlatformMismatch(err error) bool {
+	return ctrderr.IsNotFound(err) &&
+		strings.Contains(err.Error(), "no match for platform")
+}
 
-func (ctrdRun *containerdRuntime) PullImage(name string,
+// PullImage pulls the image and unpacks it. An interrupt signal cancels the pull and removes
+// the partially extracted snapshots.
+func (ctrdRun *containerdRuntime) PullImage(name, platform string,
 	progress chan<- []runtime.ProgressStatus) (runtime.Image, error) {
 
+	if platform != "" {
+		p, err := platforms.Parse(platform)
+		if err != nil {
+			if progress != nil {
+				close(progress)
+			}
+			return nil, errdefs.InvalidArgument("invalid platform '%s'", platform)
+		}
+		platform = platforms.Format(p)
+	} else {
+		platform = platforms.DefaultString()
+	}
+
 	var mutex sync.Mutex
 	descs := []ocispec.Descriptor{}
 
@@ -150,29 +291,146 @@ func (ctrdRun *containerdRuntime) PullImage(name string,
 		}()
 	}
 
-	// ignore signals while pulling - see comment above
-	signal.Ignore()
-
-	ctrdImg, err := ctrdRun.client.Pull(ctrdRun.context, name,
-		containerd.WithPullUnpack, containerd.WithImageHandler(h))
+	_, err := ctrdRun.client.ImageService().Get(ctrdRun.context, name)
+	existed := err == nil
+	startedAt := time.Now()
+
+	// cancel the pull on interrupts instead of leaving the process and snapshots behind
+	pullCtx, cancelPull := context.WithCancel(ctrdRun.context)
+	defer cancelPull()
+
+	interrupted := make(chan struct{})
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigc:
+			close(interrupted)
+			cancelPull()
+		case <-pullCtx.Done():
+		}
+	}()
+
+	// retry transient failures and show the retries in the progress
+	var ctrdImg containerd.Image
+	recorder := &responseRecorder{}
+	resolver := newPullResolver(ctrdRun, recorder.wrap)
+	notify := func(attempt int, delay time.Duration, err error) {
+		if progress != nil {
+			progress <- []runtime.ProgressStatus{pullRetryStatus(name, attempt,
+				ctrdRun.pullRetry.attempts, delay, err)}
+		}
+	}
+	err = retry(pullCtx, ctrdRun.pullRetry, recorder, notify, func(ctx context.Context) error {
+		var err error
+		ctrdImg, err = ctrdRun.client.Pull(ctx, name,
+			containerd.WithPullUnpack, containerd.WithImageHandler(h),
+			containerd.WithPlatform(platform),
+			containerd.WithPullLabel(containerdPlatformLabel, platform),
+			containerd.WithResolver(resolver))
+		return err
+	})
 
-	signal.Reset()
+	signal.Stop(sigc)
+	cancelPull()
 
 	if progress != nil {
 		stopProgress()
 		wg.Wait()
 	}
 
+	select {
+	case <-interrupted:
+		cleanupPull(ctrdRun, name, startedAt, existed)
+		return nil, errdefs.Canceled("pull image", name)
+	default:
+	}
+
 	if err == reference.ErrObjectRequired {
 		return nil, runtime.Errorf("invalid image name '%s': %v", name, err)
+	} else if err != nil && isPlatformMismatch(err) {
+		return nil, errdefs.New(errdefs.ErrNotFound, "platform",
+			fmt.Sprintf("platform '%s' not found in image '%s'", platform, name))
 	} else if err != nil {
 		return nil, runtime.Errorf("pull image '%s' failed: %v", name, err)
 	}
 
-	return &image{
-		ctrdRuntime: ctrdRun,
-		ctrdImage:   ctrdImg,
-	}, nil
+	return newImage(ctrdRun, ctrdImg), nil
+}
+
+func (ctrdRun *containerdRuntime) SetCredentials(creds runtime.Credentials) {
+	ctrdRun.credentials = creds
+}
+
+func (ctrdRun *containerdRuntime) SetInsecureRegistries(insecure runtime.InsecureRegistry) {
+	ctrdRun.insecure = insecure
+}
+
+func (ctrdRun *containerdRuntime) SetRegistryMirrors(mirrors runtime.RegistryMirrors) {
+	ctrdRun.mirrors = mirrors
+}
+
+func (ctrdRun *containerdRuntime) PushImage(name, remote string,
+	progress chan<- []runtime.ProgressStatus) error {
+
+	if remote == "" {
+		remote = name
+	}
+
+	ctrdImg, err := ctrdRun.client.GetImage(ctrdRun.context, name)
+	if err != nil {
+		if progress != nil {
+			close(progress)
+		}
+		if ctrderr.IsNotFound(err) {
+			return errdefs.NotFound("image", name)
+		}
+		return runtime.Errorf("failed to get image '%s': %v", name, err)
+	}
+
+	var mutex sync.Mutex
+	descs := []ocispec.Descriptor{}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	h := images.HandlerFunc(func(ctrdCtx context.Context,
+		desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+
+		mutex.Lock()
+		descs = append(descs, desc)
+		mutex.Unlock()
+		return nil, nil
+	})
+
+	tracker := docker.NewInMemoryTracker()
+	resolver := newResolver(ctrdRun, tracker)
+
+	pctx, stopProgress := context.WithCancel(ctrdRun.context)
+	defer stopProgress()
+	if progress != nil {
+		go func() {
+			defer wg.Done()
+			defer close(progress)
+			updatePushProgress(ctrdRun, pctx, tracker, &mutex, &descs, progress)
+		}()
+	}
+
+	err = ctrdRun.client.Push(ctrdRun.context, remote, ctrdImg.Target(),
+		containerd.WithResolver(resolver), containerd.WithImageHandler(h))
+
+	if progress != nil {
+		stopProgress()
+		wg.Wait()
+	}
+
+	if err == reference.ErrObjectRequired {
+		return runtime.Errorf("invalid image name '%s': %v", remote, err)
+	} else if err != nil {
+		return runtime.Errorf("push image '%s' failed: %v", remote, err)
+	}
+
+	return nil
 }
 
 func (ctrdRun *containerdRuntime) DeleteImage(name string) error {
@@ -187,26 +445,92 @@ func (ctrdRun *containerdRuntime) DeleteImage(name string) error {
 
 }
 
+func (ctrdRun *containerdRuntime) ExportImage(name string, w io.Writer) error {
+
+	imgSvc := ctrdRun.client.ImageService()
+
+	ctrdImg, err := imgSvc.Get(ctrdRun.context, name)
+	if errors.Is(err, ctrderr.ErrNotFound) {
+		return errdefs.NotFound("image", name)
+	} else if err != nil {
+		return runtime.Errorf("failed to get image '%s': %v", name, err)
+	}
+
+	err = ctrdRun.client.Export(ctrdRun.context, w,
+		archive.WithImage(imgSvc, name),
+		archive.WithPlatform(imagePlatform(ctrdImg.Labels)))
+	if err != nil {
+		return runtime.Errorf("export image '%s' failed: %v", name, err)
+	}
+	return nil
+}
+
+func (ctrdRun *containerdRuntime) ImportImage(r io.Reader) ([]runtime.Image, error) {
+
+	ctrdImgs, err := ctrdRun.client.Import(ctrdRun.context, r,
+		containerd.WithAllPlatforms(false))
+	if err != nil {
+		return nil, runtime.Errorf("import image failed: %v", err)
+	}
+
+	imgs := make([]runtime.Image, 0, len(ctrdImgs))
+	for _, i := range ctrdImgs {
+		ctrdImg := containerd.NewImage(ctrdRun.client, i)
+		err = ctrdImg.Unpack(ctrdRun.context, containerd.DefaultSnapshotter)
+		if err != nil {
+			return nil, runtime.Errorf("failed to unpack image '%s': %v", i.Name, err)
+		}
+		imgs = append(imgs, newImage(ctrdRun, ctrdImg))
+	}
+	return imgs, nil
+}
+
 func (ctrdRun *containerdRuntime) Snapshots() ([]runtime.Snapshot, error) {
 	return getSnapshots(ctrdRun)
 }
 
+func (ctrdRun *containerdRuntime) GetSnapshot(name string) (runtime.Snapshot, error) {
+	return getSnapshot(ctrdRun, name)
+}
+
 func (ctrdRun *containerdRuntime) DeleteSnapshot(name string) error {
 	return deleteSnapshot(ctrdRun, name)
 }
 
-func (ctrdRun *containerdRuntime) Containers(filters ...interface{}) ([]runtime.Container, error) {
+func (ctrdRun *containerdRuntime) TouchSnapshot(name string) error {
+	return touchSnapshot(ctrdRun, name)
+}
+
+func (ctrdRun *containerdRuntime) Containers(
+	filters ...interface{}) ([]runtime.Container, []string, error) {
 	return getContainers(ctrdRun, filters...)
 }
 
+func (ctrdRun *containerdRuntime) ContainerIDs() ([]string, error) {
+
+	ctrdCtrs, err := ctrdRun.client.Containers(ctrdRun.context)
+	if err != nil {
+		return nil, runtime.Errorf("failed to get containers: %v", err)
+	}
+
+	ids := make([]string, len(ctrdCtrs))
+	for i, c := range ctrdCtrs {
+		ids[i] = c.ID()
+	}
+	return ids, nil
+}
+
 func (ctrdRun *containerdRuntime) GetContainer(
 	domain, id, generation [16]byte) (runtime.Container, error) {
 	return getContainer(ctrdRun, domain, id, generation)
 }
 
 func (ctrdRun *containerdRuntime) NewContainer(domain, id, generation [16]byte, uid uint32,
-	img runtime.Image, spec *runspecs.Spec) (runtime.Container, error) {
-	return newContainer(ctrdRun, nil, domain, id, generation, uid, img.(*image), spec), nil
+	domainName string, img runtime.Image, spec *runspecs.Spec) (runtime.Container, error) {
+
+	ctr := newContainer(ctrdRun, nil, domain, id, generation, uid, img.(*image), spec)
+	ctr.domainName = domainName
+	return ctr, nil
 }
 
 func (ctrdRun *containerdRuntime) DeleteContainer(domain, id, generation [16]byte) error {
@@ -0,0 +1,440 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	cgroupsv1 "github.com/containerd/cgroups/stats/v1"
+	cgroupsv2 "github.com/containerd/cgroups/v2/stats"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/containers"
+	ctrderr "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/platforms"
+	"github.com/containerd/containerd/runtime/v2/runc/options"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/containerd/typeurl"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+// testNamespaceStore provides the namespace functions used by the runtime.
+type testNamespaceStore struct {
+	namespaces.Store
+	names []string
+}
+
+func (nsSvc *testNamespaceStore) Create(ctx context.Context,
+	namespace string, labels map[string]string) error {
+
+	for _, ns := range nsSvc.names {
+		if ns == namespace {
+			return ctrderr.ErrAlreadyExists
+		}
+	}
+	nsSvc.names = append(nsSvc.names, namespace)
+	return nil
+}
+
+func (nsSvc *testNamespaceStore) List(ctx context.Context) ([]string, error) {
+	return nsSvc.names, nil
+}
+
+func TestRuntimeContextNamespace(t *testing.T) {
+
+	confRun := config.Runtime{Namespace: "override"}
+	ns, ok := namespaces.Namespace(runtimeContext(confRun))
+	if !ok || ns != "override" {
+		t.Errorf("Expected namespace 'override', got '%s'", ns)
+	}
+}
+
+func TestEnsureNamespace(t *testing.T) {
+
+	ctx := context.Background()
+	nsSvc := &testNamespaceStore{names: []string{"default"}}
+
+	err := ensureNamespace(ctx, nsSvc, "cne", false)
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error for a missing namespace, got %v", err)
+	}
+
+	err = ensureNamespace(ctx, nsSvc, "cne", true)
+	if err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	if len(nsSvc.names) != 2 || nsSvc.names[1] != "cne" {
+		t.Errorf("Namespace not created: %v", nsSvc.names)
+	}
+
+	err = ensureNamespace(ctx, nsSvc, "cne", true)
+	if err != nil {
+		t.Errorf("Creating an existing namespace should succeed: %v", err)
+	}
+	err = ensureNamespace(ctx, nsSvc, "cne", false)
+	if err != nil {
+		t.Errorf("Existing namespace not found: %v", err)
+	}
+}
+
+// testCtrdContainer provides the container functions used by the runtime.
+type testCtrdContainer struct {
+	containerd.Container
+	info  containers.Container
+	task  containerd.Task
+	image containerd.Image
+	spec  *runspecs.Spec
+}
+
+func (ctrdCtr *testCtrdContainer) ID() string {
+	return ctrdCtr.info.ID
+}
+
+func (ctrdCtr *testCtrdContainer) Labels(ctx context.Context) (map[string]string, error) {
+	return ctrdCtr.info.Labels, nil
+}
+
+func (ctrdCtr *testCtrdContainer) Image(ctx context.Context) (containerd.Image, error) {
+	if ctrdCtr.image == nil {
+		return nil, ctrderr.ErrNotFound
+	}
+	return ctrdCtr.image, nil
+}
+
+func (ctrdCtr *testCtrdContainer) Spec(ctx context.Context) (*runspecs.Spec, error) {
+	if ctrdCtr.spec == nil {
+		return nil, ctrderr.ErrNotFound
+	}
+	return ctrdCtr.spec, nil
+}
+
+func (ctrdCtr *testCtrdContainer) Task(ctx context.Context,
+	attach cio.Attach) (containerd.Task, error) {
+
+	if ctrdCtr.task == nil {
+		return nil, ctrderr.ErrNotFound
+	}
+	return ctrdCtr.task, nil
+}
+
+// testCtrdTask provides the task functions used by the runtime.
+type testCtrdTask struct {
+	containerd.Task
+	pid    uint32
+	execs  map[uint32]string
+	status containerd.ProcessStatus
+}
+
+func (ctrdTask *testCtrdTask) Status(ctx context.Context) (containerd.Status, error) {
+	return containerd.Status{Status: ctrdTask.status}, nil
+}
+
+func (ctrdTask *testCtrdTask) Pid() uint32 {
+	return ctrdTask.pid
+}
+
+func (ctrdTask *testCtrdTask) Pids(ctx context.Context) ([]containerd.ProcessInfo, error) {
+
+	pids := []containerd.ProcessInfo{{Pid: ctrdTask.pid}}
+	for pid, execID := range ctrdTask.execs {
+		info, err := typeurl.MarshalAny(&options.ProcessDetails{ExecID: execID})
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, containerd.ProcessInfo{Pid: pid, Info: info})
+	}
+	return pids, nil
+}
+
+func (ctrdTask *testCtrdTask) LoadProcess(ctx context.Context,
+	id string, attach cio.Attach) (containerd.Process, error) {
+	return &testCtrdProcess{id: id}, nil
+}
+
+type testCtrdProcess struct {
+	containerd.Process
+	id string
+}
+
+func (ctrdCtr *testCtrdContainer) Info(ctx context.Context,
+	opts ...containerd.InfoOpts) (containers.Container, error) {
+	return ctrdCtr.info, nil
+}
+
+func TestContainerCreatedAt(t *testing.T) {
+
+	ctrdRun := &containerdRuntime{context: context.Background()}
+	ctr := &container{ctrdRuntime: ctrdRun}
+	if !ctr.CreatedAt().IsZero() {
+		t.Errorf("Container that wasn't created should not have a creation time")
+	}
+
+	createdAt := time.Now().Add(-time.Hour)
+	ctr.ctrdContainer = &testCtrdContainer{info: containers.Container{
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt.Add(time.Minute),
+	}}
+
+	first := ctr.CreatedAt()
+	time.Sleep(10 * time.Millisecond)
+	if !ctr.CreatedAt().Equal(first) {
+		t.Errorf("CreatedAt changed between calls: %v != %v", first, ctr.CreatedAt())
+	}
+	if time.Since(first) < 59*time.Minute {
+		t.Errorf("CreatedAt should not be the current time: %v", first)
+	}
+	if !ctr.UpdatedAt().Equal(createdAt.Add(time.Minute)) {
+		t.Errorf("Unexpected UpdatedAt: %v", ctr.UpdatedAt())
+	}
+}
+
+func TestContainerProcesses(t *testing.T) {
+
+	ctrdRun := &containerdRuntime{context: context.Background()}
+	ctrdCtr := &testCtrdContainer{}
+	ctr := &container{ctrdRuntime: ctrdRun, ctrdContainer: ctrdCtr}
+
+	_, err := ctr.Processes()
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Container without a task should return not found: %v", err)
+	}
+
+	task := &testCtrdTask{pid: 1}
+	ctrdCtr.task = task
+	procs, err := ctr.Processes()
+	if err != nil {
+		t.Fatalf("Failed to get processes: %v", err)
+	}
+	if procs == nil || len(procs) != 0 {
+		t.Errorf("Expected an empty list of processes, got %v", procs)
+	}
+
+	task.execs = map[uint32]string{10: "exec1", 11: "exec2"}
+	procs, err = ctr.Processes()
+	if err != nil {
+		t.Fatalf("Failed to get processes: %v", err)
+	}
+	ids := map[string]bool{}
+	for _, p := range procs {
+		ids[p.(*process).ctrdProc.(*testCtrdProcess).id] = true
+	}
+	if len(procs) != 2 || !ids["exec1"] || !ids["exec2"] {
+		t.Errorf("Expected the two exec'd processes, got %v", ids)
+	}
+}
+
+func TestMetricStats(t *testing.T) {
+
+	metrics := []interface{}{
+		&cgroupsv1.Metrics{
+			CPU:    &cgroupsv1.CPUStat{Usage: &cgroupsv1.CPUUsage{Total: 2000}},
+			Memory: &cgroupsv1.MemoryStat{Usage: &cgroupsv1.MemoryEntry{Usage: 10, Limit: 20}},
+			Pids:   &cgroupsv1.PidsStat{Current: 3},
+		},
+		&cgroupsv2.Metrics{
+			CPU:    &cgroupsv2.CPUStat{UsageUsec: 2},
+			Memory: &cgroupsv2.MemoryStat{Usage: 10, UsageLimit: 20},
+			Pids:   &cgroupsv2.PidsStat{Current: 3},
+		},
+	}
+
+	expected := runtime.Stats{CPU: 2000, MemoryUsage: 10, MemoryLimit: 20, Pids: 3}
+	for _, m := range metrics {
+		data, err := typeurl.MarshalAny(m)
+		if err != nil {
+			t.Fatalf("Failed to marshal metrics: %v", err)
+		}
+		stats, err := metricStats(&types.Metric{Data: data})
+		if err != nil {
+			t.Fatalf("Failed to decode metrics: %v", err)
+		}
+		if *stats != expected {
+			t.Errorf("Expected %v, got %v", expected, *stats)
+		}
+	}
+}
+
+func TestStaleEphemeral(t *testing.T) {
+
+	ctrdRun := &containerdRuntime{context: context.Background()}
+	ctrdCtr := &testCtrdContainer{}
+	if !staleEphemeral(ctrdRun, ctrdCtr) {
+		t.Errorf("Container without a task should be stale")
+	}
+
+	tasks := []struct {
+		status containerd.ProcessStatus
+		stale  bool
+	}{
+		{containerd.Created, false},
+		{containerd.Running, false},
+		{containerd.Paused, false},
+		{containerd.Stopped, true},
+	}
+	for _, tc := range tasks {
+		ctrdCtr.task = &testCtrdTask{status: tc.status}
+		if staleEphemeral(ctrdRun, ctrdCtr) != tc.stale {
+			t.Errorf("Container with a %s task: expected stale %t", tc.status, tc.stale)
+		}
+	}
+}
+
+func TestIsExtractSnapshot(t *testing.T) {
+
+	startedAt := time.Now()
+	snaps := []struct {
+		info     snapshots.Info
+		expected bool
+	}{
+		{snapshots.Info{Name: "extract-1234 sha256:abcd", Kind: snapshots.KindActive,
+			Created: startedAt.Add(time.Second)}, true},
+		{snapshots.Info{Name: "extract-1234 sha256:abcd", Kind: snapshots.KindActive,
+			Created: startedAt.Add(-time.Second)}, false},
+		{snapshots.Info{Name: "extract-1234 sha256:abcd", Kind: snapshots.KindCommitted,
+			Created: startedAt.Add(time.Second)}, false},
+		{snapshots.Info{Name: "abcd-1234", Kind: snapshots.KindActive,
+			Created: startedAt.Add(time.Second)}, false},
+	}
+
+	for i, s := range snaps {
+		if isExtractSnapshot(s.info, startedAt) != s.expected {
+			t.Errorf("Snapshot %d: expected %t", i, s.expected)
+		}
+	}
+}
+
+func TestSnapshotLastUsedAt(t *testing.T) {
+
+	created := time.Now().Add(-time.Hour).UTC()
+	used := created.Add(30 * time.Minute)
+	snaps := []struct {
+		labels   map[string]string
+		expected time.Time
+	}{
+		{nil, created},
+		{map[string]string{containerdSnapshotLastUsedLabel: used.Format(time.RFC3339Nano)}, used},
+		{map[string]string{containerdSnapshotLastUsedLabel: "invalid"}, created},
+		{map[string]string{containerdSnapshotLastUsedLabel: created.Add(-time.Hour).
+			Format(time.RFC3339Nano)}, created},
+	}
+
+	for i, s := range snaps {
+		info := snapshots.Info{Name: "snap", Created: created, Labels: s.labels}
+		if lastUsed := snapshotLastUsedAt(info); !lastUsed.Equal(s.expected) {
+			t.Errorf("Snapshot %d: expected %v, got %v", i, s.expected, lastUsed)
+		}
+	}
+}
+
+func TestIsPlatformMismatch(t *testing.T) {
+
+	// the index only provides a manifest for a different platform
+	blob, err := json.Marshal(ocispec.Index{
+		Manifests: []ocispec.Descriptor{{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Platform:  &ocispec.Platform{OS: "linux", Architecture: "s390x"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal image index: %v", err)
+	}
+	store := &testContentStore{blob: blob}
+	only := platforms.Only(ocispec.Platform{OS: "linux", Architecture: "amd64"})
+
+	_, mismatch := images.Manifest(context.Background(), store,
+		ocispec.Descriptor{MediaType: ocispec.MediaTypeImageIndex}, only)
+	_, unknown := images.Manifest(context.Background(), store,
+		ocispec.Descriptor{MediaType: "unknown"}, only)
+
+	errs := []struct {
+		err      error
+		expected bool
+	}{
+		{mismatch, true},
+		{unknown, false},
+		{ctrderr.ErrNotFound, false},
+		{errors.New("no match for platform"), false},
+		{nil, false},
+	}
+
+	for i, e := range errs {
+		if isPlatformMismatch(e.err) != e.expected {
+			t.Errorf("Error %d '%v': expected %t", i, e.err, e.expected)
+		}
+	}
+}
+
+func TestWaitIdle(t *testing.T) {
+
+	var buf bytes.Buffer
+	activity := make(chan struct{}, 1)
+	aw := &activityWriter{w: &buf, activity: activity}
+
+	done := make(chan struct{})
+	go func() {
+		waitIdle(activity, 100*time.Millisecond)
+		close(done)
+	}()
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(10 * time.Millisecond)
+		aw.Write([]byte("x"))
+		select {
+		case <-done:
+			t.Fatalf("waitIdle returned while writing")
+		default:
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("waitIdle didn't return after the writes stopped")
+	}
+	if buf.String() != "xxxx" {
+		t.Errorf("Unexpected output: %q", buf.String())
+	}
+}
+
+func TestSocketError(t *testing.T) {
+
+	socket := "/run/containerd/containerd.sock"
+
+	tests := []struct {
+		err   error
+		cause error
+	}{
+		{&os.PathError{Op: "stat", Path: socket, Err: syscall.ENOENT}, errdefs.ErrNotFound},
+		{&os.PathError{Op: "stat", Path: socket, Err: syscall.EACCES}, errdefs.ErrPermissionDenied},
+		{fmt.Errorf("failed to dial %q: connection error: desc = \"transport: error while "+
+			"dialing: dial unix %s: connect: permission denied\"", socket, socket),
+			errdefs.ErrPermissionDenied},
+		{fmt.Errorf("failed to dial %q: context deadline exceeded", socket),
+			errdefs.ErrUnavailable},
+	}
+
+	for _, test := range tests {
+		err := socketError(socket, test.err)
+		if !errors.Is(err, test.cause) {
+			t.Errorf("Expected '%v' for '%v', got '%v'", test.cause, test.err, err)
+		}
+		if errdefs.Resource(err) != runtime.SocketResource {
+			t.Errorf("Expected socket resource for '%v'", err)
+		}
+	}
+}
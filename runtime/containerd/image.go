@@ -13,7 +13,9 @@ import (
 	ctrderr "github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/platforms"
 	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
 
 	digest "github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/identity"
@@ -59,28 +61,21 @@ func updateImageProgress(ctrdRun *containerdRuntime, ctx context.Context,
 	}
 }
 
-// updateImageStatus sends the status of the current image download to the progress channel.
+// updateImageStatus returns the status of the current image download.
 func updateImageStatus(ctx context.Context, start time.Time, cs content.Store,
 	mutex *sync.Mutex, descs *[]ocispec.Descriptor) ([]runtime.ProgressStatus, error) {
 
-	statuses := []runtime.ProgressStatus{}
-	actStats := map[string]*runtime.ProgressStatus{}
+	layers := []layerState{}
+	isActive := map[string]bool{}
 
 	active, err := cs.ListStatuses(ctx, "")
 	if err == nil {
-		for _, active := range active {
-			if !strings.HasPrefix(active.Ref, "layer-") {
+		for i := range active {
+			if !strings.HasPrefix(active[i].Ref, "layer-") {
 				continue
 			}
-			statuses = append(statuses, runtime.ProgressStatus{
-				Reference: active.Ref,
-				Status:    runtime.StatusRunning,
-				Offset:    active.Offset,
-				Total:     active.Total,
-				StartedAt: active.StartedAt,
-				UpdatedAt: active.UpdatedAt,
-			})
-			actStats[active.Ref] = &statuses[len(statuses)-1]
+			layers = append(layers, layerState{ref: active[i].Ref, active: &active[i]})
+			isActive[active[i].Ref] = true
 		}
 	}
 
@@ -90,10 +85,7 @@ func updateImageStatus(ctx context.Context, start time.Time, cs content.Store,
 	for _, desc := range *descs {
 
 		ref := remotes.MakeRefKey(ctx, desc)
-		if !strings.HasPrefix(ref, "layer-") {
-			continue
-		}
-		if _, isActive := actStats[ref]; isActive {
+		if !strings.HasPrefix(ref, "layer-") || isActive[ref] {
 			continue
 		}
 
@@ -102,13 +94,45 @@ func updateImageStatus(ctx context.Context, start time.Time, cs content.Store,
 			continue
 		}
 
+		layer := layerState{ref: ref}
+		if err == nil {
+			layer.info = &info
+		}
+		layers = append(layers, layer)
+	}
+
+	return imageProgress(start, layers), nil
+}
+
+// layerState describes the state of an image layer in the content store.
+type layerState struct {
+	ref    string
+	active *content.Status // status of an active download, nil otherwise
+	info   *content.Info   // content information, nil if not in the content store
+}
+
+// imageProgress returns the progress status for the provided layer states. A layer is pending
+// until the download starts, running while it is downloaded and extracted, and complete when
+// the uncompressed content is available. Layers that were created before the start time
+// already existed.
+func imageProgress(start time.Time, layers []layerState) []runtime.ProgressStatus {
+
+	statuses := []runtime.ProgressStatus{}
+
+	for _, layer := range layers {
+
 		stat := runtime.ProgressStatus{
-			Reference: ref,
-			Status:    runtime.StatusUnknown,
+			Reference: layer.ref,
+			Status:    runtime.StatusPending,
 		}
-		if err != nil && ctrderr.IsNotFound(err) {
-			stat.Status = runtime.StatusPending
-		} else if err == nil {
+
+		if active := layer.active; active != nil {
+			stat.Status = runtime.StatusRunning
+			stat.Offset = active.Offset
+			stat.Total = active.Total
+			stat.StartedAt = active.StartedAt
+			stat.UpdatedAt = active.UpdatedAt
+		} else if info := layer.info; info != nil {
 			if info.CreatedAt.After(start) {
 				if _, done := info.Labels["containerd.io/uncompressed"]; done {
 					stat.Status = runtime.StatusComplete
@@ -125,15 +149,139 @@ func updateImageStatus(ctx context.Context, start time.Time, cs content.Store,
 		statuses = append(statuses, stat)
 	}
 
-	return statuses, nil
+	return statuses
+}
+
+// updatePushProgress sends the current image upload status in a regular 100ms interval
+// to the provided progress channel.
+func updatePushProgress(ctrdRun *containerdRuntime, ctx context.Context,
+	tracker docker.StatusTracker, mutex *sync.Mutex, descs *[]ocispec.Descriptor,
+	progress chan<- []runtime.ProgressStatus) {
+
+	ticker := time.NewTicker(time.Duration(updateIntervalMsecs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for loop := true; loop; {
+		done := false
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			done = true
+			loop = false
+		}
+
+		mutex.Lock()
+		uploads := []uploadState{}
+		for _, desc := range *descs {
+			ref := remotes.MakeRefKey(ctrdRun.context, desc)
+			upload := uploadState{ref: ref}
+			if status, err := tracker.GetStatus(ref); err == nil {
+				upload.status = &status.Status
+			}
+			uploads = append(uploads, upload)
+		}
+		mutex.Unlock()
+
+		progress <- pushProgress(uploads, done)
+	}
+}
+
+// uploadState describes the state of an upload to the registry.
+type uploadState struct {
+	ref    string
+	status *content.Status // status of the upload, nil if the upload hasn't started
+}
+
+// pushProgress returns the progress status for the provided upload states. An upload is pending
+// until it starts, running while it is uploaded, and complete when all content was uploaded.
+// Uploads that didn't start when the push is done already existed in the registry.
+func pushProgress(uploads []uploadState, done bool) []runtime.ProgressStatus {
+
+	statuses := []runtime.ProgressStatus{}
+
+	for _, upload := range uploads {
+
+		stat := runtime.ProgressStatus{
+			Reference: upload.ref,
+			Status:    runtime.StatusPending,
+		}
+
+		if status := upload.status; status != nil {
+			stat.Status = runtime.StatusRunning
+			if status.Total > 0 && status.Offset >= status.Total {
+				stat.Status = runtime.StatusComplete
+			}
+			stat.Offset = status.Offset
+			stat.Total = status.Total
+			stat.StartedAt = status.StartedAt
+			stat.UpdatedAt = status.UpdatedAt
+		} else if done {
+			stat.Status = runtime.StatusExists
+		}
+		statuses = append(statuses, stat)
+	}
+
+	return statuses
 }
 
 type image struct {
 	ctrdRuntime *containerdRuntime
 	ctrdImage   containerd.Image
+	mutex       sync.Mutex
+	ociImg      *ocispec.Image // cached image configuration
 }
 
-func (img *image) Config() (*ocispec.ImageConfig, error) {
+// imagePlatform returns the platform matcher for the platform recorded in the image labels
+// when the image was pulled or the default platform for images without a recorded platform.
+func imagePlatform(labels map[string]string) platforms.MatchComparer {
+
+	if platform, ok := labels[containerdPlatformLabel]; ok {
+		if p, err := platforms.Parse(platform); err == nil {
+			return platforms.Only(p)
+		}
+	}
+	return platforms.Default()
+}
+
+// newImage returns the image for the containerd image. The image uses the platform for which
+// it was pulled to select the manifest, configuration, and layers.
+func newImage(ctrdRun *containerdRuntime, ctrdImg containerd.Image) *image {
+
+	labels := ctrdImg.Labels()
+	img := images.Image{
+		Name:   ctrdImg.Name(),
+		Target: ctrdImg.Target(),
+		Labels: labels,
+	}
+	return &image{
+		ctrdRuntime: ctrdRun,
+		ctrdImage: containerd.NewImageWithPlatform(ctrdRun.client, img,
+			imagePlatform(labels)),
+	}
+}
+
+// ociImage returns the OCI image configuration, which includes the history of the image.
+// The configuration is read from the content store only once and cached in the image.
+func (img *image) ociImage() (*ocispec.Image, error) {
+
+	img.mutex.Lock()
+	defer img.mutex.Unlock()
+
+	if img.ociImg != nil {
+		return img.ociImg, nil
+	}
+
+	ociImg, err := readOCIImage(img)
+	if err != nil {
+		return nil, err
+	}
+	img.ociImg = ociImg
+	return ociImg, nil
+}
+
+// readOCIImage reads the OCI image configuration from the content store.
+func readOCIImage(img *image) (*ocispec.Image, error) {
 
 	ctrdRun := img.ctrdRuntime
 	ociDesc, err := img.ctrdImage.Config(ctrdRun.context)
@@ -141,10 +289,7 @@ func (img *image) Config() (*ocispec.ImageConfig, error) {
 		return nil, runtime.Errorf("failed to get image configuration: %v", err)
 	}
 
-	var (
-		ociimage ocispec.Image
-		config   ocispec.ImageConfig
-	)
+	var ociimage ocispec.Image
 
 	switch ociDesc.MediaType {
 	case ocispec.MediaTypeImageConfig, images.MediaTypeDockerSchema2Config:
@@ -157,12 +302,62 @@ func (img *image) Config() (*ocispec.ImageConfig, error) {
 		if err := json.Unmarshal(blob, &ociimage); err != nil {
 			return nil, runtime.Errorf("error in image YAML configuration: %v", err)
 		}
-		config = ociimage.Config
 	default:
 		return nil, runtime.Errorf("unknown image config media type %s", ociDesc.MediaType)
 	}
 
-	return &config, nil
+	return &ociimage, nil
+}
+
+func (img *image) Config() (*ocispec.ImageConfig, error) {
+
+	ociimage, err := img.ociImage()
+	if err != nil {
+		return nil, err
+	}
+	return &ociimage.Config, nil
+}
+
+// imageHistory combines the history entries of the image configuration with the layer
+// descriptors of the manifest. Entries that don't create a layer are marked as empty.
+func imageHistory(history []ocispec.History,
+	layers []ocispec.Descriptor) []runtime.ImageHistory {
+
+	entries := make([]runtime.ImageHistory, len(history))
+	l := 0
+	for i, h := range history {
+		entries[i] = runtime.ImageHistory{
+			CreatedBy:  h.CreatedBy,
+			Comment:    h.Comment,
+			EmptyLayer: h.EmptyLayer,
+		}
+		if h.Created != nil {
+			entries[i].CreatedAt = *h.Created
+		}
+		if !h.EmptyLayer && l < len(layers) {
+			entries[i].Layer = layers[l].Digest
+			entries[i].Size = layers[l].Size
+			l++
+		}
+	}
+	return entries
+}
+
+func (img *image) History() ([]runtime.ImageHistory, error) {
+
+	ociimage, err := img.ociImage()
+	if err != nil {
+		return nil, err
+	}
+
+	ctrdRun := img.ctrdRuntime
+	manifest, err := images.Manifest(ctrdRun.context, img.ctrdImage.ContentStore(),
+		img.ctrdImage.Target(), imagePlatform(img.ctrdImage.Labels()))
+	if err != nil {
+		return nil, runtime.Errorf("failed to get image manifest: %v", err)
+	}
+
+	return imageHistory(ociimage.History, manifest.Layers), nil
 }
 
 func (img *image) Digest() digest.Digest {
@@ -188,7 +383,16 @@ func (img *image) CreatedAt() time.Time {
 	/* TODO: Image.Metadata()Is supposed to be available in containerd 1.3.3
 	   return img.ctrdImage.Metadata().CreatedAt
 	*/
-	return time.Now()
+	ctrdRun := img.ctrdRuntime
+	ctrdImg, err := ctrdRun.client.ImageService().Get(ctrdRun.context, img.ctrdImage.Name())
+	if err != nil {
+		return time.Now()
+	}
+	return ctrdImg.CreatedAt
+}
+
+func (img *image) Labels() map[string]string {
+	return img.ctrdImage.Labels()
 }
 
 func (img *image) Size() int64 {
@@ -196,6 +400,28 @@ func (img *image) Size() int64 {
 	return size
 }
 
+func (img *image) Tag(name string) (runtime.Image, error) {
+
+	ctrdRun := img.ctrdRuntime
+	imgSvc := ctrdRun.client.ImageService()
+
+	newImg := images.Image{
+		Name:   name,
+		Target: img.ctrdImage.Target(),
+		Labels: img.ctrdImage.Labels(),
+	}
+
+	created, err := imgSvc.Create(ctrdRun.context, newImg)
+	if err != nil && ctrderr.IsAlreadyExists(err) {
+		created, err = imgSvc.Update(ctrdRun.context, newImg, "target")
+	}
+	if err != nil {
+		return nil, runtime.Errorf("failed to tag image '%s': %v", name, err)
+	}
+
+	return newImage(ctrdRun, containerd.NewImage(ctrdRun.client, created)), nil
+}
+
 func (img *image) Mount(path string) error {
 
 	ctrdRun := img.ctrdRuntime
@@ -0,0 +1,308 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	"github.com/containerd/containerd/content"
+	ctrderr "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"google.golang.org/grpc"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+func TestImageProgress(t *testing.T) {
+
+	start := time.Now()
+	ref := "layer-sha256:0123456789abcdef"
+	size := int64(1000)
+
+	// layer: waiting -> downloading -> extracting -> complete
+	sequence := []struct {
+		layer  layerState
+		status string
+		offset int64
+	}{
+		{layerState{ref: ref}, runtime.StatusPending, 0},
+		{layerState{ref: ref, active: &content.Status{
+			Ref: ref, Offset: 400, Total: size, StartedAt: start}},
+			runtime.StatusRunning, 400},
+		{layerState{ref: ref, info: &content.Info{
+			Size: size, CreatedAt: start.Add(time.Second)}},
+			runtime.StatusRunning, size},
+		{layerState{ref: ref, info: &content.Info{
+			Size: size, CreatedAt: start.Add(time.Second),
+			Labels: map[string]string{"containerd.io/uncompressed": "sha256:0"}}},
+			runtime.StatusComplete, size},
+	}
+
+	for i, s := range sequence {
+		statuses := imageProgress(start, []layerState{s.layer})
+		if len(statuses) != 1 {
+			t.Fatalf("Step %d: expected one status, got %d", i, len(statuses))
+		}
+		stat := statuses[0]
+		if stat.Reference != ref {
+			t.Errorf("Step %d: expected reference '%s', got '%s'", i, ref, stat.Reference)
+		}
+		if stat.Status != s.status {
+			t.Errorf("Step %d: expected status '%s', got '%s'", i, s.status, stat.Status)
+		}
+		if stat.Offset != s.offset {
+			t.Errorf("Step %d: expected offset %d, got %d", i, s.offset, stat.Offset)
+		}
+		if s.status != runtime.StatusPending && stat.Total != size {
+			t.Errorf("Step %d: expected total %d, got %d", i, size, stat.Total)
+		}
+	}
+
+	// layers that existed before the pull
+	statuses := imageProgress(start, []layerState{
+		{ref: ref, info: &content.Info{Size: size, CreatedAt: start.Add(-time.Hour)}},
+		{ref: "layer-sha256:fedcba9876543210"},
+	})
+	if len(statuses) != 2 {
+		t.Fatalf("Expected two statuses, got %d", len(statuses))
+	}
+	if statuses[0].Status != runtime.StatusExists {
+		t.Errorf("Expected existing layer, got '%s'", statuses[0].Status)
+	}
+	if statuses[1].Status != runtime.StatusPending {
+		t.Errorf("Expected pending layer, got '%s'", statuses[1].Status)
+	}
+}
+
+func TestPushProgress(t *testing.T) {
+
+	ref := "layer-sha256:0123456789abcdef"
+	size := int64(1000)
+
+	sequence := []struct {
+		upload uploadState
+		done   bool
+		status string
+	}{
+		{uploadState{ref: ref}, false, runtime.StatusPending},
+		{uploadState{ref: ref, status: &content.Status{Offset: 400, Total: size}},
+			false, runtime.StatusRunning},
+		{uploadState{ref: ref, status: &content.Status{Offset: size, Total: size}},
+			false, runtime.StatusComplete},
+		{uploadState{ref: ref}, true, runtime.StatusExists},
+	}
+
+	for i, s := range sequence {
+		statuses := pushProgress([]uploadState{s.upload}, s.done)
+		if len(statuses) != 1 {
+			t.Fatalf("Step %d: expected one status, got %d", i, len(statuses))
+		}
+		if statuses[0].Status != s.status {
+			t.Errorf("Step %d: expected status %s, got %s", i, s.status, statuses[0].Status)
+		}
+	}
+}
+
+func TestImageHistory(t *testing.T) {
+
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []ocispec.History{
+		{Created: &created, CreatedBy: "ADD rootfs.tar /"},
+		{CreatedBy: "ENV PATH=/bin", EmptyLayer: true},
+		{CreatedBy: "RUN apk add curl"},
+	}
+	layers := []ocispec.Descriptor{
+		{Digest: digest.FromString("base"), Size: 1000},
+		{Digest: digest.FromString("curl"), Size: 200},
+	}
+
+	entries := imageHistory(history, layers)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if !entries[0].CreatedAt.Equal(created) || entries[0].Size != 1000 {
+		t.Errorf("Unexpected first entry %v", entries[0])
+	}
+	if !entries[1].EmptyLayer || entries[1].Layer != "" || entries[1].Size != 0 {
+		t.Errorf("Empty layer not marked %v", entries[1])
+	}
+	if entries[2].Layer != layers[1].Digest || entries[2].Size != 200 {
+		t.Errorf("Layer not assigned to entry %v", entries[2])
+	}
+}
+
+func TestImagePlatform(t *testing.T) {
+
+	s390x := ocispec.Platform{OS: "linux", Architecture: "s390x"}
+
+	p := imagePlatform(map[string]string{containerdPlatformLabel: "linux/s390x"})
+	if !p.Match(s390x) {
+		t.Errorf("Recorded platform not used for the image")
+	}
+
+	for _, labels := range []map[string]string{nil, {containerdPlatformLabel: "/"}} {
+		p = imagePlatform(labels)
+		if !p.Match(platforms.DefaultSpec()) {
+			t.Errorf("Labels %v: default platform not used for the image", labels)
+		}
+	}
+}
+
+// testContentStore provides the image configuration blob and counts the reads.
+type testContentStore struct {
+	content.Store
+	blob  []byte
+	reads int
+}
+
+type testReaderAt struct {
+	*bytes.Reader
+}
+
+func (ra *testReaderAt) Close() error {
+	return nil
+}
+
+func (store *testContentStore) ReaderAt(ctx context.Context,
+	desc ocispec.Descriptor) (content.ReaderAt, error) {
+	store.reads++
+	return &testReaderAt{bytes.NewReader(store.blob)}, nil
+}
+
+type testCtrdImage struct {
+	containerd.Image
+	name  string
+	store *testContentStore
+}
+
+func (ctrdImg *testCtrdImage) Name() string {
+	return ctrdImg.name
+}
+
+func (ctrdImg *testCtrdImage) Target() ocispec.Descriptor {
+	return ocispec.Descriptor{}
+}
+
+func (ctrdImg *testCtrdImage) Labels() map[string]string {
+	return nil
+}
+
+func (ctrdImg *testCtrdImage) Config(ctx context.Context) (ocispec.Descriptor, error) {
+	return ocispec.Descriptor{MediaType: ocispec.MediaTypeImageConfig}, nil
+}
+
+func (ctrdImg *testCtrdImage) ContentStore() content.Store {
+	return ctrdImg.store
+}
+
+func newTestCtrdImage(t testing.TB) *testCtrdImage {
+
+	blob, err := json.Marshal(ocispec.Image{
+		Config: ocispec.ImageConfig{Entrypoint: []string{"/bin/sh"}, Cmd: []string{"-c"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal image configuration: %v", err)
+	}
+	return &testCtrdImage{store: &testContentStore{blob: blob}}
+}
+
+func TestImageConfigCache(t *testing.T) {
+
+	ctrdRun := &containerdRuntime{context: context.Background()}
+	ctrdImg := newTestCtrdImage(t)
+	img := &image{ctrdRuntime: ctrdRun, ctrdImage: ctrdImg}
+
+	for i := 0; i < 3; i++ {
+		config, err := img.Config()
+		if err != nil {
+			t.Fatalf("Failed to get image configuration: %v", err)
+		}
+		if len(config.Cmd) != 1 || config.Cmd[0] != "-c" {
+			t.Errorf("Unexpected image configuration: %v", config)
+		}
+	}
+	if ctrdImg.store.reads != 1 {
+		t.Errorf("Expected one read from the content store, got %d", ctrdImg.store.reads)
+	}
+}
+
+// BenchmarkImageConfig compares reading the image configuration for a list of containers
+// with separate images for each container and with images shared between the containers.
+func BenchmarkImageConfig(b *testing.B) {
+
+	const numContainers = 100
+	ctrdRun := &containerdRuntime{context: context.Background()}
+
+	b.Run("separate", func(b *testing.B) {
+		ctrdImg := newTestCtrdImage(b)
+		for i := 0; i < b.N; i++ {
+			for c := 0; c < numContainers; c++ {
+				img := &image{ctrdRuntime: ctrdRun, ctrdImage: ctrdImg}
+				img.Config()
+			}
+		}
+		b.ReportMetric(float64(ctrdImg.store.reads)/float64(b.N), "reads/op")
+	})
+
+	b.Run("shared", func(b *testing.B) {
+		ctrdImg := newTestCtrdImage(b)
+		for i := 0; i < b.N; i++ {
+			img := &image{ctrdRuntime: ctrdRun, ctrdImage: ctrdImg}
+			for c := 0; c < numContainers; c++ {
+				img.Config()
+			}
+		}
+		b.ReportMetric(float64(ctrdImg.store.reads)/float64(b.N), "reads/op")
+	})
+}
+
+// testImagesClient provides the image service functions used by the runtime.
+type testImagesClient struct {
+	imagesapi.ImagesClient
+}
+
+func (imgClient *testImagesClient) Get(ctx context.Context, req *imagesapi.GetImageRequest,
+	opts ...grpc.CallOption) (*imagesapi.GetImageResponse, error) {
+	return nil, ctrderr.ToGRPC(ctrderr.ErrNotFound)
+}
+
+func TestPushUnknownImage(t *testing.T) {
+
+	client, err := containerd.New("", containerd.WithServices(
+		containerd.WithImageService(&testImagesClient{})))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctrdRun := &containerdRuntime{
+		client:  client,
+		context: namespaces.WithNamespace(context.Background(), "test"),
+	}
+
+	progress := make(chan []runtime.ProgressStatus)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range progress {
+		}
+	}()
+
+	err = ctrdRun.PushImage("docker.io/library/unknown:latest", "", progress)
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Progress channel not closed after push failed")
+	}
+}
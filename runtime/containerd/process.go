@@ -14,16 +14,18 @@ import (
 type process struct {
 	container *container
 	ctrdProc  containerd.Process
+	output    <-chan struct{} // closed after copying the output, if not nil
 }
 
 func (proc *process) Wait() (<-chan runtime.ExitStatus, error) {
 
 	ctrdRun := proc.container.ctrdRuntime
-	runExitStatus := make(chan runtime.ExitStatus)
+	runExitStatus := make(chan runtime.ExitStatus, 1)
 
 	ctrdExitStatus, err := proc.ctrdProc.Wait(ctrdRun.context)
 	if err != nil && ctrderr.IsNotFound(err) {
 		runExitStatus <- runtime.ExitStatus{}
+		close(runExitStatus)
 		return runExitStatus, nil
 	}
 	if err != nil {
@@ -34,6 +36,9 @@ func (proc *process) Wait() (<-chan runtime.ExitStatus, error) {
 		defer close(runExitStatus)
 
 		exitStatus := <-ctrdExitStatus
+		if proc.output != nil {
+			<-proc.output
+		}
 		code, exitedAt, err := exitStatus.Result()
 		runExitStatus <- runtime.ExitStatus{
 			ExitTime: exitedAt,
@@ -54,3 +59,12 @@ func (proc *process) Signal(sig os.Signal) error {
 	}
 	return nil
 }
+
+func (proc *process) Resize(width, height uint32) error {
+
+	err := proc.ctrdProc.Resize(proc.container.ctrdRuntime.context, width, height)
+	if err != nil {
+		return runtime.Errorf("resize failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package containerd
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/reference"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/runtime"
+)
+
+// proxyFunc is the function that selects the proxy for a request.
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+// newProxyFunc returns the proxy function for the proxy configuration. Empty fields of the
+// configuration fall back to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.
+func newProxyFunc(proxy config.Proxy) proxyFunc {
+
+	proxyConf := httpproxy.FromEnvironment()
+	if proxy.HTTP != "" {
+		proxyConf.HTTPProxy = proxy.HTTP
+	}
+	if proxy.HTTPS != "" {
+		proxyConf.HTTPSProxy = proxy.HTTPS
+	}
+	if proxy.NoProxy != "" {
+		proxyConf.NoProxy = proxy.NoProxy
+	}
+
+	fn := proxyConf.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return fn(req.URL)
+	}
+}
+
+// wrapTransport returns the transport wrapped by the optional wrapper.
+func wrapTransport(wrap transportWrapper, transport http.RoundTripper) http.RoundTripper {
+	if wrap == nil {
+		return transport
+	}
+	return wrap(transport)
+}
+
+// newTransport returns a transport with the settings of the default transport that uses the
+// proxy function.
+func newTransport(proxy proxyFunc) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	return transport
+}
+
+// registryHosts returns the registry host configurations that authorize requests with the
+// optional credentials and use the proxy function, or the proxies of the environment if nil.
+// The optional wrapper wraps the transports of the hosts. Insecure registries are accessed
+// over HTTPS without verifying the certificate or, if the registry doesn't answer HTTPS
+// requests, over plain HTTP.
+func registryHosts(creds runtime.Credentials, insecure runtime.InsecureRegistry,
+	proxy proxyFunc, wrap transportWrapper) docker.RegistryHosts {
+
+	authOpts := []docker.AuthorizerOpt{docker.WithAuthCreds(creds)}
+	var hostOpts []docker.RegistryOpt
+	if proxy != nil || wrap != nil {
+		if proxy == nil {
+			proxy = http.ProxyFromEnvironment
+		}
+		client := &http.Client{Transport: wrapTransport(wrap, newTransport(proxy))}
+		authOpts = append(authOpts, docker.WithAuthClient(client))
+		hostOpts = append(hostOpts, docker.WithClient(client))
+	} else {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	authorizer := docker.NewDockerAuthorizer(authOpts...)
+	hostOpts = append(hostOpts, docker.WithAuthorizer(authorizer))
+	secureHosts := docker.ConfigureDefaultRegistries(hostOpts...)
+	if insecure == nil {
+		return secureHosts
+	}
+
+	insecureTransport := newTransport(proxy)
+	insecureTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	insecureClient := &http.Client{Transport: wrapTransport(wrap, insecureTransport)}
+	insecureAuthorizer := docker.NewDockerAuthorizer(docker.WithAuthCreds(creds),
+		docker.WithAuthClient(insecureClient))
+	skipVerifyHosts := docker.ConfigureDefaultRegistries(
+		docker.WithAuthorizer(insecureAuthorizer),
+		docker.WithClient(insecureClient))
+	plainHTTPHosts := docker.ConfigureDefaultRegistries(
+		docker.WithAuthorizer(insecureAuthorizer),
+		docker.WithClient(insecureClient),
+		docker.WithPlainHTTP(docker.MatchAllHosts))
+
+	// the resolver doesn't fall back to the next host on connection errors, so probe once
+	// whether the insecure registry answers HTTPS and use only the working endpoint
+	var mutex sync.Mutex
+	useHTTPS := make(map[string]bool)
+
+	return func(host string) ([]docker.RegistryHost, error) {
+		if !insecure(host) {
+			return secureHosts(host)
+		}
+		hosts, err := skipVerifyHosts(host)
+		if err != nil || len(hosts) == 0 {
+			return hosts, err
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		https, ok := useHTTPS[host]
+		if !ok {
+			https = probeRegistryHost(hosts[0])
+			useHTTPS[host] = https
+		}
+		if https {
+			return hosts, nil
+		}
+		return plainHTTPHosts(host)
+	}
+}
+
+// registryProbeTimeout is the time to wait for a registry to answer a probe.
+const registryProbeTimeout = 10 * time.Second
+
+// probeRegistryHost returns true if the registry host answers requests to the API endpoint.
+// Any response, including authorization errors, counts as an answer.
+func probeRegistryHost(host docker.RegistryHost) bool {
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, host.Scheme+"://"+host.Host+host.Path+"/", nil)
+	if err != nil {
+		return false
+	}
+	client := host.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// newResolver returns a resolver for accessing registries. The resolver uses the credentials,
+// insecure registries, and proxies set for the runtime and the optional tracker for tracking
+// uploads.
+func newResolver(ctrdRun *containerdRuntime, tracker docker.StatusTracker) remotes.Resolver {
+
+	opts := docker.ResolverOptions{Tracker: tracker}
+	if ctrdRun.credentials != nil || ctrdRun.insecure != nil || ctrdRun.proxy != nil {
+		opts.Hosts = registryHosts(ctrdRun.credentials, ctrdRun.insecure, ctrdRun.proxy, nil)
+	}
+	return docker.NewResolver(opts)
+}
+
+// mirrorHosts returns the registry host configuration for the mirror endpoint. The endpoint
+// uses the settings of the registry hosts for the host of the endpoint, and the API path is
+// appended to the path of the endpoint unless it already ends with it. Mirrors are only used
+// for pulling images.
+func mirrorHosts(hosts docker.RegistryHosts, endpoint string) docker.RegistryHosts {
+
+	return func(string) ([]docker.RegistryHost, error) {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		regHosts, err := hosts(u.Host)
+		if err != nil || len(regHosts) == 0 {
+			return nil, err
+		}
+
+		regHost := regHosts[0]
+		for _, h := range regHosts {
+			if h.Scheme == u.Scheme {
+				regHost = h
+				break
+			}
+		}
+
+		apiPath := strings.TrimSuffix(u.Path, "/")
+		if !strings.HasSuffix(apiPath, "/v2") {
+			apiPath += "/v2"
+		}
+		regHost.Host = u.Host
+		regHost.Scheme = u.Scheme
+		regHost.Path = apiPath
+		regHost.Capabilities = docker.HostCapabilityPull | docker.HostCapabilityResolve
+		return []docker.RegistryHost{regHost}, nil
+	}
+}
+
+// mirrorResolver is a resolver that tries the mirrors of the registry in order before the
+// registry itself. Fetchers use the mirror or registry that resolved the reference.
+type mirrorResolver struct {
+	origin   remotes.Resolver
+	hosts    docker.RegistryHosts
+	mirrors  runtime.RegistryMirrors
+	mutex    sync.Mutex
+	resolved map[string]remotes.Resolver
+}
+
+// newPullResolver returns a resolver for pulling images that uses the mirrors set for the
+// runtime in addition to the settings used by newResolver. The optional wrapper wraps the
+// transports for accessing the registry and mirrors.
+func newPullResolver(ctrdRun *containerdRuntime, wrap transportWrapper) remotes.Resolver {
+
+	hosts := registryHosts(ctrdRun.credentials, ctrdRun.insecure, ctrdRun.proxy, wrap)
+	origin := docker.NewResolver(docker.ResolverOptions{Hosts: hosts})
+	if ctrdRun.mirrors == nil {
+		return origin
+	}
+
+	return &mirrorResolver{
+		origin:   origin,
+		hosts:    hosts,
+		mirrors:  ctrdRun.mirrors,
+		resolved: make(map[string]remotes.Resolver),
+	}
+}
+
+func (mr *mirrorResolver) Resolve(ctx context.Context,
+	ref string) (string, ocispec.Descriptor, error) {
+
+	if refspec, err := reference.Parse(ref); err == nil {
+		for _, endpoint := range mr.mirrors(refspec.Hostname()) {
+			mirror := docker.NewResolver(docker.ResolverOptions{
+				Hosts: mirrorHosts(mr.hosts, endpoint),
+			})
+			name, desc, err := mirror.Resolve(ctx, ref)
+			if err == nil {
+				mr.setResolved(ref, mirror)
+				return name, desc, nil
+			}
+			if ctx.Err() != nil {
+				return "", ocispec.Descriptor{}, ctx.Err()
+			}
+		}
+	}
+
+	name, desc, err := mr.origin.Resolve(ctx, ref)
+	if err == nil {
+		mr.setResolved(ref, mr.origin)
+	}
+	return name, desc, err
+}
+
+// setResolved records the resolver that resolved the reference.
+func (mr *mirrorResolver) setResolved(ref string, resolver remotes.Resolver) {
+
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+
+	mr.resolved[ref] = resolver
+}
+
+func (mr *mirrorResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+
+	mr.mutex.Lock()
+	resolver, ok := mr.resolved[ref]
+	mr.mutex.Unlock()
+
+	if !ok {
+		resolver = mr.origin
+	}
+	return resolver.Fetcher(ctx, ref)
+}
+
+func (mr *mirrorResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	return mr.origin.Pusher(ctx, ref)
+}
@@ -0,0 +1,254 @@
+package containerd
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/czankel/cne/config"
+)
+
+// authorizeRequest returns the authorization header for a request to the host after the
+// registry responded with a basic authentication challenge.
+func authorizeRequest(t *testing.T, creds func(string) (string, string, error),
+	host string) string {
+
+	ctx := context.Background()
+	hosts, err := registryHosts(creds, nil, nil, nil)(host)
+	if err != nil || len(hosts) != 1 {
+		t.Fatalf("Failed to get registry host: %v", err)
+	}
+	authorizer := hosts[0].Authorizer
+
+	req, err := http.NewRequest("GET", "https://"+host+"/v2/", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp := &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Header:     http.Header{"Www-Authenticate": []string{`Basic realm="test"`}},
+		Request:    req,
+	}
+	authorizer.AddResponses(ctx, []*http.Response{resp})
+
+	req, err = http.NewRequest("GET", "https://"+host+"/v2/", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	err = authorizer.Authorize(ctx, req)
+	if err != nil {
+		t.Fatalf("Failed to authorize request: %v", err)
+	}
+	return req.Header.Get("Authorization")
+}
+
+func TestRegistryHostsCredentials(t *testing.T) {
+
+	creds := func(host string) (string, string, error) {
+		if host == "registry.example.com" {
+			return "user", "secret", nil
+		}
+		return "", "", nil
+	}
+
+	expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	auth := authorizeRequest(t, creds, "registry.example.com")
+	if auth != expected {
+		t.Errorf("Expected authorization '%s', got '%s'", expected, auth)
+	}
+
+	auth = authorizeRequest(t, creds, "other.example.com")
+	if auth != "" {
+		t.Errorf("Unexpected authorization for other host: '%s'", auth)
+	}
+}
+
+func TestRegistryHostsInsecure(t *testing.T) {
+
+	var plainHits, tlsHits int32
+	plain := newTestRegistry(false, false, &plainHits)
+	defer plain.Close()
+	secure := newTestRegistry(true, false, &tlsHits)
+	defer secure.Close()
+
+	plainHost := strings.TrimPrefix(plain.URL, "http://")
+	tlsHost := strings.TrimPrefix(secure.URL, "https://")
+	insecure := func(host string) bool {
+		return host == plainHost || host == tlsHost
+	}
+	hosts := registryHosts(nil, insecure, nil, nil)
+
+	tests := []struct {
+		host   string
+		scheme string
+	}{
+		{plainHost, "http"},
+		{tlsHost, "https"},
+	}
+	for _, tc := range tests {
+		regHosts, err := hosts(tc.host)
+		if err != nil {
+			t.Fatalf("Failed to get registry hosts: %v", err)
+		}
+		if len(regHosts) != 1 || regHosts[0].Scheme != tc.scheme {
+			t.Errorf("Insecure registry %s should use %s: %v", tc.host, tc.scheme, regHosts)
+		} else if regHosts[0].Client == http.DefaultClient {
+			t.Errorf("Insecure registry should not use the default client")
+		}
+	}
+
+	// the plain HTTP registry must be reachable by the resolver
+	resolver := newResolver(&containerdRuntime{insecure: insecure}, nil)
+	_, _, err := resolver.Resolve(context.Background(), plainHost+"/library/test:latest")
+	if hits := atomic.LoadInt32(&plainHits); err != nil || hits != 1 {
+		t.Errorf("Failed to resolve image of plain HTTP registry (%d requests): %v", hits, err)
+	}
+
+	regHosts, err := hosts("docker.io")
+	if err != nil {
+		t.Fatalf("Failed to get registry hosts: %v", err)
+	}
+	if len(regHosts) != 1 || regHosts[0].Scheme != "https" ||
+		regHosts[0].Client != http.DefaultClient {
+		t.Errorf("Secure registry should use HTTPS with the default client: %v", regHosts)
+	}
+}
+
+func TestRegistryHostsProxy(t *testing.T) {
+
+	proxy := newProxyFunc(config.Proxy{
+		HTTPS:   "http://proxy.example.com:3128",
+		NoProxy: "internal.example.com",
+	})
+	hosts := registryHosts(nil, nil, proxy, nil)
+
+	tests := []struct {
+		host  string
+		proxy string
+	}{
+		{"registry.example.com", "http://proxy.example.com:3128"},
+		{"internal.example.com", ""},
+	}
+	for _, tc := range tests {
+		regHosts, err := hosts(tc.host)
+		if err != nil || len(regHosts) != 1 {
+			t.Fatalf("Failed to get registry host for %s: %v", tc.host, err)
+		}
+		transport, ok := regHosts[0].Client.Transport.(*http.Transport)
+		if !ok || transport.Proxy == nil {
+			t.Fatalf("Registry host %s should use a transport with a proxy", tc.host)
+		}
+
+		req, err := http.NewRequest("GET", "https://"+tc.host+"/v2/", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		proxyURL, err := transport.Proxy(req)
+		if err != nil {
+			t.Fatalf("Failed to get proxy for %s: %v", tc.host, err)
+		}
+		if tc.proxy == "" && proxyURL != nil {
+			t.Errorf("Host %s should bypass the proxy, got %v", tc.host, proxyURL)
+		} else if tc.proxy != "" && (proxyURL == nil || proxyURL.String() != tc.proxy) {
+			t.Errorf("Host %s should use proxy %s, got %v", tc.host, tc.proxy, proxyURL)
+		}
+	}
+}
+
+// testManifest is the manifest served by the test registries.
+const testManifest = `{"schemaVersion":2}`
+
+// newTestRegistry returns a registry server that serves the test manifest or, if failing is
+// set, responds with an internal server error. The counter is incremented for each request
+// other than requests to the API root.
+func newTestRegistry(tls, failing bool, counter *int32) *httptest.Server {
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		atomic.AddInt32(counter, 1)
+		if failing || !strings.HasSuffix(r.URL.Path, "/manifests/latest") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromString(testManifest).String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(testManifest)))
+		if r.Method == http.MethodGet {
+			io.WriteString(w, testManifest)
+		}
+	})
+	if tls {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func TestMirrorResolver(t *testing.T) {
+
+	var originHits, failingHits, mirrorHits int32
+	origin := newTestRegistry(true, false, &originHits)
+	defer origin.Close()
+	failing := newTestRegistry(false, true, &failingHits)
+	defer failing.Close()
+	mirror := newTestRegistry(false, false, &mirrorHits)
+	defer mirror.Close()
+
+	// an endpoint that refuses connections
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	originHost := strings.TrimPrefix(origin.URL, "https://")
+	ref := originHost + "/library/test:latest"
+
+	tests := []struct {
+		name        string
+		mirrors     []string
+		originHits  int32
+		failingHits int32
+		mirrorHits  int32
+	}{
+		{"next mirror", []string{closed.URL, failing.URL, mirror.URL + "/v2/"}, 0, 1, 1},
+		{"origin", []string{failing.URL, closed.URL}, 1, 1, 0},
+	}
+	for _, tc := range tests {
+		atomic.StoreInt32(&originHits, 0)
+		atomic.StoreInt32(&failingHits, 0)
+		atomic.StoreInt32(&mirrorHits, 0)
+
+		resolver := newPullResolver(&containerdRuntime{
+			insecure: func(host string) bool { return host == originHost },
+			mirrors: func(host string) []string {
+				if host == originHost {
+					return tc.mirrors
+				}
+				return nil
+			},
+		}, nil)
+
+		_, desc, err := resolver.Resolve(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("%s: failed to resolve reference: %v", tc.name, err)
+		}
+		if desc.Digest != digest.FromString(testManifest) {
+			t.Errorf("%s: unexpected digest %s", tc.name, desc.Digest)
+		}
+		if atomic.LoadInt32(&originHits) != tc.originHits ||
+			atomic.LoadInt32(&failingHits) != tc.failingHits ||
+			atomic.LoadInt32(&mirrorHits) != tc.mirrorHits {
+			t.Errorf("%s: expected origin/failing/mirror requests %d/%d/%d, got %d/%d/%d",
+				tc.name, tc.originHits, tc.failingHits, tc.mirrorHits,
+				originHits, failingHits, mirrorHits)
+		}
+	}
+}
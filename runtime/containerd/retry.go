@@ -0,0 +1,260 @@
+package containerd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/runtime"
+)
+
+// maxRetryDelay limits the exponential backoff between attempts.
+const maxRetryDelay = 30 * time.Second
+
+// retryPolicy describes how often an operation is attempted and the delay before the first
+// retry.
+type retryPolicy struct {
+	attempts int
+	delay    time.Duration
+}
+
+// newRetryPolicy returns the retry policy for the configuration. Unset values use the
+// defaults.
+func newRetryPolicy(retry config.Retry) retryPolicy {
+
+	policy := retryPolicy{
+		attempts: retry.Attempts,
+		delay:    time.Duration(retry.Delay) * time.Millisecond,
+	}
+	if policy.attempts <= 0 {
+		policy.attempts = config.DefaultPullRetryAttempts
+	}
+	if policy.delay <= 0 {
+		policy.delay = config.DefaultPullRetryDelay * time.Millisecond
+	}
+	return policy
+}
+
+// backoff returns the delay after the failed attempt, which starts with 1.
+func (policy retryPolicy) backoff(attempt int) time.Duration {
+
+	delay := policy.delay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// transportWrapper returns a transport that wraps the transport used for accessing registries.
+type transportWrapper func(http.RoundTripper) http.RoundTripper
+
+// responseRecorder records the failed responses received through the transports it wraps.
+// A pull sends requests concurrently, so the responses are recorded per request, and a
+// successful response to a request clears the failure of an earlier attempt of that request,
+// for example, after an authorization.
+type responseRecorder struct {
+	mutex    sync.Mutex
+	failures map[string]recordedResponse // failed responses by request
+}
+
+// recordedResponse describes the status and Retry-After header of a failed response.
+type recordedResponse struct {
+	status     int
+	retryAfter string
+}
+
+// recordingTransport is a transport that reports all responses to the recorder.
+type recordingTransport struct {
+	base     http.RoundTripper
+	recorder *responseRecorder
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	resp, err := rt.base.RoundTrip(req)
+	if err == nil {
+		rt.recorder.record(req.Method+" "+req.URL.String(), resp)
+	}
+	return resp, err
+}
+
+// wrap returns a transport that records the responses of the transport.
+func (rec *responseRecorder) wrap(base http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{base: base, recorder: rec}
+}
+
+// record records the response to the request or clears the failure of the request if the
+// response is successful.
+func (rec *responseRecorder) record(request string, resp *http.Response) {
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	if resp.StatusCode < http.StatusBadRequest {
+		delete(rec.failures, request)
+		return
+	}
+	if rec.failures == nil {
+		rec.failures = map[string]recordedResponse{}
+	}
+	rec.failures[request] = recordedResponse{
+		status:     resp.StatusCode,
+		retryAfter: resp.Header.Get("Retry-After"),
+	}
+}
+
+// reset clears the recorded responses.
+func (rec *responseRecorder) reset() {
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	rec.failures = nil
+}
+
+// failedResponses returns the responses of the requests that failed.
+func (rec *responseRecorder) failedResponses() []recordedResponse {
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	resps := make([]recordedResponse, 0, len(rec.failures))
+	for _, resp := range rec.failures {
+		resps = append(resps, resp)
+	}
+	return resps
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, which is either the number of
+// seconds or a date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if date.Before(now) {
+			return 0, true
+		}
+		return date.Sub(now), true
+	}
+	return 0, false
+}
+
+// isCertificateError returns true if the error reports an invalid certificate or a broken TLS
+// connection, which fail again when retried.
+func isCertificateError(err error) bool {
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalid) ||
+		errors.As(err, &hostname) || errors.As(err, &recordHeader)
+}
+
+// retryDelay returns the delay before the next attempt and true if the error is transient.
+// Network errors, server errors, and rate limits are transient, and the Retry-After header of
+// a rate limit response, limited to the maximum delay, replaces the backoff delay. Other
+// errors, such as certificate and authorization failures or missing images, are not retried.
+func retryDelay(err error, rec *responseRecorder, backoff time.Duration) (time.Duration, bool) {
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		isCertificateError(err) {
+		return 0, false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return backoff, true
+	}
+
+	transient := false
+	rateLimited := false
+	delay := time.Duration(0)
+	for _, resp := range rec.failedResponses() {
+		if resp.status == http.StatusTooManyRequests {
+			transient = true
+			if d, ok := parseRetryAfter(resp.retryAfter, time.Now()); ok {
+				rateLimited = true
+				if d > delay {
+					delay = d
+				}
+			}
+		} else if resp.status >= http.StatusInternalServerError {
+			transient = true
+		}
+	}
+	if !transient {
+		return 0, false
+	}
+	if !rateLimited {
+		return backoff, true
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay, true
+}
+
+// retry calls the function until it succeeds, fails with an error that isn't transient, or the
+// policy's attempts are used up. The notify function is called before waiting for the next
+// attempt. Waiting stops when the context is canceled.
+func retry(ctx context.Context, policy retryPolicy, rec *responseRecorder,
+	notify func(attempt int, delay time.Duration, err error),
+	fn func(ctx context.Context) error) error {
+
+	for attempt := 1; ; attempt++ {
+
+		rec.reset()
+		err := fn(ctx)
+		if err == nil || attempt >= policy.attempts {
+			return err
+		}
+
+		delay, ok := retryDelay(err, rec, policy.backoff(attempt))
+		if !ok {
+			return err
+		}
+		if notify != nil {
+			notify(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// pullRetryStatus returns the progress status for retrying the pull of the image after the
+// failed attempt.
+func pullRetryStatus(name string, attempt, attempts int, delay time.Duration,
+	err error) runtime.ProgressStatus {
+
+	now := time.Now()
+	return runtime.ProgressStatus{
+		Reference: "retry:" + name,
+		Status:    runtime.StatusRetrying,
+		Details: fmt.Sprintf("attempt %d of %d failed, retrying in %s: %v",
+			attempt, attempts, delay.Round(time.Millisecond), err),
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+}
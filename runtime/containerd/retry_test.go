@@ -0,0 +1,218 @@
+package containerd
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// testTransport is a transport that fails the first requests with the error or, if the error
+// is nil, with a response of the status code and the headers, and then serves the test
+// manifest.
+type testTransport struct {
+	failures int
+	status   int
+	header   http.Header
+	err      error
+	requests int
+}
+
+func (tt *testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	tt.requests++
+	if tt.requests <= tt.failures {
+		if tt.err != nil {
+			return nil, tt.err
+		}
+		header := tt.header
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			StatusCode: tt.status,
+			Status:     http.StatusText(tt.status),
+			Header:     header,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", ocispec.MediaTypeImageManifest)
+	header.Set("Docker-Content-Digest", digest.FromString(testManifest).String())
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        http.StatusText(http.StatusOK),
+		Header:        header,
+		ContentLength: int64(len(testManifest)),
+		Body:          ioutil.NopCloser(strings.NewReader(testManifest)),
+		Request:       req,
+	}, nil
+}
+
+// testNetError is a temporary network error.
+type testNetError struct{}
+
+func (testNetError) Error() string   { return "connection reset" }
+func (testNetError) Timeout() bool   { return false }
+func (testNetError) Temporary() bool { return true }
+
+func TestRetryPull(t *testing.T) {
+
+	policy := retryPolicy{attempts: 4, delay: time.Millisecond}
+	tests := []struct {
+		name      string
+		transport *testTransport
+		success   bool
+		retries   int
+	}{
+		{"server error",
+			&testTransport{failures: 2, status: http.StatusServiceUnavailable}, true, 2},
+		{"network error",
+			&testTransport{failures: 3, err: testNetError{}}, true, 3},
+		{"rate limit",
+			&testTransport{failures: 6, status: http.StatusTooManyRequests,
+				header: http.Header{"Retry-After": []string{"0"}}}, true, 1},
+		{"exhausted",
+			&testTransport{failures: 10, status: http.StatusBadGateway}, false, 3},
+		{"unauthorized",
+			&testTransport{failures: 10, status: http.StatusUnauthorized}, false, 0},
+		{"not found",
+			&testTransport{failures: 10, status: http.StatusNotFound}, false, 0},
+	}
+	for _, tc := range tests {
+		recorder := &responseRecorder{}
+		wrap := func(http.RoundTripper) http.RoundTripper { return recorder.wrap(tc.transport) }
+		resolver := newPullResolver(&containerdRuntime{}, wrap)
+
+		retries := 0
+		notify := func(attempt int, delay time.Duration, err error) {
+			retries++
+			if attempt != retries {
+				t.Errorf("%s: expected attempt %d, got %d", tc.name, retries, attempt)
+			}
+		}
+		err := retry(context.Background(), policy, recorder, notify,
+			func(ctx context.Context) error {
+				_, _, err := resolver.Resolve(ctx, "registry.example.com/library/test:latest")
+				return err
+			})
+		if tc.success && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		} else if !tc.success && err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+		if retries != tc.retries {
+			t.Errorf("%s: expected %d retries, got %d", tc.name, tc.retries, retries)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+
+	backoff := 100 * time.Millisecond
+	tests := []struct {
+		status     int
+		retryAfter string
+		err        error
+		delay      time.Duration
+		transient  bool
+	}{
+		{http.StatusTooManyRequests, "7", errors.New("rate limited"), 7 * time.Second, true},
+		{http.StatusTooManyRequests, "600", errors.New("rate limited"), maxRetryDelay, true},
+		{http.StatusTooManyRequests, "", errors.New("rate limited"), backoff, true},
+		{http.StatusServiceUnavailable, "", errors.New("unavailable"), backoff, true},
+		{0, "", testNetError{}, backoff, true},
+		{0, "", &url.Error{Op: "Get", URL: "https://registry.example.com/v2/",
+			Err: testNetError{}}, backoff, true},
+		{0, "", &url.Error{Op: "Get", URL: "https://registry.example.com/v2/",
+			Err: x509.UnknownAuthorityError{}}, 0, false},
+		{0, "", &url.Error{Op: "Get", URL: "https://registry.example.com/v2/",
+			Err: x509.HostnameError{Host: "registry.example.com"}}, 0, false},
+		{http.StatusForbidden, "", errors.New("forbidden"), 0, false},
+		{http.StatusInternalServerError, "", context.Canceled, 0, false},
+	}
+	for _, tc := range tests {
+		recorder := &responseRecorder{}
+		if tc.status != 0 {
+			header := http.Header{}
+			header.Set("Retry-After", tc.retryAfter)
+			recorder.record("GET /", &http.Response{StatusCode: tc.status, Header: header})
+		}
+		delay, transient := retryDelay(tc.err, recorder, backoff)
+		if delay != tc.delay || transient != tc.transient {
+			t.Errorf("Status %d, error '%v': expected %v/%t, got %v/%t", tc.status, tc.err,
+				tc.delay, tc.transient, delay, transient)
+		}
+	}
+
+	now := time.Now()
+	date := now.Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date, now); !ok || delay <= 88*time.Second ||
+		delay > 90*time.Second {
+		t.Errorf("Unexpected delay for Retry-After date '%s': %v", date, delay)
+	}
+
+	policy := retryPolicy{attempts: 10, delay: time.Second}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second,
+		4 * time.Second, 8 * time.Second, 16 * time.Second, maxRetryDelay, maxRetryDelay} {
+		if delay := policy.backoff(attempt + 1); delay != expected {
+			t.Errorf("Attempt %d: expected backoff %v, got %v", attempt+1, expected, delay)
+		}
+	}
+}
+
+// statusTransport is a transport that responds with the status code for the request path.
+type statusTransport map[string]int
+
+func (st statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: st[req.URL.Path],
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestResponseRecorder(t *testing.T) {
+
+	backoff := 100 * time.Millisecond
+	recorder := &responseRecorder{}
+	client := &http.Client{Transport: recorder.wrap(statusTransport{
+		"/blob":     http.StatusServiceUnavailable,
+		"/manifest": http.StatusOK,
+	})}
+
+	// a successful response to another request doesn't hide the failed request
+	for _, path := range []string{"/blob", "/manifest"} {
+		resp, err := client.Get("https://registry.example.com" + path)
+		if err != nil {
+			t.Fatalf("Request %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	if delay, ok := retryDelay(errors.New("failed"), recorder, backoff); !ok || delay != backoff {
+		t.Errorf("Failed request not retried: %v/%t", delay, ok)
+	}
+
+	// a successful response to the request clears the failure
+	recorder.record("GET /token", &http.Response{StatusCode: http.StatusUnauthorized})
+	recorder.record("GET /token", &http.Response{StatusCode: http.StatusOK})
+	if resps := recorder.failedResponses(); len(resps) != 1 {
+		t.Errorf("Expected one failed response, got %v", resps)
+	}
+
+	recorder.reset()
+	if _, ok := retryDelay(errors.New("failed"), recorder, backoff); ok {
+		t.Errorf("Error without a failed response should not be retried")
+	}
+}
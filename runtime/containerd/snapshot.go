@@ -13,6 +13,8 @@ import (
 	"github.com/containerd/containerd/snapshots"
 	"github.com/opencontainers/image-spec/identity"
 
+	"github.com/google/uuid"
+
 	"github.com/czankel/cne/errdefs"
 	"github.com/czankel/cne/runtime"
 )
@@ -64,6 +66,9 @@ func getSnapshotDomains(ctrdRun *containerdRuntime) ([][16]byte, error) {
 	return domains, err
 }
 
+// getSnapshots returns all snapshots of all domains. Committed snapshots are named by their
+// digest and can be shared between domains, so only active snapshots can be associated with
+// a domain through their name.
 func getSnapshots(ctrdRun *containerdRuntime) ([]runtime.Snapshot, error) {
 	var snaps []runtime.Snapshot
 
@@ -94,6 +99,105 @@ func getSnapshot(ctrdRun *containerdRuntime, snapName string) (runtime.Snapshot,
 	return &snapshot{ctrdRuntime: ctrdRun, info: info}, nil
 }
 
+// touchSnapshot records the current time as the last use of the snapshot in a label.
+func touchSnapshot(ctrdRun *containerdRuntime, snapName string) error {
+
+	ctrdCtx := ctrdRun.context
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	info := snapshots.Info{
+		Name: snapName,
+		Labels: map[string]string{
+			containerdSnapshotLastUsedLabel: time.Now().UTC().Format(time.RFC3339Nano),
+		},
+	}
+	_, err := snapSvc.Update(ctrdCtx, info, "labels."+containerdSnapshotLastUsedLabel)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return errdefs.NotFound("snapshot", snapName)
+	} else if err != nil {
+		return runtime.Errorf("failed to update snapshot: %v", err)
+	}
+	return nil
+}
+
+// snapshotLastUsedAt returns the time of the last use recorded in the label of the snapshot or
+// the time the snapshot was created.
+func snapshotLastUsedAt(info snapshots.Info) time.Time {
+
+	lastUsed, err := time.Parse(time.RFC3339Nano, info.Labels[containerdSnapshotLastUsedLabel])
+	if err != nil || lastUsed.Before(info.Created) {
+		return info.Created
+	}
+	return lastUsed
+}
+
+// snapshotGenerationLabel returns the label of a committed snapshot that records the generation
+// of the container when the container was committed with the snapshot as its root filesystem.
+// Committed snapshots can be shared, so the label includes the container.
+func snapshotGenerationLabel(domain, id [16]byte) string {
+	return containerdSnapshotGenerationLabel + activeSnapshotName(domain, id)
+}
+
+// labelSnapshotGeneration records the generation of the container in the parent snapshot of
+// the active snapshot of the container.
+func labelSnapshotGeneration(ctrdRun *containerdRuntime, domain, id, gen [16]byte) error {
+
+	active, err := getActiveSnapshot(ctrdRun, domain, id)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if active.Parent() == "" {
+		return nil
+	}
+
+	label := snapshotGenerationLabel(domain, id)
+	info := snapshots.Info{
+		Name:   active.Parent(),
+		Labels: map[string]string{label: hex.EncodeToString(gen[:])},
+	}
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	_, err = snapSvc.Update(ctrdRun.context, info, "labels."+label)
+	if err != nil {
+		return runtime.Errorf("failed to update snapshot labels: %v", err)
+	}
+	return nil
+}
+
+// previousGeneration returns the most recent committed snapshot below the active snapshot of
+// the container that was committed with a generation other than the provided generation.
+func previousGeneration(ctrdRun *containerdRuntime,
+	domain, id, gen [16]byte) (runtime.Snapshot, [16]byte, error) {
+
+	var prevGen [16]byte
+
+	active, err := getActiveSnapshot(ctrdRun, domain, id)
+	if err != nil {
+		return nil, prevGen, err
+	}
+
+	label := snapshotGenerationLabel(domain, id)
+	genStr := hex.EncodeToString(gen[:])
+	for name := active.Parent(); name != ""; {
+		snap, err := getSnapshot(ctrdRun, name)
+		if err != nil {
+			return nil, prevGen, err
+		}
+		if g := snap.Labels()[label]; g != "" && g != genStr {
+			b, err := hex.DecodeString(g)
+			if err != nil || len(b) != len(prevGen) {
+				return nil, prevGen, runtime.Errorf("invalid generation label '%s'", g)
+			}
+			copy(prevGen[:], b)
+			return snap, prevGen, nil
+		}
+		name = snap.Parent()
+	}
+
+	return nil, prevGen, errdefs.NotFound("previous generation", activeSnapshotName(domain, id))
+}
+
 func getActiveSnapshot(ctrdRun *containerdRuntime, domain, id [16]byte) (runtime.Snapshot, error) {
 	return getSnapshot(ctrdRun, activeSnapshotName(domain, id))
 }
@@ -309,6 +413,54 @@ func updateSnapshot(ctrdRun *containerdRuntime,
 	return snap, err
 }
 
+// copyActiveSnapshot creates the active snapshot for the container id with the content of the
+// active snapshot of the container srcID. The snapshot shares the parent with the source.
+func copyActiveSnapshot(ctrdRun *containerdRuntime, domain, srcID, id [16]byte) error {
+
+	ctrdCtx := ctrdRun.context
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	diffSvc := ctrdRun.client.DiffService()
+
+	srcName := activeSnapshotName(domain, srcID)
+	srcSnap, err := getSnapshot(ctrdRun, srcName)
+	if err != nil {
+		return err
+	}
+	parentName := srcSnap.Parent()
+	if parentName == "" {
+		return runtime.Errorf("snapshot %s doesn't have a parent", srcName)
+	}
+
+	snapName := activeSnapshotName(domain, id)
+	parentMnts, err := snapSvc.View(ctrdCtx, snapName+"-view", parentName)
+	if err != nil {
+		return runtime.Errorf("creating snapshot '%v' failed: %v", parentName, err)
+	}
+	defer snapSvc.Remove(ctrdCtx, snapName+"-view")
+
+	srcMnts, err := snapSvc.Mounts(ctrdCtx, srcName)
+	if err != nil {
+		return runtime.Errorf("failed to mount snapshot: %v", err)
+	}
+
+	desc, err := diffSvc.Compare(ctrdCtx, parentMnts, srcMnts)
+	if err != nil {
+		return runtime.Errorf("failed to create diff between snapshots: %v", err)
+	}
+
+	mounts, _, err := createSnapshot(ctrdRun, snapName, parentName, true /* mutable */)
+	if err != nil {
+		return err
+	}
+
+	_, err = diffSvc.Apply(ctrdCtx, desc, mounts)
+	if err != nil {
+		snapSvc.Remove(ctrdCtx, snapName)
+		return runtime.Errorf("failed to apply snapshot: %v", err)
+	}
+	return nil
+}
+
 func getActiveSnapMounts(ctrdRun *containerdRuntime, dom, cid [16]byte) ([]mount.Mount, error) {
 
 	snapName := activeSnapshotName(dom, cid)
@@ -343,6 +495,45 @@ func deleteActiveSnapshot(ctrdRun *containerdRuntime, domain, id [16]byte) error
 	return nil
 }
 
+// replaceActiveSnapshot replaces the active snapshot of the container with a new active
+// snapshot based on the parent snapshot. Snapshots cannot be renamed, so the current active
+// snapshot is committed under a temporary name first. It is removed after the new active
+// snapshot was created, or used to restore the active snapshot if the new snapshot cannot be
+// created.
+func replaceActiveSnapshot(ctrdRun *containerdRuntime, domain, id [16]byte,
+	parentName string) error {
+
+	ctrdCtx := ctrdRun.context
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+
+	activeSnapName := activeSnapshotName(domain, id)
+	savedSnapName := activeSnapName + "-replaced-" + uuid.New().String()
+
+	labels := map[string]string{}
+	labels["containerd.io/gc.root"] = time.Now().UTC().Format(time.RFC3339)
+
+	err := snapSvc.Commit(ctrdCtx, savedSnapName, activeSnapName, snapshots.WithLabels(labels))
+	if err != nil && ctrderr.IsNotFound(err) {
+		savedSnapName = ""
+	} else if err != nil {
+		return runtime.Errorf("failed to save active snapshot: %v", err)
+	}
+
+	_, _, err = createSnapshot(ctrdRun, activeSnapName, parentName, true /* mutable */)
+	if err != nil {
+		if savedSnapName != "" {
+			// ignore errors, the saved snapshot keeps the content of the active snapshot
+			createSnapshot(ctrdRun, activeSnapName, savedSnapName, true /* mutable */)
+		}
+		return err
+	}
+
+	if savedSnapName != "" {
+		return deleteSnapshot(ctrdRun, savedSnapName)
+	}
+	return nil
+}
+
 // delete all unrefeenced containers for the provided container starting with the active snapshot
 func deleteContainerSnapshots(ctrdRun *containerdRuntime, domain, id [16]byte) error {
 
@@ -401,10 +592,22 @@ func (snap *snapshot) Parent() string {
 	return snap.info.Parent
 }
 
+func (snap *snapshot) Kind() string {
+	return strings.ToLower(snap.info.Kind.String())
+}
+
+func (snap *snapshot) Labels() map[string]string {
+	return snap.info.Labels
+}
+
 func (snap *snapshot) CreatedAt() time.Time {
 	return snap.info.Created
 }
 
+func (snap *snapshot) LastUsedAt() time.Time {
+	return snapshotLastUsedAt(snap.info)
+}
+
 func (snap *snapshot) Size() (int64, error) {
 
 	ctrdRun := snap.ctrdRuntime
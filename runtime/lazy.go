@@ -0,0 +1,274 @@
+package runtime
+
+import (
+	"io"
+	"sync"
+
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+)
+
+// lazyRuntime is a runtime that opens the underlying runtime on first use.
+type lazyRuntime struct {
+	runType  RuntimeType
+	confRun  config.Runtime
+	mutex    sync.Mutex
+	opened   bool // set after the first attempt to open the runtime or after Close
+	run      Runtime
+	err      error
+	creds    Credentials
+	insecure InsecureRegistry
+	mirrors  RegistryMirrors
+}
+
+// OpenLazy returns a runtime for the specified name that opens the connection to the runtime
+// only when it is used for the first time. Errors for opening the runtime are returned by the
+// first function that accesses the runtime.
+func OpenLazy(confRun config.Runtime) (Runtime, error) {
+	reg, ok := runtimes[confRun.Name]
+	if !ok {
+		return nil, errdefs.NotFound("runtime", confRun.Name)
+	}
+	return &lazyRuntime{runType: reg, confRun: confRun}, nil
+}
+
+// open opens the underlying runtime once and applies the registry settings.
+func (lazy *lazyRuntime) open() (Runtime, error) {
+
+	lazy.mutex.Lock()
+	defer lazy.mutex.Unlock()
+
+	if lazy.opened {
+		return lazy.run, lazy.err
+	}
+	lazy.opened = true
+
+	lazy.run, lazy.err = lazy.runType.Open(lazy.confRun)
+	if lazy.err != nil {
+		return nil, lazy.err
+	}
+	if lazy.creds != nil {
+		lazy.run.SetCredentials(lazy.creds)
+	}
+	if lazy.insecure != nil {
+		lazy.run.SetInsecureRegistries(lazy.insecure)
+	}
+	if lazy.mirrors != nil {
+		lazy.run.SetRegistryMirrors(lazy.mirrors)
+	}
+	return lazy.run, nil
+}
+
+func (lazy *lazyRuntime) Namespace() string {
+	return lazy.confRun.Namespace
+}
+
+// Close closes the underlying runtime if it was opened.
+func (lazy *lazyRuntime) Close() {
+
+	lazy.mutex.Lock()
+	defer lazy.mutex.Unlock()
+
+	if lazy.run != nil {
+		lazy.run.Close()
+		lazy.run = nil
+	}
+	if !lazy.opened || lazy.err == nil {
+		lazy.err = errdefs.InvalidArgument("runtime is closed")
+	}
+	lazy.opened = true
+}
+
+// Version returns the name and socket of the runtime configuration if the runtime cannot be
+// opened.
+func (lazy *lazyRuntime) Version() (VersionInfo, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return VersionInfo{Name: lazy.confRun.Name, Socket: lazy.confRun.SocketName}, err
+	}
+	return run.Version()
+}
+
+func (lazy *lazyRuntime) Images() ([]Image, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.Images()
+}
+
+func (lazy *lazyRuntime) GetImage(name string) (Image, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.GetImage(name)
+}
+
+func (lazy *lazyRuntime) PullImage(name, platform string,
+	progress chan<- []ProgressStatus) (Image, error) {
+	run, err := lazy.open()
+	if err != nil {
+		if progress != nil {
+			close(progress)
+		}
+		return nil, err
+	}
+	return run.PullImage(name, platform, progress)
+}
+
+func (lazy *lazyRuntime) PushImage(name, remote string, progress chan<- []ProgressStatus) error {
+	run, err := lazy.open()
+	if err != nil {
+		if progress != nil {
+			close(progress)
+		}
+		return err
+	}
+	return run.PushImage(name, remote, progress)
+}
+
+// SetCredentials sets the credentials function, which is applied when the runtime is opened.
+func (lazy *lazyRuntime) SetCredentials(creds Credentials) {
+
+	lazy.mutex.Lock()
+	defer lazy.mutex.Unlock()
+
+	lazy.creds = creds
+	if lazy.run != nil {
+		lazy.run.SetCredentials(creds)
+	}
+}
+
+// SetInsecureRegistries sets the insecure registries function, which is applied when the
+// runtime is opened.
+func (lazy *lazyRuntime) SetInsecureRegistries(insecure InsecureRegistry) {
+
+	lazy.mutex.Lock()
+	defer lazy.mutex.Unlock()
+
+	lazy.insecure = insecure
+	if lazy.run != nil {
+		lazy.run.SetInsecureRegistries(insecure)
+	}
+}
+
+// SetRegistryMirrors sets the registry mirrors function, which is applied when the runtime is
+// opened.
+func (lazy *lazyRuntime) SetRegistryMirrors(mirrors RegistryMirrors) {
+
+	lazy.mutex.Lock()
+	defer lazy.mutex.Unlock()
+
+	lazy.mirrors = mirrors
+	if lazy.run != nil {
+		lazy.run.SetRegistryMirrors(mirrors)
+	}
+}
+
+func (lazy *lazyRuntime) DeleteImage(name string) error {
+	run, err := lazy.open()
+	if err != nil {
+		return err
+	}
+	return run.DeleteImage(name)
+}
+
+func (lazy *lazyRuntime) ExportImage(name string, w io.Writer) error {
+	run, err := lazy.open()
+	if err != nil {
+		return err
+	}
+	return run.ExportImage(name, w)
+}
+
+func (lazy *lazyRuntime) ImportImage(r io.Reader) ([]Image, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.ImportImage(r)
+}
+
+func (lazy *lazyRuntime) Snapshots() ([]Snapshot, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.Snapshots()
+}
+
+func (lazy *lazyRuntime) GetSnapshot(name string) (Snapshot, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.GetSnapshot(name)
+}
+
+func (lazy *lazyRuntime) DeleteSnapshot(name string) error {
+	run, err := lazy.open()
+	if err != nil {
+		return err
+	}
+	return run.DeleteSnapshot(name)
+}
+
+func (lazy *lazyRuntime) TouchSnapshot(name string) error {
+	run, err := lazy.open()
+	if err != nil {
+		return err
+	}
+	return run.TouchSnapshot(name)
+}
+
+func (lazy *lazyRuntime) Containers(filters ...interface{}) ([]Container, []string, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return run.Containers(filters...)
+}
+
+func (lazy *lazyRuntime) ContainerIDs() ([]string, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.ContainerIDs()
+}
+
+func (lazy *lazyRuntime) GetContainer(domain, id, generation [16]byte) (Container, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.GetContainer(domain, id, generation)
+}
+
+func (lazy *lazyRuntime) NewContainer(domain, id, generation [16]byte, uid uint32,
+	domainName string, image Image, spec *runspecs.Spec) (Container, error) {
+	run, err := lazy.open()
+	if err != nil {
+		return nil, err
+	}
+	return run.NewContainer(domain, id, generation, uid, domainName, image, spec)
+}
+
+func (lazy *lazyRuntime) DeleteContainer(domain, id, generation [16]byte) error {
+	run, err := lazy.open()
+	if err != nil {
+		return err
+	}
+	return run.DeleteContainer(domain, id, generation)
+}
+
+func (lazy *lazyRuntime) PurgeContainer(domain, id, generation [16]byte) error {
+	run, err := lazy.open()
+	if err != nil {
+		return err
+	}
+	return run.PurgeContainer(domain, id, generation)
+}
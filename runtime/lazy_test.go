@@ -0,0 +1,110 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+)
+
+// testRuntimeType counts the number of times the runtime is opened.
+type testRuntimeType struct {
+	opens int
+	err   error
+	run   *testRuntime
+}
+
+type testRuntime struct {
+	Runtime
+	closed bool
+	creds  Credentials
+}
+
+func (runType *testRuntimeType) Open(confRun config.Runtime) (Runtime, error) {
+	runType.opens++
+	if runType.err != nil {
+		return nil, runType.err
+	}
+	runType.run = &testRuntime{}
+	return runType.run, nil
+}
+
+func (run *testRuntime) Close() {
+	run.closed = true
+}
+
+func (run *testRuntime) SetCredentials(creds Credentials) {
+	run.creds = creds
+}
+
+func (run *testRuntime) Images() ([]Image, error) {
+	return nil, nil
+}
+
+func TestOpenLazy(t *testing.T) {
+
+	runType := &testRuntimeType{}
+	Register("lazy-test", runType)
+	defer delete(runtimes, "lazy-test")
+
+	if _, err := OpenLazy(config.Runtime{Name: "unknown"}); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected NotFound for an unknown runtime, got %v", err)
+	}
+
+	run, err := OpenLazy(config.Runtime{Name: "lazy-test", Namespace: "test"})
+	if err != nil {
+		t.Fatalf("Failed to open runtime: %v", err)
+	}
+	if run.Namespace() != "test" || runType.opens != 0 {
+		t.Errorf("Runtime opened before first use")
+	}
+	run.SetCredentials(func(host string) (string, string, error) { return "user", "", nil })
+
+	for i := 0; i < 2; i++ {
+		if _, err := run.Images(); err != nil {
+			t.Fatalf("Failed to get images: %v", err)
+		}
+	}
+	if runType.opens != 1 {
+		t.Errorf("Expected runtime to be opened once, got %d", runType.opens)
+	}
+	if runType.run.creds == nil {
+		t.Errorf("Credentials not applied to the opened runtime")
+	}
+
+	run.Close()
+	if !runType.run.closed {
+		t.Errorf("Runtime not closed")
+	}
+	if _, err := run.Images(); err == nil {
+		t.Errorf("Expected error for closed runtime")
+	}
+}
+
+func TestOpenLazyUnused(t *testing.T) {
+
+	runType := &testRuntimeType{err: errdefs.InternalError("injected")}
+	Register("lazy-test", runType)
+	defer delete(runtimes, "lazy-test")
+
+	run, err := OpenLazy(config.Runtime{Name: "lazy-test"})
+	if err != nil {
+		t.Fatalf("Failed to open runtime: %v", err)
+	}
+	run.Close()
+	if runType.opens != 0 {
+		t.Errorf("Close should not open the runtime")
+	}
+
+	run, _ = OpenLazy(config.Runtime{Name: "lazy-test", SocketName: "/run/test.sock"})
+	if _, err := run.Images(); !errors.Is(err, errdefs.ErrInternalError) {
+		t.Errorf("Expected error from opening the runtime, got %v", err)
+	}
+
+	info, err := run.Version()
+	if err == nil || info.Name != "lazy-test" || info.Socket != "/run/test.sock" {
+		t.Errorf("Expected runtime information and error, got %v: %v", info, err)
+	}
+	run.Close()
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"sync"
+)
+
+// LogBuffer is a bounded in-memory buffer that retains the most recent output written to it.
+// It can be used as an additional writer for the output streams of a process.
+type LogBuffer struct {
+	mutex sync.Mutex
+	buf   []byte
+	next  int
+	full  bool
+}
+
+// NewLogBuffer returns a new log buffer that retains up to size bytes.
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{buf: make([]byte, size)}
+}
+
+// Write appends the data to the buffer and overwrites the oldest data if the buffer is full.
+func (lb *LogBuffer) Write(p []byte) (int, error) {
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	n := len(p)
+	size := len(lb.buf)
+	if size == 0 {
+		return n, nil
+	}
+	if len(p) >= size {
+		p = p[len(p)-size:]
+		lb.next = 0
+		lb.full = true
+	}
+
+	for len(p) > 0 {
+		c := copy(lb.buf[lb.next:], p)
+		p = p[c:]
+		lb.next += c
+		if lb.next == size {
+			lb.next = 0
+			lb.full = true
+		}
+	}
+	return n, nil
+}
+
+// Bytes returns a copy of the buffered data with the oldest data first.
+func (lb *LogBuffer) Bytes() []byte {
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if !lb.full {
+		return append([]byte{}, lb.buf[:lb.next]...)
+	}
+	return append(append([]byte{}, lb.buf[lb.next:]...), lb.buf[:lb.next]...)
+}
@@ -0,0 +1,40 @@
+package runtime
+
+import (
+	"testing"
+)
+
+func TestLogBuffer(t *testing.T) {
+
+	lb := NewLogBuffer(8)
+
+	lb.Write([]byte("abc"))
+	if string(lb.Bytes()) != "abc" {
+		t.Errorf("Expected 'abc', got '%s'", lb.Bytes())
+	}
+
+	lb.Write([]byte("defgh"))
+	if string(lb.Bytes()) != "abcdefgh" {
+		t.Errorf("Expected 'abcdefgh', got '%s'", lb.Bytes())
+	}
+
+	// exceed the buffer size
+	n, err := lb.Write([]byte("ijk"))
+	if n != 3 || err != nil {
+		t.Errorf("Write should consume all data: %d, %v", n, err)
+	}
+	if string(lb.Bytes()) != "defghijk" {
+		t.Errorf("Expected 'defghijk', got '%s'", lb.Bytes())
+	}
+
+	// single write larger than the buffer
+	lb.Write([]byte("0123456789"))
+	if string(lb.Bytes()) != "23456789" {
+		t.Errorf("Expected '23456789', got '%s'", lb.Bytes())
+	}
+
+	lb.Write([]byte("x"))
+	if string(lb.Bytes()) != "3456789x" {
+		t.Errorf("Expected '3456789x', got '%s'", lb.Bytes())
+	}
+}
@@ -0,0 +1,83 @@
+package mock
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// writeArchive writes a minimal OCI image layout with the index of the images to the writer.
+// The archive doesn't include any blobs.
+func writeArchive(w io.Writer, imgs []*image) error {
+
+	index := ocispec.Index{}
+	index.SchemaVersion = 2
+	for _, img := range imgs {
+		index.Manifests = append(index.Manifests, ocispec.Descriptor{
+			MediaType:   ocispec.MediaTypeImageManifest,
+			Digest:      img.digest,
+			Size:        img.size,
+			Annotations: map[string]string{ocispec.AnnotationRefName: img.name},
+		})
+	}
+
+	layout, err := json.Marshal(ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion})
+	if err != nil {
+		return errdefs.InternalError("failed to encode image layout: %v", err)
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return errdefs.InternalError("failed to encode image index: %v", err)
+	}
+
+	tw := tar.NewWriter(w)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{ocispec.ImageLayoutFile, layout}, {"index.json", data}} {
+		err = tw.WriteHeader(&tar.Header{
+			Name:     f.name,
+			Mode:     0444,
+			Size:     int64(len(f.data)),
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = tw.Write(f.data)
+		}
+		if err != nil {
+			return errdefs.SystemError(err, "failed to write archive")
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return errdefs.SystemError(err, "failed to write archive")
+	}
+	return nil
+}
+
+// readArchive returns the image references of the index of an archive written by writeArchive.
+func readArchive(r io.Reader) ([]ocispec.Descriptor, error) {
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errdefs.InvalidArgument("archive has no image index")
+		}
+		if err != nil {
+			return nil, errdefs.InvalidArgument("invalid archive: %v", err)
+		}
+		if hdr.Name != "index.json" {
+			continue
+		}
+
+		var index ocispec.Index
+		if err = json.NewDecoder(tr).Decode(&index); err != nil {
+			return nil, errdefs.InvalidArgument("invalid image index: %v", err)
+		}
+		return index.Manifests, nil
+	}
+}
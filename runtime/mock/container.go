@@ -0,0 +1,486 @@
+package mock
+
+import (
+	"encoding/hex"
+	"time"
+
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+type container struct {
+	runtime    *Runtime
+	domain     [16]byte
+	id         [16]byte
+	generation [16]byte
+	uid        uint32
+	domainName string
+	image      runtime.Image
+	spec       *runspecs.Spec
+	createdAt  time.Time
+	updatedAt  time.Time
+	created    bool
+	ephemeral  bool
+	running    bool
+	rootFs     string
+	active     *snapshot
+	committed  []string
+}
+
+func (ctr *container) CreatedAt() time.Time {
+	return ctr.createdAt
+}
+
+func (ctr *container) UpdatedAt() time.Time {
+	return ctr.updatedAt
+}
+
+func (ctr *container) Domain() [16]byte {
+	return ctr.domain
+}
+
+func (ctr *container) DomainName() string {
+	return ctr.domainName
+}
+
+func (ctr *container) ID() [16]byte {
+	return ctr.id
+}
+
+func (ctr *container) Generation() [16]byte {
+	return ctr.generation
+}
+
+func (ctr *container) RuntimeID() string {
+	return containerKey(ctr.domain, ctr.id)
+}
+
+func (ctr *container) Image() runtime.Image {
+	return ctr.image
+}
+
+func (ctr *container) UID() uint32 {
+	return ctr.uid
+}
+
+func (ctr *container) SetRootFs(snap runtime.Snapshot) error {
+
+	run := ctr.runtime
+	if err := run.failure("SetRootFs"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	// a nil snapshot resets the root filesystem to the image
+	rootFs := ""
+	if snap != nil {
+		if _, ok := run.snapshots[snap.Name()]; !ok {
+			return errdefs.NotFound("snapshot", snap.Name())
+		}
+		rootFs = snap.Name()
+	}
+	ctr.rootFs = rootFs
+
+	// replace the active snapshot of a created container like the containerd runtime
+	if ctr.created {
+		delete(run.snapshots, ctr.active.name)
+		ctr.active = run.newSnapshot(ctr.rootFsParent(), "active")
+	}
+	return nil
+}
+
+// rootFsParent returns the parent for the active snapshot, which is the root filesystem or,
+// if not set, the top layer of the image.
+func (ctr *container) rootFsParent() string {
+
+	if ctr.rootFs == "" && ctr.image != nil {
+		if img, ok := ctr.image.(*image); ok && len(img.rootFS) > 0 {
+			return img.rootFS[len(img.rootFS)-1].String()
+		}
+	}
+	return ctr.rootFs
+}
+
+func (ctr *container) UpdateSpec(spec *runspecs.Spec) error {
+
+	run := ctr.runtime
+	if err := run.failure("UpdateSpec"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	ctr.spec = spec
+	ctr.updatedAt = time.Now()
+	run.mutex.Unlock()
+
+	return nil
+}
+
+func (ctr *container) Create() ([]string, error) {
+
+	run := ctr.runtime
+	if err := run.failure("Create"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	key := containerKey(ctr.domain, ctr.id)
+	if _, ok := run.containers[key]; ok {
+		return nil, errdefs.AlreadyExists("container", key)
+	}
+
+	ctr.active = run.newSnapshot(ctr.rootFsParent(), "active")
+	ctr.created = true
+	ctr.createdAt = time.Now()
+	ctr.updatedAt = ctr.createdAt
+	run.containers[key] = ctr
+
+	return nil, nil
+}
+
+func (ctr *container) Delete() error {
+
+	run := ctr.runtime
+	if err := run.failure("Delete"); err != nil {
+		return err
+	}
+
+	if ctr.ephemeral {
+		run.mutex.Lock()
+		delete(run.snapshots, ctr.active.name)
+		run.mutex.Unlock()
+		return nil
+	}
+	return run.deleteContainer(ctr.domain, ctr.id, false)
+}
+
+func (ctr *container) Purge() error {
+
+	run := ctr.runtime
+	if err := run.failure("Purge"); err != nil {
+		return err
+	}
+	return run.deleteContainer(ctr.domain, ctr.id, true)
+}
+
+// Snapshot commits the active snapshot and continues with a new active snapshot.
+func (ctr *container) Snapshot() (runtime.Snapshot, error) {
+
+	run := ctr.runtime
+	if err := run.failure("Snapshot"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !ctr.created {
+		return nil, errdefs.NotFound("container", ctr.RuntimeID())
+	}
+
+	snap := run.newSnapshot(ctr.active.parent, "committed")
+	ctr.active.parent = snap.name
+	ctr.committed = append(ctr.committed, snap.name)
+
+	return snap, nil
+}
+
+func (ctr *container) Ephemeral() (runtime.Container, error) {
+
+	run := ctr.runtime
+	if err := run.failure("Ephemeral"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !ctr.created {
+		return nil, errdefs.NotFound("container", ctr.RuntimeID())
+	}
+
+	eph := *ctr
+	eph.ephemeral = true
+	eph.committed = nil
+	eph.createdAt = time.Now()
+	eph.active = run.newSnapshot(ctr.active.parent, "active")
+
+	return &eph, nil
+}
+
+// Amend replaces the last committed snapshot with a snapshot that includes the current changes.
+func (ctr *container) Amend() (runtime.Snapshot, error) {
+
+	run := ctr.runtime
+	if err := run.failure("Amend"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if len(ctr.committed) == 0 {
+		return nil, errdefs.NotFound("snapshot", ctr.RuntimeID())
+	}
+
+	last := len(ctr.committed) - 1
+	old := run.snapshots[ctr.committed[last]]
+	snap := run.newSnapshot(old.parent, "committed")
+	delete(run.snapshots, old.name)
+
+	ctr.committed[last] = snap.name
+	ctr.active.parent = snap.name
+
+	return snap, nil
+}
+
+func (ctr *container) Diff(changes func(runtime.Change) error) error {
+
+	run := ctr.runtime
+	if err := run.failure("Diff"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	list := run.changes
+	created := ctr.created
+	run.mutex.Unlock()
+
+	if !created {
+		return errdefs.NotFound("snapshot", ctr.RuntimeID())
+	}
+	for _, c := range list {
+		if err := changes(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctr *container) Commit(generation [16]byte) error {
+
+	run := ctr.runtime
+	if err := run.failure("Commit"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	ctr.generation = generation
+	ctr.updatedAt = time.Now()
+	ctr.labelGeneration()
+	run.mutex.Unlock()
+
+	return nil
+}
+
+// generationLabel is the label of a committed snapshot with the generation of the container.
+func (ctr *container) generationLabel() string {
+	return "generation." + containerKey(ctr.domain, ctr.id)
+}
+
+// labelGeneration records the generation in the parent of the active snapshot.
+// The runtime must be locked.
+func (ctr *container) labelGeneration() {
+
+	if ctr.active == nil {
+		return
+	}
+	snap, ok := ctr.runtime.snapshots[ctr.active.parent]
+	if !ok {
+		return
+	}
+	if snap.labels == nil {
+		snap.labels = map[string]string{}
+	}
+	snap.labels[ctr.generationLabel()] = hex.EncodeToString(ctr.generation[:])
+}
+
+func (ctr *container) Exec(stream runtime.Stream,
+	procSpec *runspecs.Process) (runtime.Process, error) {
+
+	run := ctr.runtime
+	if err := run.failure("Exec"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	run.processes = append(run.processes, *procSpec)
+	execFunc := run.execFunc
+	run.mutex.Unlock()
+
+	code := uint32(0)
+	if execFunc != nil {
+		code = execFunc(stream, procSpec.Args)
+	}
+	return &process{code: code, exitAt: time.Now()}, nil
+}
+
+func (ctr *container) Start() error {
+
+	run := ctr.runtime
+	if err := run.failure("Start"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !ctr.created {
+		return errdefs.NotFound("container", ctr.RuntimeID())
+	}
+	ctr.running = true
+	return nil
+}
+
+func (ctr *container) Stop() error {
+
+	run := ctr.runtime
+	if err := run.failure("Stop"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	ctr.running = false
+	run.mutex.Unlock()
+
+	return nil
+}
+
+func (ctr *container) Running() (bool, error) {
+
+	run := ctr.runtime
+	if err := run.failure("Running"); err != nil {
+		return false, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	return ctr.running, nil
+}
+
+// mainProcess returns ErrNotFound if the main process of the container isn't running.
+func (ctr *container) mainProcess() error {
+
+	run := ctr.runtime
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !ctr.running {
+		return errdefs.NotFound("process", ctr.RuntimeID())
+	}
+	return nil
+}
+
+func (ctr *container) Attach(stream runtime.Stream) (runtime.Process, error) {
+
+	if err := ctr.runtime.failure("Attach"); err != nil {
+		return nil, err
+	}
+	if err := ctr.mainProcess(); err != nil {
+		return nil, err
+	}
+	return &process{exitAt: time.Now()}, nil
+}
+
+func (ctr *container) Logs(stream runtime.Stream, follow bool) error {
+
+	if err := ctr.runtime.failure("Logs"); err != nil {
+		return err
+	}
+	return ctr.mainProcess()
+}
+
+// Processes returns no processes as simulated processes exit immediately.
+func (ctr *container) Processes() ([]runtime.Process, error) {
+
+	if err := ctr.runtime.failure("Processes"); err != nil {
+		return nil, err
+	}
+	if err := ctr.mainProcess(); err != nil {
+		return nil, err
+	}
+	return []runtime.Process{}, nil
+}
+
+func (ctr *container) Stats() (*runtime.Stats, error) {
+
+	if err := ctr.runtime.failure("Stats"); err != nil {
+		return nil, err
+	}
+	if err := ctr.mainProcess(); err != nil {
+		return nil, err
+	}
+
+	stats := &runtime.Stats{Pids: 1}
+	if ctr.spec != nil && ctr.spec.Linux != nil && ctr.spec.Linux.Resources != nil &&
+		ctr.spec.Linux.Resources.Memory != nil && ctr.spec.Linux.Resources.Memory.Limit != nil {
+		stats.MemoryLimit = uint64(*ctr.spec.Linux.Resources.Memory.Limit)
+	}
+	return stats, nil
+}
+
+func (ctr *container) Restore(snap runtime.Snapshot, generation [16]byte) error {
+
+	run := ctr.runtime
+	if err := run.failure("Restore"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !ctr.created {
+		return errdefs.NotFound("container", ctr.RuntimeID())
+	}
+	if s, ok := run.snapshots[snap.Name()]; !ok || s.kind != "committed" {
+		return errdefs.NotFound("snapshot", snap.Name())
+	}
+
+	ctr.running = false
+	ctr.active.parent = snap.Name()
+	ctr.generation = generation
+	ctr.updatedAt = time.Now()
+
+	return nil
+}
+
+func (ctr *container) Rollback() ([16]byte, error) {
+
+	var gen [16]byte
+
+	run := ctr.runtime
+	if err := run.failure("Rollback"); err != nil {
+		return gen, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if !ctr.created {
+		return gen, errdefs.NotFound("container", ctr.RuntimeID())
+	}
+
+	label := ctr.generationLabel()
+	current := hex.EncodeToString(ctr.generation[:])
+	for snap := run.snapshots[ctr.active.parent]; snap != nil; snap = run.snapshots[snap.parent] {
+		if g := snap.labels[label]; g != "" && g != current {
+			b, _ := hex.DecodeString(g)
+			copy(gen[:], b)
+
+			ctr.running = false
+			ctr.active.parent = snap.name
+			ctr.generation = gen
+			ctr.updatedAt = time.Now()
+			return gen, nil
+		}
+	}
+
+	return gen, errdefs.NotFound("previous generation", ctr.RuntimeID())
+}
@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+type image struct {
+	runtime   *Runtime
+	name      string
+	digest    digest.Digest
+	rootFS    []digest.Digest
+	createdAt time.Time
+	size      int64
+	labels    map[string]string
+}
+
+func (img *image) Name() string {
+	return img.name
+}
+
+func (img *image) Digest() digest.Digest {
+	return img.digest
+}
+
+func (img *image) RootFS() ([]digest.Digest, error) {
+	if err := img.runtime.failure("RootFS"); err != nil {
+		return nil, err
+	}
+	return img.rootFS, nil
+}
+
+func (img *image) CreatedAt() time.Time {
+	return img.createdAt
+}
+
+func (img *image) Labels() map[string]string {
+	return img.labels
+}
+
+func (img *image) Config() (*v1.ImageConfig, error) {
+	if err := img.runtime.failure("Config"); err != nil {
+		return nil, err
+	}
+	return &v1.ImageConfig{
+		Env: []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"},
+		Cmd: []string{"/bin/sh"},
+	}, nil
+}
+
+func (img *image) Size() int64 {
+	return img.size
+}
+
+func (img *image) History() ([]runtime.ImageHistory, error) {
+	if err := img.runtime.failure("History"); err != nil {
+		return nil, err
+	}
+	return []runtime.ImageHistory{{
+		CreatedAt: img.createdAt,
+		CreatedBy: "/bin/sh -c #(nop) ADD file:rootfs in /",
+		Layer:     img.rootFS[0],
+		Size:      img.size,
+	}}, nil
+}
+
+func (img *image) Tag(name string) (runtime.Image, error) {
+
+	run := img.runtime
+	if err := run.failure("Tag"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	tagged := *img
+	tagged.name = name
+	run.images[name] = &tagged
+	return &tagged, nil
+}
+
+func (img *image) Mount(path string) error {
+	return errdefs.NotImplemented()
+}
+
+func (img *image) Unmount(path string) error {
+	return errdefs.NotImplemented()
+}
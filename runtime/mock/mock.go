@@ -0,0 +1,585 @@
+// Package mock implements an in-memory runtime for testing.
+//
+// The runtime is registered with the name "mock" and keeps all images, snapshots, and containers
+// in maps. Runtimes opened for the same namespace share their state, so tests can prepare and
+// inspect the state with Namespace while the code under test opens the runtime with Open.
+// Errors can be injected for any interface function with Fail.
+package mock
+
+import (
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+// ExecFunc simulates the execution of a process in a container and returns the exit code.
+type ExecFunc func(stream runtime.Stream, args []string) uint32
+
+// Runtime is the in-memory runtime.
+type Runtime struct {
+	mutex      sync.Mutex
+	namespace  string
+	images     map[string]*image
+	snapshots  map[string]*snapshot
+	containers map[string]*container
+	failures   map[string]error
+	execFunc   ExecFunc
+	processes  []runspecs.Process
+	changes    []runtime.Change
+	snapCount  int
+}
+
+type mockRuntimeType struct{}
+
+var runtimesMutex sync.Mutex
+var runtimes = map[string]*Runtime{}
+
+func init() {
+	runtime.Register("mock", &mockRuntimeType{})
+}
+
+// Open returns the runtime for the namespace of the runtime configuration.
+func (r *mockRuntimeType) Open(confRun config.Runtime) (runtime.Runtime, error) {
+
+	run := Namespace(confRun.Namespace)
+	if err := run.failure("Open"); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Namespace returns the runtime for the namespace and creates the runtime if it doesn't exist.
+func Namespace(name string) *Runtime {
+
+	runtimesMutex.Lock()
+	defer runtimesMutex.Unlock()
+
+	run, ok := runtimes[name]
+	if !ok {
+		run = &Runtime{
+			namespace:  name,
+			images:     make(map[string]*image),
+			snapshots:  make(map[string]*snapshot),
+			containers: make(map[string]*container),
+			failures:   make(map[string]error),
+		}
+		runtimes[name] = run
+	}
+	return run
+}
+
+// Reset removes the runtimes of all namespaces.
+func Reset() {
+	runtimesMutex.Lock()
+	runtimes = map[string]*Runtime{}
+	runtimesMutex.Unlock()
+}
+
+// Fail injects an error that is returned by the runtime, image, container, or snapshot function
+// with the provided name, such as "PullImage" or "Exec". A nil error removes the failure.
+func (run *Runtime) Fail(function string, err error) {
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if err == nil {
+		delete(run.failures, function)
+	} else {
+		run.failures[function] = err
+	}
+}
+
+// failure returns the error injected for the function.
+func (run *Runtime) failure(function string) error {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+	return run.failures[function]
+}
+
+// SetExecFunc sets the function that simulates processes started with Exec. Processes exit
+// immediately with exit code 0 if no function is set.
+func (run *Runtime) SetExecFunc(execFunc ExecFunc) {
+	run.mutex.Lock()
+	run.execFunc = execFunc
+	run.mutex.Unlock()
+}
+
+// Commands returns the arguments of all processes that were started with Exec.
+func (run *Runtime) Commands() [][]string {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	cmds := make([][]string, len(run.processes))
+	for i, p := range run.processes {
+		cmds[i] = p.Args
+	}
+	return cmds
+}
+
+// Processes returns the specifications of all processes that were started with Exec.
+func (run *Runtime) Processes() []runspecs.Process {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+	return append([]runspecs.Process{}, run.processes...)
+}
+
+// SetChanges sets the filesystem changes that are reported by Diff for all containers.
+func (run *Runtime) SetChanges(changes []runtime.Change) {
+	run.mutex.Lock()
+	run.changes = changes
+	run.mutex.Unlock()
+}
+
+// AddImage adds an image with a single layer to the runtime, as if it was pulled.
+func (run *Runtime) AddImage(name string, size int64) runtime.Image {
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	return run.addImage(name, size)
+}
+
+func (run *Runtime) addImage(name string, size int64) *image {
+
+	layer := digest.FromString("layer:" + name)
+	img := &image{
+		runtime:   run,
+		name:      name,
+		digest:    digest.FromString(name),
+		rootFS:    []digest.Digest{layer},
+		createdAt: time.Now(),
+		size:      size,
+	}
+	run.images[name] = img
+
+	if _, ok := run.snapshots[layer.String()]; !ok {
+		run.snapshots[layer.String()] = &snapshot{
+			name:      layer.String(),
+			kind:      "committed",
+			createdAt: time.Now(),
+			size:      size,
+		}
+	}
+	return img
+}
+
+// newSnapshot creates a new snapshot with a unique name. The mutex must be held.
+func (run *Runtime) newSnapshot(parent, kind string) *snapshot {
+
+	run.snapCount++
+	snap := &snapshot{
+		name:      digest.FromBytes([]byte{byte(run.snapCount >> 8), byte(run.snapCount)}).String(),
+		parent:    parent,
+		kind:      kind,
+		createdAt: time.Now(),
+	}
+	run.snapshots[snap.name] = snap
+	return snap
+}
+
+// containerKey returns the key for the container in the map of containers.
+func containerKey(domain, id [16]byte) string {
+	return hex.EncodeToString(domain[:]) + "-" + hex.EncodeToString(id[:])
+}
+
+// Runtime Interface
+
+func (run *Runtime) Namespace() string {
+	return run.namespace
+}
+
+func (run *Runtime) Close() {
+}
+
+func (run *Runtime) Version() (runtime.VersionInfo, error) {
+
+	info := runtime.VersionInfo{Name: "mock"}
+	if err := run.failure("Version"); err != nil {
+		return info, err
+	}
+	info.Version = "1.0.0"
+	return info, nil
+}
+
+func (run *Runtime) Images() ([]runtime.Image, error) {
+
+	if err := run.failure("Images"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	names := make([]string, 0, len(run.images))
+	for n := range run.images {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	imgs := make([]runtime.Image, len(names))
+	for i, n := range names {
+		imgs[i] = run.images[n]
+	}
+	return imgs, nil
+}
+
+func (run *Runtime) GetImage(name string) (runtime.Image, error) {
+
+	if err := run.failure("GetImage"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	img, ok := run.images[name]
+	if !ok {
+		return nil, errdefs.NotFound("image", name)
+	}
+	return img, nil
+}
+
+// PullImage pulls the image for any platform in the form os/arch[/variant].
+func (run *Runtime) PullImage(name, platform string,
+	progress chan<- []runtime.ProgressStatus) (runtime.Image, error) {
+
+	if progress != nil {
+		defer close(progress)
+	}
+
+	if err := run.failure("PullImage"); err != nil {
+		return nil, err
+	}
+	if platform != "" && len(strings.Split(platform, "/")) < 2 {
+		return nil, errdefs.InvalidArgument("invalid platform '%s'", platform)
+	}
+
+	run.mutex.Lock()
+	img, ok := run.images[name]
+	if !ok {
+		img = run.addImage(name, 0)
+	}
+	run.mutex.Unlock()
+
+	if progress != nil {
+		now := time.Now()
+		progress <- []runtime.ProgressStatus{{
+			Reference: img.rootFS[0].String(),
+			Status:    runtime.StatusComplete,
+			Offset:    img.size,
+			Total:     img.size,
+			StartedAt: now,
+			UpdatedAt: now,
+		}}
+	}
+	return img, nil
+}
+
+func (run *Runtime) PushImage(name, remote string,
+	progress chan<- []runtime.ProgressStatus) error {
+
+	if progress != nil {
+		defer close(progress)
+	}
+
+	if err := run.failure("PushImage"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if _, ok := run.images[name]; !ok {
+		return errdefs.NotFound("image", name)
+	}
+	return nil
+}
+
+func (run *Runtime) SetCredentials(creds runtime.Credentials) {
+}
+
+func (run *Runtime) SetInsecureRegistries(insecure runtime.InsecureRegistry) {
+}
+
+func (run *Runtime) SetRegistryMirrors(mirrors runtime.RegistryMirrors) {
+}
+
+func (run *Runtime) DeleteImage(name string) error {
+
+	if err := run.failure("DeleteImage"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if _, ok := run.images[name]; !ok {
+		return errdefs.NotFound("image", name)
+	}
+	delete(run.images, name)
+	return nil
+}
+
+func (run *Runtime) ExportImage(name string, w io.Writer) error {
+
+	if err := run.failure("ExportImage"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	img, ok := run.images[name]
+	run.mutex.Unlock()
+	if !ok {
+		return errdefs.NotFound("image", name)
+	}
+	return writeArchive(w, []*image{img})
+}
+
+func (run *Runtime) ImportImage(r io.Reader) ([]runtime.Image, error) {
+
+	if err := run.failure("ImportImage"); err != nil {
+		return nil, err
+	}
+
+	descs, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	imgs := []runtime.Image{}
+	for _, desc := range descs {
+		name := desc.Annotations[ocispec.AnnotationRefName]
+		if name == "" {
+			continue
+		}
+		imgs = append(imgs, run.addImage(name, desc.Size))
+	}
+	return imgs, nil
+}
+
+func (run *Runtime) Snapshots() ([]runtime.Snapshot, error) {
+
+	if err := run.failure("Snapshots"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	names := make([]string, 0, len(run.snapshots))
+	for n := range run.snapshots {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	snaps := make([]runtime.Snapshot, len(names))
+	for i, n := range names {
+		snaps[i] = run.snapshots[n]
+	}
+	return snaps, nil
+}
+
+func (run *Runtime) GetSnapshot(name string) (runtime.Snapshot, error) {
+
+	if err := run.failure("GetSnapshot"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	snap, ok := run.snapshots[name]
+	if !ok {
+		return nil, errdefs.NotFound("snapshot", name)
+	}
+	return snap, nil
+}
+
+func (run *Runtime) DeleteSnapshot(name string) error {
+
+	if err := run.failure("DeleteSnapshot"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if _, ok := run.snapshots[name]; !ok {
+		return errdefs.NotFound("snapshot", name)
+	}
+	for _, snap := range run.snapshots {
+		if snap.parent == name {
+			return errdefs.InUse("snapshot", name)
+		}
+	}
+	delete(run.snapshots, name)
+	return nil
+}
+
+func (run *Runtime) TouchSnapshot(name string) error {
+
+	if err := run.failure("TouchSnapshot"); err != nil {
+		return err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	snap, ok := run.snapshots[name]
+	if !ok {
+		return errdefs.NotFound("snapshot", name)
+	}
+
+	// replace the snapshot, as callers might still use the previous snapshot
+	touched := *snap
+	touched.lastUsedAt = time.Now()
+	run.snapshots[name] = &touched
+	return nil
+}
+
+func (run *Runtime) Containers(filters ...interface{}) ([]runtime.Container, []string, error) {
+
+	if err := run.failure("Containers"); err != nil {
+		return nil, nil, err
+	}
+
+	hasDomain := false
+	var domain [16]byte
+
+	if len(filters) > 1 {
+		return nil, nil, errdefs.InvalidArgument("too many arguments to get containers")
+	}
+	if len(filters) == 1 {
+		domain, hasDomain = filters[0].([16]byte)
+		if !hasDomain {
+			return nil, nil, errdefs.InvalidArgument("invalid arguments for getting containers")
+		}
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	keys := make([]string, 0, len(run.containers))
+	for k, ctr := range run.containers {
+		if ctr.created && (!hasDomain || ctr.domain == domain) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	ctrs := make([]runtime.Container, len(keys))
+	for i, k := range keys {
+		ctrs[i] = run.containers[k]
+	}
+	return ctrs, []string{}, nil
+}
+
+func (run *Runtime) ContainerIDs() ([]string, error) {
+
+	if err := run.failure("ContainerIDs"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	ids := []string{}
+	for k, ctr := range run.containers {
+		if ctr.created {
+			ids = append(ids, k)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (run *Runtime) GetContainer(domain, id, generation [16]byte) (runtime.Container, error) {
+
+	if err := run.failure("GetContainer"); err != nil {
+		return nil, err
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	key := containerKey(domain, id)
+	ctr, ok := run.containers[key]
+	if !ok || !ctr.created || ctr.generation != generation {
+		return nil, errdefs.NotFound("container", key)
+	}
+	return ctr, nil
+}
+
+func (run *Runtime) NewContainer(domain, id, generation [16]byte, uid uint32,
+	domainName string, img runtime.Image, spec *runspecs.Spec) (runtime.Container, error) {
+
+	if err := run.failure("NewContainer"); err != nil {
+		return nil, err
+	}
+
+	return &container{
+		runtime:    run,
+		domain:     domain,
+		id:         id,
+		generation: generation,
+		uid:        uid,
+		domainName: domainName,
+		image:      img,
+		spec:       spec,
+	}, nil
+}
+
+func (run *Runtime) DeleteContainer(domain, id, generation [16]byte) error {
+
+	if err := run.failure("DeleteContainer"); err != nil {
+		return err
+	}
+	return run.deleteContainer(domain, id, false)
+}
+
+func (run *Runtime) PurgeContainer(domain, id, generation [16]byte) error {
+
+	if err := run.failure("PurgeContainer"); err != nil {
+		return err
+	}
+	return run.deleteContainer(domain, id, true)
+}
+
+// deleteContainer deletes the container and the active snapshot and, if purge is set, all
+// committed snapshots of the container.
+func (run *Runtime) deleteContainer(domain, id [16]byte, purge bool) error {
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	key := containerKey(domain, id)
+	ctr, ok := run.containers[key]
+	if !ok {
+		return errdefs.NotFound("container", key)
+	}
+	delete(run.containers, key)
+
+	if ctr.active != nil {
+		delete(run.snapshots, ctr.active.name)
+	}
+	if purge {
+		for _, name := range ctr.committed {
+			delete(run.snapshots, name)
+		}
+	}
+	return nil
+}
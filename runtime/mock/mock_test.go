@@ -0,0 +1,135 @@
+package mock
+
+import (
+	"errors"
+	"testing"
+
+	runspecs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var _ runtime.Runtime = &Runtime{}
+var _ runtime.Image = &image{}
+var _ runtime.Container = &container{}
+var _ runtime.Snapshot = &snapshot{}
+var _ runtime.Process = &process{}
+
+func TestMockOpen(t *testing.T) {
+
+	defer Reset()
+
+	run, err := runtime.Open(config.Runtime{Name: "mock", Namespace: "test"})
+	if err != nil {
+		t.Fatalf("Failed to open mock runtime: %v", err)
+	}
+	if run != Namespace("test") || run.Namespace() != "test" {
+		t.Errorf("Runtime doesn't share the state of the namespace")
+	}
+
+	Namespace("test").Fail("Open", errdefs.InternalError("injected"))
+	_, err = runtime.Open(config.Runtime{Name: "mock", Namespace: "test"})
+	if !errors.Is(err, errdefs.ErrInternalError) {
+		t.Errorf("Expected injected error, got %v", err)
+	}
+}
+
+func TestMockPullImage(t *testing.T) {
+
+	defer Reset()
+	run := Namespace("test")
+
+	progress := make(chan []runtime.ProgressStatus, 1)
+	img, err := run.PullImage("docker.io/library/busybox:latest", "", progress)
+	if err != nil {
+		t.Fatalf("Failed to pull image: %v", err)
+	}
+	if status, ok := <-progress; !ok || status[0].Status != runtime.StatusComplete {
+		t.Errorf("Expected complete status, got %v", status)
+	}
+	if _, ok := <-progress; ok {
+		t.Errorf("Progress channel not closed")
+	}
+
+	rootFS, _ := img.RootFS()
+	if _, err = run.GetSnapshot(rootFS[0].String()); err != nil {
+		t.Errorf("Image layer not unpacked: %v", err)
+	}
+
+	if _, err = run.PullImage("other", "arm64", nil); !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Expected invalid platform error, got %v", err)
+	}
+
+	run.Fail("PullImage", errdefs.InternalError("injected"))
+	if _, err = run.PullImage("other", "", nil); err == nil {
+		t.Errorf("Injected error not returned")
+	}
+	if _, err = run.GetImage("other"); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Image exists after failed pull")
+	}
+
+	run.Fail("PullImage", nil)
+	if _, err = run.PullImage("other", "", nil); err != nil {
+		t.Errorf("Failure not removed: %v", err)
+	}
+}
+
+func TestMockContainer(t *testing.T) {
+
+	defer Reset()
+	run := Namespace("test")
+
+	img := run.AddImage("busybox", 1000)
+	domain := [16]byte{1}
+	id := [16]byte{2}
+
+	ctr, err := run.NewContainer(domain, id, [16]byte{}, 0, "test", img, &runspecs.Spec{})
+	if err != nil {
+		t.Fatalf("Failed to define container: %v", err)
+	}
+	if _, err = ctr.Create(); err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+
+	run.SetExecFunc(func(stream runtime.Stream, args []string) uint32 { return 2 })
+	proc, err := ctr.Exec(runtime.Stream{}, &runspecs.Process{Args: []string{"false"}})
+	if err != nil {
+		t.Fatalf("Failed to exec: %v", err)
+	}
+	exitStatus, _ := proc.Wait()
+	if status := <-exitStatus; status.Code != 2 {
+		t.Errorf("Expected exit code 2, got %d", status.Code)
+	}
+	if cmds := run.Commands(); len(cmds) != 1 || cmds[0][0] != "false" {
+		t.Errorf("Unexpected commands %v", cmds)
+	}
+
+	snap, err := ctr.Snapshot()
+	if err != nil || snap.Kind() != "committed" {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	gen := [16]byte{3}
+	if err = ctr.Commit(gen); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if _, err = run.GetContainer(domain, id, gen); err != nil {
+		t.Errorf("Failed to get committed container: %v", err)
+	}
+	if ctrs, _, _ := run.Containers(domain); len(ctrs) != 1 {
+		t.Errorf("Expected one container, got %d", len(ctrs))
+	}
+
+	if _, err = ctr.Stats(); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Expected not found for stopped container, got %v", err)
+	}
+
+	if err = run.PurgeContainer(domain, id, gen); err != nil {
+		t.Fatalf("Failed to purge container: %v", err)
+	}
+	if _, err = run.GetSnapshot(snap.Name()); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Snapshot not purged")
+	}
+}
@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/czankel/cne/runtime"
+)
+
+// process is a simulated process that has already exited when it is returned by Exec.
+type process struct {
+	code    uint32
+	exitAt  time.Time
+	mutex   sync.Mutex // signals and the terminal size can change while waiting
+	signals []os.Signal
+	width   uint32
+	height  uint32
+}
+
+func (proc *process) Signal(sig os.Signal) error {
+
+	proc.mutex.Lock()
+	defer proc.mutex.Unlock()
+
+	proc.signals = append(proc.signals, sig)
+	return nil
+}
+
+func (proc *process) Wait() (<-chan runtime.ExitStatus, error) {
+
+	exitStatus := make(chan runtime.ExitStatus, 1)
+	exitStatus <- runtime.ExitStatus{ExitTime: proc.exitAt, Code: proc.code}
+	close(exitStatus)
+
+	return exitStatus, nil
+}
+
+func (proc *process) Resize(width, height uint32) error {
+
+	proc.mutex.Lock()
+	defer proc.mutex.Unlock()
+
+	proc.width = width
+	proc.height = height
+	return nil
+}
@@ -0,0 +1,50 @@
+package mock
+
+import (
+	"time"
+)
+
+type snapshot struct {
+	name       string
+	parent     string
+	kind       string
+	labels     map[string]string
+	createdAt  time.Time
+	lastUsedAt time.Time
+	size       int64
+}
+
+func (snap *snapshot) Name() string {
+	return snap.name
+}
+
+func (snap *snapshot) Parent() string {
+	return snap.parent
+}
+
+func (snap *snapshot) Kind() string {
+	return snap.kind
+}
+
+func (snap *snapshot) Labels() map[string]string {
+	return snap.labels
+}
+
+func (snap *snapshot) CreatedAt() time.Time {
+	return snap.createdAt
+}
+
+func (snap *snapshot) LastUsedAt() time.Time {
+	if snap.lastUsedAt.IsZero() {
+		return snap.createdAt
+	}
+	return snap.lastUsedAt
+}
+
+func (snap *snapshot) Size() (int64, error) {
+	return snap.size, nil
+}
+
+func (snap *snapshot) Inodes() (int64, error) {
+	return 0, nil
+}
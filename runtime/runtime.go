@@ -18,6 +18,10 @@ import (
 	"github.com/czankel/cne/errdefs"
 )
 
+// SocketResource is the resource of errors for accessing the socket of the runtime, such as a
+// missing socket or missing permissions.
+const SocketResource = "runtime socket"
+
 // Runtime is the main interface for managing containers, images, and snapshots.
 type Runtime interface {
 
@@ -27,6 +31,11 @@ type Runtime interface {
 	// Close closes the runtime and any open descriptors
 	Close()
 
+	// Version returns the version information of the runtime. If the daemon of the runtime
+	// cannot be reached, it returns the information that is known without the daemon together
+	// with the error.
+	Version() (VersionInfo, error)
+
 	// Images returns a list of images that are registered in the runtime
 	Images() ([]Image, error)
 
@@ -34,25 +43,68 @@ type Runtime interface {
 	GetImage(name string) (Image, error)
 
 	// PullImage pulls an image into a local registry and returns an image instance.
+	// The platform, in the form os/arch[/variant], selects the manifest of a multi-platform
+	// image. The platform of the host is used if the platform is empty.
 	//
 	// PullImage is a blocking call and reports the progress through the optionally provided
 	// channel. The channel can be nil to skip sending updates.
 	//
 	// Note that the status sent may exclude status information for entries that haven't
 	// changed.
-	PullImage(name string, progress chan<- []ProgressStatus) (Image, error)
+	PullImage(name, platform string, progress chan<- []ProgressStatus) (Image, error)
+
+	// PushImage pushes an image from the local registry to the remote reference or, if the
+	// remote reference is empty, to the reference of the image name.
+	//
+	// PushImage is a blocking call and reports the progress through the optionally provided
+	// channel. The channel can be nil to skip sending updates.
+	PushImage(name, remote string, progress chan<- []ProgressStatus) error
+
+	// SetCredentials sets the function that provides the credentials for accessing registries
+	// when pulling and pushing images. Registries are accessed anonymously without credentials.
+	SetCredentials(creds Credentials)
+
+	// SetInsecureRegistries sets the function that selects the registries that are accessed
+	// over plain HTTP or without TLS verification when pulling and pushing images.
+	SetInsecureRegistries(insecure InsecureRegistry)
+
+	// SetRegistryMirrors sets the function that provides the mirrors that are tried in order
+	// before the registry when pulling images.
+	SetRegistryMirrors(mirrors RegistryMirrors)
 
 	// DeleteImage deletes the specified image from the registry.
 	DeleteImage(name string) error
 
+	// ExportImage writes the specified image as an OCI image archive to the writer. The
+	// archive also includes the Docker manifest, so it can be loaded by Docker.
+	// It returns ErrNotFound if the image doesn't exist.
+	ExportImage(name string, w io.Writer) error
+
+	// ImportImage reads an OCI or Docker image archive from the reader and registers all
+	// images of the archive. It returns the imported images.
+	ImportImage(r io.Reader) ([]Image, error)
+
 	// Snapshots returns all snapshots.
 	Snapshots() ([]Snapshot, error)
 
+	// GetSnapshot returns the specified snapshot. It returns ErrNotFound if the snapshot
+	// doesn't exist.
+	GetSnapshot(name string) (Snapshot, error)
+
 	// DeleteSnapshot deletes the snapshot
 	DeleteSnapshot(name string) error
 
-	// Containers returns all containers in the specified domain.
-	Containers(filters ...interface{}) ([]Container, error)
+	// TouchSnapshot records the current time as the last use of the snapshot.
+	// It returns ErrNotFound if the snapshot doesn't exist.
+	TouchSnapshot(name string) error
+
+	// Containers returns all containers in the specified domain. Containers that cannot be
+	// read are skipped, and it returns a list of warnings for the skipped containers.
+	Containers(filters ...interface{}) ([]Container, []string, error)
+
+	// ContainerIDs returns the runtime specific IDs of all containers including containers
+	// that are skipped by Containers because they cannot be read.
+	ContainerIDs() ([]string, error)
 
 	// GetContainer looks up and returns the specified container by domain, id, and generation.
 	// It returns ErrNotFound if the container could not be found.
@@ -60,8 +112,9 @@ type Runtime interface {
 	// The container can be used to execute commands with Exec.
 	GetContainer(domain, id, generation [16]byte) (Container, error)
 
-	// NewContainer defines a new Container without creating it.
-	NewContainer(domain, id, generation [16]byte, uid uint32,
+	// NewContainer defines a new Container without creating it. The domain name is a
+	// descriptive name of the domain, such as the project name.
+	NewContainer(domain, id, generation [16]byte, uid uint32, domainName string,
 		image Image, spec *runspecs.Spec) (Container, error)
 
 	// DeleteContainer deletes the specified container. It returns ErrNotFound if the container
@@ -73,6 +126,27 @@ type Runtime interface {
 	PurgeContainer(domain, id, generation [16]byte) error
 }
 
+// VersionInfo describes the runtime and the version of its daemon.
+type VersionInfo struct {
+	Name     string // Name of the runtime
+	Socket   string // Socket for accessing the daemon
+	Version  string // Version of the daemon
+	Revision string // Revision of the daemon, such as the git hash
+}
+
+// Credentials returns the username and secret for accessing the registry host. The secret is
+// used as a token if the username is empty, and the registry is accessed anonymously if both
+// are empty.
+type Credentials func(host string) (string, string, error)
+
+// InsecureRegistry returns true if the registry host is accessed over plain HTTP or without
+// TLS verification.
+type InsecureRegistry func(host string) bool
+
+// RegistryMirrors returns the mirror endpoints for the registry host. Endpoints are URLs with
+// the scheme, host, and an optional path prefix for the API.
+type RegistryMirrors func(host string) []string
+
 // Image describes an image that consists of a file system and configuration options.
 type Image interface {
 
@@ -88,12 +162,23 @@ type Image interface {
 	// CreatedAt returns the data the image was created.
 	CreatedAt() time.Time
 
+	// Labels returns the labels of the image.
+	Labels() map[string]string
+
 	// Config returns the configuration of the image.
 	Config() (*v1.ImageConfig, error)
 
 	// Size returns the size of the image.
 	Size() int64
 
+	// History returns the history of the image with an entry for each step that was used for
+	// building the image, starting with the oldest entry.
+	History() ([]ImageHistory, error)
+
+	// Tag creates a new reference with the provided name for the image and returns the image
+	// of the new reference. An existing reference with the name is replaced.
+	Tag(name string) (Image, error)
+
 	// Mount mounts the image to the provide path.
 	Mount(path string) error
 
@@ -101,6 +186,16 @@ type Image interface {
 	Unmount(path string) error
 }
 
+// ImageHistory describes a step of building an image.
+type ImageHistory struct {
+	CreatedAt  time.Time
+	CreatedBy  string        // Command that created the layer
+	Comment    string        // Optional comment
+	EmptyLayer bool          // Set if the step didn't create a layer
+	Layer      digest.Digest // Digest of the layer; empty for an empty layer
+	Size       int64         // Size of the (compressed) layer
+}
+
 // Container provides an abstraction for running processes in an isolated environment in user space.
 //
 // Containers are uniquely identified by these fields:
@@ -130,12 +225,22 @@ type Container interface {
 	// The domain allows for grouping containers.
 	Domain() [16]byte
 
+	// DomainName returns the descriptive name of the domain or an empty string if unknown.
+	DomainName() string
+
 	// ID returns the immutable container id that has to be unique in a domain.
 	ID() [16]byte
 
 	// Generation returns a value representing the filesystem.
 	Generation() [16]byte
 
+	// RuntimeID returns the ID the runtime uses for the container, for example, for using
+	// the tools of the runtime directly.
+	RuntimeID() string
+
+	// Image returns the image the container is based on.
+	Image() Image
+
 	// Return the User ID
 	UID() uint32
 
@@ -147,8 +252,9 @@ type Container interface {
 	// UpdateSpec updates the container spec.
 	UpdateSpec(spec *runspecs.Spec) error
 
-	// Create creates the container.
-	Create() error
+	// Create creates the container. It returns a list of warnings for conditions that
+	// don't prevent the creation of the container, such as deprecated configurations.
+	Create() ([]string, error)
 
 	// Delete deletes the container.
 	Delete() error
@@ -162,15 +268,64 @@ type Container interface {
 	// ErrNotImplemented error and nil for the snapshot.
 	Snapshot() (Snapshot, error)
 
+	// Ephemeral creates a temporary container with a copy of the current filesystem of the
+	// container. Changes in the temporary container are discarded when it is deleted. Temporary
+	// containers that were left behind, for example, after a crash, are deleted when another
+	// temporary container is created.
+	Ephemeral() (Container, error)
+
 	// Amend amends the committed snapshot with the current changes to the filesystem.
 	Amend() (Snapshot, error)
 
+	// Diff calls the provided function for each file that was added, modified, or deleted in
+	// the current filesystem of the container relative to the last committed snapshot. The
+	// changes are reported in the order of the path names, and the iteration stops if the
+	// function returns an error.
+	Diff(changes func(Change) error) error
+
 	// Commit commits the container after it has been built with a new generation value.
 	Commit(generation [16]byte) error
 
+	// Restore replaces the current filesystem of the container with the content of the
+	// committed snapshot and sets the generation of the container. The main process of the
+	// container is stopped.
+	Restore(snap Snapshot, generation [16]byte) error
+
+	// Rollback reverts the filesystem of the container to the snapshot of the generation that
+	// was committed before the current generation, and returns that generation. The main
+	// process of the container is stopped. It returns ErrNotFound if there is no previous
+	// generation.
+	Rollback() ([16]byte, error)
+
 	// Exec starts the provided command in the process spec and returns immediately.
 	// The container must be started before calling Exec.
 	Exec(stream Stream, procSpec *runspecs.Process) (Process, error)
+
+	// Start starts the main process of the container, as defined by the image, and returns
+	// immediately without attaching to the process.
+	Start() error
+
+	// Stop stops the main process and all other processes of the container.
+	Stop() error
+
+	// Running returns true if the main process of the container is running.
+	Running() (bool, error)
+
+	// Attach attaches the stream to the main process of the container.
+	Attach(stream Stream) (Process, error)
+
+	// Logs attaches the stdout and stderr of the stream to the main process of the container.
+	// If follow is set, it returns after the process exits, otherwise after the pending output
+	// was copied. It returns ErrNotFound if the main process of the container doesn't exist.
+	Logs(stream Stream, follow bool) error
+
+	// Processes returns the processes that were started with Exec and are still running.
+	// It returns ErrNotFound if the main process of the container doesn't exist.
+	Processes() ([]Process, error)
+
+	// Stats returns the current resource usage of the container. It returns ErrNotFound if
+	// the main process of the container doesn't exist.
+	Stats() (*Stats, error)
 }
 
 // Stream describes the IO channels to a process that is running in a container.
@@ -181,6 +336,27 @@ type Stream struct {
 	Terminal bool
 }
 
+// Change kinds of a file in the filesystem of a container.
+const (
+	ChangeAdd    = "add"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+)
+
+// Change describes a file that was changed in the filesystem of a container.
+type Change struct {
+	Kind string // ChangeAdd, ChangeModify, or ChangeDelete
+	Path string // Absolute path in the container
+}
+
+// Stats describes the resource usage of a running container.
+type Stats struct {
+	CPU         uint64 // CPU time in nanoseconds
+	MemoryUsage uint64 // Memory usage in bytes
+	MemoryLimit uint64 // Memory limit in bytes
+	Pids        uint64 // Number of processes
+}
+
 // Snapshot describes a snapshot of the current container filesystem.
 type Snapshot interface {
 
@@ -190,9 +366,19 @@ type Snapshot interface {
 	// Parent returns the name of the parent snapshot.
 	Parent() string
 
+	// Kind returns the kind of the snapshot, such as active or committed.
+	Kind() string
+
+	// Labels returns the labels of the snapshot.
+	Labels() map[string]string
+
 	// CreatedAt returns the time the snapshot was created.
 	CreatedAt() time.Time
 
+	// LastUsedAt returns the time the snapshot was last used, or the time it was created if
+	// it wasn't used since.
+	LastUsedAt() time.Time
+
 	// Size returns the size of the snapshot.
 	Size() (int64, error)
 
@@ -208,6 +394,9 @@ type Process interface {
 
 	// Wait waits asynchronously for the process to exit and sends the exit code to the channel.
 	Wait() (<-chan ExitStatus, error)
+
+	// Resize changes the size of the terminal of a process that was started with a terminal.
+	Resize(width, height uint32) error
 }
 
 // Progress status values.
@@ -219,6 +408,7 @@ const (
 	StatusComplete = "complete"
 	StatusAborted  = "aborted"
 	StatusError    = "error"
+	StatusRetrying = "retrying"
 )
 
 // ProgressStatus provides information about a running or completed image download or processes.
@@ -236,7 +426,7 @@ type ProgressStatus struct {
 type ExitStatus struct {
 	ExitTime time.Time
 	Error    error
-	Code     uint32 // Exit value from the process
+	Code     uint32 // Exit value from the process or 128+n if terminated by signal n
 }
 
 //